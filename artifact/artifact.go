@@ -0,0 +1,193 @@
+// Package artifact holds responses encoded once per dataset version, with their
+// precompressed variants, so that large exports are not recompressed on every request.
+package artifact
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings, as sent in Accept-Encoding and Content-Encoding
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+	Brotli   = "br"
+	Zstd     = "zstd"
+)
+
+// preference orders the codings by compression ratio, to break ties between equal q-values
+var preference = []string{Brotli, Zstd, Gzip}
+
+// Artifact is a response body and its compressed variants. It is immutable once built.
+type Artifact struct {
+	ContentType string
+	ModTime     time.Time
+	encodings   map[string][]byte
+	etags       map[string]string
+}
+
+// New compresses data with every supported coding. The codings are computed concurrently
+// with their highest practical levels, since an artifact is built once per version.
+func New(contentType string, data []byte, modTime time.Time) (*Artifact, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:16])
+
+	a := &Artifact{
+		ContentType: contentType,
+		ModTime:     modTime,
+		encodings:   map[string][]byte{Identity: data},
+		etags:       map[string]string{Identity: `"` + hash + `"`},
+	}
+
+	compressors := map[string]func([]byte) ([]byte, error){
+		Gzip:   compressGzip,
+		Brotli: compressBrotli,
+		Zstd:   compressZstd,
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for coding, compress := range compressors {
+		wg.Go(func() {
+			compressed, err := compress(data)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to compress with %s: %w", coding, err))
+				return
+			}
+			a.encodings[coding] = compressed
+			// Each representation has its own strong ETag, as required for Range and If-Range
+			a.etags[coding] = `"` + hash + "-" + coding + `"`
+		})
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return a, nil
+}
+
+// Body returns the representation for a coding returned by Negotiate
+func (a *Artifact) Body(coding string) []byte {
+	return a.encodings[coding]
+}
+
+// ETag returns the strong ETag of the representation for a coding returned by Negotiate
+func (a *Artifact) ETag(coding string) string {
+	return a.etags[coding]
+}
+
+// Size returns the size in bytes of the representation for a coding
+func (a *Artifact) Size(coding string) int {
+	return len(a.encodings[coding])
+}
+
+// Negotiate picks the coding to send for an Accept-Encoding header: the supported coding
+// with the highest q-value, preferring the best compression on ties, or Identity.
+func Negotiate(acceptEncoding string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for part := range strings.SplitSeq(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if coding == "*" {
+			wildcard = q
+		} else {
+			weights[coding] = q
+		}
+	}
+
+	best, bestQ := Identity, 0.0
+	for _, coding := range preference {
+		q, listed := weights[coding]
+		if !listed {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+func compressGzip(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func compressBrotli(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	// Quality 9 compresses close to the maximum (11) in a fraction of the time.
+	// The window is sized to the data, a small export does not allocate the largest one.
+	bw := brotli.NewWriterOptions(&buf, brotli.WriterOptions{Quality: 9, LGWin: windowLog(len(data), 10, 24)})
+	if _, err := bw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := bw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func compressZstd(data []byte) ([]byte, error) {
+	zw, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.SpeedBetterCompression),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithLowerEncoderMem(true),
+		zstd.WithWindowSize(1<<windowLog(len(data), 10, 27)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zw.Close() }()
+	return zw.EncodeAll(data, nil), nil
+}
+
+// windowLog returns the base-2 logarithm of the smallest window holding size bytes, within [minLog, maxLog]
+func windowLog(size, minLog, maxLog int) int {
+	log := minLog
+	for log < maxLog && 1<<log < size {
+		log++
+	}
+	return log
+}
@@ -0,0 +1,98 @@
+package artifact
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNew(t *testing.T) {
+	data := []byte(strings.Repeat(`{"cis":61266250,"elementPharmaceutique":"DOLIPRANE 1000 mg, comprimé"},`, 500))
+	modTime := time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)
+
+	a, err := New("application/json", data, modTime)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if a.ContentType != "application/json" || !a.ModTime.Equal(modTime) {
+		t.Errorf("Unexpected artifact: %s %v", a.ContentType, a.ModTime)
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		Identity: func(r io.Reader) (io.Reader, error) { return r, nil },
+		Gzip:     func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		Brotli:   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		Zstd: func(r io.Reader) (io.Reader, error) {
+			zr, err := zstd.NewReader(r)
+			return zr, err
+		},
+	}
+
+	etags := make(map[string]bool)
+	for coding, decode := range decoders {
+		body := a.Body(coding)
+		if coding != Identity && len(body) >= len(data) {
+			t.Errorf("%s: expected a compressed body, got %d bytes for %d", coding, len(body), len(data))
+		}
+		if a.Size(coding) != len(body) {
+			t.Errorf("%s: expected size %d, got %d", coding, len(body), a.Size(coding))
+		}
+
+		r, err := decode(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("%s: invalid body: %v", coding, err)
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: invalid body: %v", coding, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%s: decoded body differs from the data", coding)
+		}
+
+		etag := a.ETag(coding)
+		if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+			t.Errorf("%s: expected a strong quoted ETag, got %s", coding, etag)
+		}
+		etags[etag] = true
+	}
+	if len(etags) != len(decoders) {
+		t.Errorf("Expected one ETag per representation, got %v", etags)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", Identity},
+		{"identity", Identity},
+		{"gzip", Gzip},
+		{"gzip, deflate", Gzip},
+		{"gzip, deflate, br", Brotli},
+		{"gzip, deflate, br, zstd", Brotli},
+		{"zstd, gzip", Zstd},
+		{"br;q=0.5, gzip", Gzip},
+		{"br;q=0, gzip;q=0.1", Gzip},
+		{"GZIP", Gzip},
+		{"*", Brotli},
+		{"*;q=0.5, br;q=0", Zstd},
+		{"br;q=0, gzip;q=0, zstd;q=0", Identity},
+		{"gzip;q=invalid", Identity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := Negotiate(tt.acceptEncoding); got != tt.expected {
+				t.Errorf("Negotiate(%q) = %s, expected %s", tt.acceptEncoding, got, tt.expected)
+			}
+		})
+	}
+}
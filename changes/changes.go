@@ -0,0 +1,210 @@
+// Package changes computes the differences between two successive BDPM datasets,
+// so that clients can follow the updates without downloading the full export.
+package changes
+
+import (
+	"cmp"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// FieldChange is the old and new value of one field, named after its JSON key.
+// For a list, Old and New only hold the items that differ: the removed and previous versions
+// of the changed items in Old, the added and current versions in New.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Modification lists the fields that changed for one entity
+type Modification struct {
+	ID     int           `json:"id"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// Diff holds the identifiers added and removed between two datasets and the field-level
+// changes of the entities present in both. Identifiers are sorted.
+type Diff struct {
+	Added    []int          `json:"added"`
+	Removed  []int          `json:"removed"`
+	Modified []Modification `json:"modified"`
+}
+
+// ChangeSet is the difference between two successive dataset versions
+type ChangeSet struct {
+	FromVersion   uint64    `json:"fromVersion"`
+	ToVersion     uint64    `json:"toVersion"`
+	FromLoadedAt  time.Time `json:"fromLoadedAt"`
+	ToLoadedAt    time.Time `json:"toLoadedAt"`
+	Medicaments   Diff      `json:"medicaments"`   // By CIS
+	Presentations Diff      `json:"presentations"` // By CIP13
+	Generiques    Diff      `json:"generiques"`    // By group ID
+}
+
+// Len returns the number of entities added, removed or modified
+func (d Diff) Len() int {
+	return len(d.Added) + len(d.Removed) + len(d.Modified)
+}
+
+// Compare returns the differences between two maps of entities keyed by their identifier.
+// Fields without a JSON name, such as the precomputed normalized fields, are ignored.
+func Compare[T any](previous, current map[int]T) Diff {
+	diff := Diff{
+		Added:    make([]int, 0),
+		Removed:  make([]int, 0),
+		Modified: make([]Modification, 0),
+	}
+
+	for id, entity := range current {
+		old, exists := previous[id]
+		if !exists {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		if fields := compareFields(old, entity); len(fields) > 0 {
+			diff.Modified = append(diff.Modified, Modification{ID: id, Fields: fields})
+		}
+	}
+
+	for id := range previous {
+		if _, exists := current[id]; !exists {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.SortFunc(diff.Modified, func(a, b Modification) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return diff
+}
+
+// compareFields returns the JSON fields of two structs that differ, in declaration order
+func compareFields[T any](previous, current T) []FieldChange {
+	oldValue := reflect.ValueOf(previous)
+	newValue := reflect.ValueOf(current)
+	structType := oldValue.Type()
+
+	var fields []FieldChange
+	for i := range structType.NumField() {
+		name := jsonName(structType.Field(i))
+		if name == "" {
+			continue
+		}
+
+		oldField, newField := oldValue.Field(i), newValue.Field(i)
+		if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+			continue
+		}
+
+		if oldField.Kind() == reflect.Slice {
+			oldItems, newItems := compareItems(oldField, newField)
+			if oldItems.Len() > 0 || newItems.Len() > 0 { // Otherwise only the order changed
+				fields = append(fields, FieldChange{Field: name, Old: oldItems.Interface(), New: newItems.Interface()})
+			}
+			continue
+		}
+		fields = append(fields, FieldChange{Field: name, Old: oldField.Interface(), New: newField.Interface()})
+	}
+
+	return fields
+}
+
+// itemKeys names the fields identifying the items of the nested lists, so that a changed price
+// reports one presentation rather than every presentation of the medicament
+var itemKeys = map[reflect.Type][]string{
+	reflect.TypeFor[entities.Presentation]():         {"Cip13"},
+	reflect.TypeFor[entities.Composition]():          {"ElementPharmaceutique", "CodeSubstance", "NatureComposant"},
+	reflect.TypeFor[entities.Generique]():            {"Group"},
+	reflect.TypeFor[entities.AvisHAS]():              {"CodeDossierHAS", "MotifEvaluation", "DateAvis"},
+	reflect.TypeFor[entities.InfoImportante]():       {"DateDebut", "Lien"},
+	reflect.TypeFor[entities.GeneriqueMedicament]():  {"Cis"},
+	reflect.TypeFor[entities.GeneriqueComposition](): {"ElementPharmaceutique", "DenominationSubstance"},
+}
+
+// compareItems returns the items of two lists that differ, in the order of each list. Items are
+// matched by their key fields, or by value for the lists without keys or with duplicate keys.
+func compareItems(previous, current reflect.Value) (reflect.Value, reflect.Value) {
+	key := func(item reflect.Value) string { return jsonKey(item.Interface()) }
+	if fields, keyed := itemKeys[previous.Type().Elem()]; keyed {
+		byFields := func(item reflect.Value) string {
+			values := make([]any, len(fields))
+			for i, field := range fields {
+				values[i] = item.FieldByName(field).Interface()
+			}
+			return jsonKey(values)
+		}
+		if uniqueKeys(previous, byFields) && uniqueKeys(current, byFields) {
+			key = byFields
+		}
+	}
+
+	return differingItems(previous, current, key), differingItems(current, previous, key)
+}
+
+// differingItems returns the items of list whose key is missing from other or whose value differs.
+// Keys are counted, so that a value listed twice and then once is reported.
+func differingItems(list, other reflect.Value, key func(reflect.Value) string) reflect.Value {
+	others := make(map[string][]reflect.Value, other.Len())
+	for i := range other.Len() {
+		k := key(other.Index(i))
+		others[k] = append(others[k], other.Index(i))
+	}
+
+	items := reflect.MakeSlice(list.Type(), 0, 0)
+	for i := range list.Len() {
+		item := list.Index(i)
+		k := key(item)
+		matches := others[k]
+		if len(matches) > 0 && reflect.DeepEqual(matches[0].Interface(), item.Interface()) {
+			others[k] = matches[1:]
+			continue
+		}
+		items = reflect.Append(items, item)
+	}
+	return items
+}
+
+// jsonKey encodes a value as a map key, pointers being followed
+func jsonKey(value any) string {
+	data, _ := json.Marshal(value) // Entities and their key fields always encode
+	return string(data)
+}
+
+// uniqueKeys reports whether no two items of the list share a key
+func uniqueKeys(list reflect.Value, key func(reflect.Value) string) bool {
+	seen := make(map[string]bool, list.Len())
+	for i := range list.Len() {
+		k := key(list.Index(i))
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+	}
+	return true
+}
+
+// jsonName returns the JSON key of an exported field, or "" when it is not serialized
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
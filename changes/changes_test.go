@@ -0,0 +1,158 @@
+package changes
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func TestCompare(t *testing.T) {
+	previous := map[int]entities.Medicament{
+		1: {Cis: 1, Denomination: "DOLIPRANE", EtatComercialisation: "Commercialisée"},
+		2: {Cis: 2, Denomination: "ADVIL", VoiesAdministration: []string{"orale"}},
+		3: {Cis: 3, Denomination: "SPASFON"},
+	}
+	current := map[int]entities.Medicament{
+		1: {Cis: 1, Denomination: "DOLIPRANE", EtatComercialisation: "Non commercialisée"},
+		// Only the normalized field changed, which is not part of the API
+		2: {Cis: 2, Denomination: "ADVIL", DenominationNormalized: "advil", VoiesAdministration: []string{"orale"}},
+		5: {Cis: 5, Denomination: "NUROFEN"},
+		4: {Cis: 4, Denomination: "EFFERALGAN"},
+	}
+
+	diff := Compare(previous, current)
+
+	if fmt.Sprint(diff.Added) != "[4 5]" {
+		t.Errorf("Expected added [4 5], got %v", diff.Added)
+	}
+	if fmt.Sprint(diff.Removed) != "[3]" {
+		t.Errorf("Expected removed [3], got %v", diff.Removed)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0].ID != 1 {
+		t.Fatalf("Expected only CIS 1 modified, got %+v", diff.Modified)
+	}
+
+	field := diff.Modified[0].Fields
+	if len(field) != 1 || field[0].Field != "etatComercialisation" ||
+		field[0].Old != "Commercialisée" || field[0].New != "Non commercialisée" {
+		t.Errorf("Expected etatComercialisation change, got %+v", field)
+	}
+	if diff.Len() != 4 {
+		t.Errorf("Expected 4 changes, got %d", diff.Len())
+	}
+}
+
+func TestCompare_NestedFields(t *testing.T) {
+	previous := map[int]entities.Presentation{
+		3400912345678: {Cip13: 3400912345678, Prix: 2.5},
+	}
+	current := map[int]entities.Presentation{
+		3400912345678: {Cip13: 3400912345678, Prix: 2.5, Disponibilite: &entities.Disponibilite{CodeStatut: 1}},
+	}
+
+	diff := Compare(previous, current)
+
+	if len(diff.Modified) != 1 || len(diff.Modified[0].Fields) != 1 || diff.Modified[0].Fields[0].Field != "disponibilite" {
+		t.Fatalf("Expected disponibilite change, got %+v", diff.Modified)
+	}
+}
+
+func TestCompare_EmptyListsInJSON(t *testing.T) {
+	diff := Compare(map[int]entities.GeneriqueList{}, map[int]entities.GeneriqueList{})
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("Failed to marshal diff: %v", err)
+	}
+	if string(data) != `{"added":[],"removed":[],"modified":[]}` {
+		t.Errorf("Expected empty JSON lists, got %s", data)
+	}
+}
+
+func TestCompare_NestedLists(t *testing.T) {
+	previous := map[int]entities.Medicament{
+		1: {
+			Cis: 1,
+			Presentation: []entities.Presentation{
+				{Cip13: 3400900000001, Prix: 2.5},
+				{Cip13: 3400900000002, Prix: 4},
+				{Cip13: 3400900000003, Prix: 6},
+			},
+			Conditions: []string{"liste I", "réservé à l'usage hospitalier"},
+		},
+		2: {Cis: 2, Conditions: []string{"liste I", "liste II"}},
+	}
+	current := map[int]entities.Medicament{
+		1: {
+			Cis: 1,
+			Presentation: []entities.Presentation{
+				{Cip13: 3400900000002, Prix: 4.5},
+				{Cip13: 3400900000001, Prix: 2.5},
+				{Cip13: 3400900000004, Prix: 8},
+			},
+			Conditions: []string{"liste I"},
+		},
+		// Only the order changed
+		2: {Cis: 2, Conditions: []string{"liste II", "liste I"}},
+	}
+
+	diff := Compare(previous, current)
+	if len(diff.Modified) != 1 || diff.Modified[0].ID != 1 {
+		t.Fatalf("Expected only CIS 1 modified, got %+v", diff.Modified)
+	}
+
+	fields := diff.Modified[0].Fields
+	if len(fields) != 2 {
+		t.Fatalf("Expected presentation and conditions changes, got %+v", fields)
+	}
+
+	oldPresentations := fields[0].Old.([]entities.Presentation)
+	newPresentations := fields[0].New.([]entities.Presentation)
+	if fields[0].Field != "presentation" ||
+		fmt.Sprint(cip13s(oldPresentations)) != "[3400900000002 3400900000003]" ||
+		fmt.Sprint(cip13s(newPresentations)) != "[3400900000002 3400900000004]" {
+		t.Errorf("Expected the changed, removed and added presentations only, got %v -> %v", oldPresentations, newPresentations)
+	}
+	if newPresentations[0].Prix != 4.5 {
+		t.Errorf("Expected the new price of the changed presentation, got %v", newPresentations[0].Prix)
+	}
+
+	if fields[1].Field != "conditions" || fmt.Sprint(fields[1].Old) != "[réservé à l'usage hospitalier]" || fmt.Sprint(fields[1].New) != "[]" {
+		t.Errorf("Expected the removed condition only, got %v -> %v", fields[1].Old, fields[1].New)
+	}
+
+	data, err := json.Marshal(fields[1])
+	if err != nil {
+		t.Fatalf("Failed to marshal change: %v", err)
+	}
+	if string(data) != `{"field":"conditions","old":["réservé à l'usage hospitalier"],"new":[]}` {
+		t.Errorf("Expected JSON lists of the differing items, got %s", data)
+	}
+}
+
+func TestCompare_DuplicateKeys(t *testing.T) {
+	// Two opinions share their key fields: items are then matched by value
+	avis := entities.AvisHAS{CodeDossierHAS: "CT-1", MotifEvaluation: "Inscription", DateAvis: "20240101", Valeur: "Important"}
+	other := avis
+	other.Libelle = "Autre indication"
+	changed := other
+	changed.Valeur = "Modéré"
+
+	previous := map[int]entities.Medicament{1: {Cis: 1, SMR: []entities.AvisHAS{avis, other}}}
+	current := map[int]entities.Medicament{1: {Cis: 1, SMR: []entities.AvisHAS{avis, changed}}}
+
+	fields := Compare(previous, current).Modified[0].Fields
+	if len(fields) != 1 || len(fields[0].Old.([]entities.AvisHAS)) != 1 || fields[0].New.([]entities.AvisHAS)[0].Valeur != "Modéré" {
+		t.Errorf("Expected only the changed opinion, got %+v", fields)
+	}
+}
+
+func cip13s(presentations []entities.Presentation) []int {
+	ids := make([]int, len(presentations))
+	for i, p := range presentations {
+		ids[i] = p.Cip13
+	}
+	return ids
+}
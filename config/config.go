@@ -13,16 +13,27 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Port               string
-	Address            string
-	Env                Environment // Type-safe environment enum
-	LogLevel           string      // Console logging level (file logging is always DEBUG)
-	LogRetentionWeeks  int         // Number of weeks to keep log files
-	MaxLogFileSize     int64       // Maximum log file size in bytes
-	MaxRequestBody     int64       // Maximum request body size in bytes
-	MaxHeaderSize      int64       // Maximum header size in bytes
-	AllowDirectAccess  bool        // Allow 0.0.0.0/:: binding (staging/development only)
-	DisableRateLimiter bool        // Disable rate limiting middleware
+	Port                 string
+	Address              string
+	Env                  Environment // Type-safe environment enum
+	LogLevel             string      // Console logging level (file logging is always DEBUG)
+	LogRetentionWeeks    int         // Number of weeks to keep log files
+	MaxLogFileSize       int64       // Maximum log file size in bytes
+	MaxRequestBody       int64       // Maximum request body size in bytes
+	MaxHeaderSize        int64       // Maximum header size in bytes
+	AllowDirectAccess    bool        // Allow 0.0.0.0/:: binding (staging/development only)
+	DisableRateLimiter   bool        // Disable rate limiting middleware
+	ChangesRetention     int         // Number of update change sets served by /v1/changes
+	WebhooksFile         string      // JSON file of webhook subscriptions loaded at startup and saved by the admin API (optional)
+	WebhooksAllowPrivate bool        // Accept webhook URLs on loopback, private and link-local addresses
+	AdminToken           string      // Bearer token of the /admin endpoints, disabled when empty
+	EventsMaxClients     int         // Maximum concurrent /v1/events streams
+	SnapshotPath         string      // File the parsed dataset is saved to after each update
+	DisableSnapshot      bool        // Always download and parse at startup instead of serving the snapshot
+	BDPMLocalPath        string      // Directory or archive the BDPM files are read from instead of downloaded (offline mode)
+
+	DownloadRetries           int // Retries of a BDPM file after a transient failure
+	DownloadRetryDelaySeconds int // Delay before the first retry, doubled at each retry
 }
 
 // Environment represents the application environment
@@ -101,16 +112,27 @@ func Load() (*Config, error) {
 	}
 
 	cfg := &Config{
-		Port:               getEnvWithDefault("PORT", "8000"),
-		Address:            getEnvWithDefault("ADDRESS", "127.0.0.1"),
-		Env:                env, // Use parsed Environment enum
-		LogLevel:           getEnvWithDefault("LOG_LEVEL", "info"),
-		LogRetentionWeeks:  getIntEnvWithDefault("LOG_RETENTION_WEEKS", 4),         // 4 weeks default
-		MaxLogFileSize:     getInt64EnvWithDefault("MAX_LOG_FILE_SIZE", 104857600), // 100MB default
-		MaxRequestBody:     getInt64EnvWithDefault("MAX_REQUEST_BODY", 1048576),    // 1MB default
-		MaxHeaderSize:      getInt64EnvWithDefault("MAX_HEADER_SIZE", 1048576),     // 1MB default
-		AllowDirectAccess:  getBoolEnvWithDefault("ALLOW_DIRECT_ACCESS", false),
-		DisableRateLimiter: getBoolEnvWithDefault("DISABLE_RATE_LIMITER", false),
+		Port:                 getEnvWithDefault("PORT", "8000"),
+		Address:              getEnvWithDefault("ADDRESS", "127.0.0.1"),
+		Env:                  env, // Use parsed Environment enum
+		LogLevel:             getEnvWithDefault("LOG_LEVEL", "info"),
+		LogRetentionWeeks:    getIntEnvWithDefault("LOG_RETENTION_WEEKS", 4),         // 4 weeks default
+		MaxLogFileSize:       getInt64EnvWithDefault("MAX_LOG_FILE_SIZE", 104857600), // 100MB default
+		MaxRequestBody:       getInt64EnvWithDefault("MAX_REQUEST_BODY", 1048576),    // 1MB default
+		MaxHeaderSize:        getInt64EnvWithDefault("MAX_HEADER_SIZE", 1048576),     // 1MB default
+		AllowDirectAccess:    getBoolEnvWithDefault("ALLOW_DIRECT_ACCESS", false),
+		DisableRateLimiter:   getBoolEnvWithDefault("DISABLE_RATE_LIMITER", false),
+		ChangesRetention:     getIntEnvWithDefault("CHANGES_RETENTION", 14), // One week of updates
+		WebhooksFile:         getEnvWithDefault("WEBHOOKS_FILE", ""),
+		WebhooksAllowPrivate: getBoolEnvWithDefault("WEBHOOKS_ALLOW_PRIVATE", false),
+		AdminToken:           getEnvWithDefault("ADMIN_TOKEN", ""),
+		EventsMaxClients:     getIntEnvWithDefault("EVENTS_MAX_CLIENTS", 100),
+		SnapshotPath:         getEnvWithDefault("SNAPSHOT_PATH", "snapshots/dataset.gob.gz"),
+		DisableSnapshot:      getBoolEnvWithDefault("DISABLE_SNAPSHOT", false),
+		BDPMLocalPath:        getEnvWithDefault("BDPM_LOCAL_PATH", ""),
+
+		DownloadRetries:           getIntEnvWithDefault("DOWNLOAD_RETRIES", 3),
+		DownloadRetryDelaySeconds: getIntEnvWithDefault("DOWNLOAD_RETRY_DELAY_SECONDS", 2),
 	}
 
 	if err := validateConfig(cfg); err != nil {
@@ -159,6 +181,31 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("invalid MAX_LOG_FILE_SIZE: %w", err)
 	}
 
+	// Validate CHANGES_RETENTION
+	if err := validateChangesRetention(cfg.ChangesRetention); err != nil {
+		return fmt.Errorf("invalid CHANGES_RETENTION: %w", err)
+	}
+
+	// Validate ADMIN_TOKEN
+	if err := validateAdminToken(cfg.AdminToken); err != nil {
+		return fmt.Errorf("invalid ADMIN_TOKEN: %w", err)
+	}
+
+	// Validate EVENTS_MAX_CLIENTS
+	if err := validateEventsMaxClients(cfg.EventsMaxClients); err != nil {
+		return fmt.Errorf("invalid EVENTS_MAX_CLIENTS: %w", err)
+	}
+
+	// Validate BDPM_LOCAL_PATH
+	if err := validateBDPMLocalPath(cfg.BDPMLocalPath); err != nil {
+		return fmt.Errorf("invalid BDPM_LOCAL_PATH: %w", err)
+	}
+
+	// Validate DOWNLOAD_RETRIES and DOWNLOAD_RETRY_DELAY_SECONDS
+	if err := validateDownloadRetries(cfg.DownloadRetries, cfg.DownloadRetryDelaySeconds); err != nil {
+		return fmt.Errorf("invalid download retries: %w", err)
+	}
+
 	return nil
 }
 
@@ -281,6 +328,72 @@ func validateMaxLogFileSize(size int64) error {
 	return nil
 }
 
+// validateChangesRetention validates the CHANGES_RETENTION environment variable
+func validateChangesRetention(retention int) error {
+	if retention <= 0 {
+		return fmt.Errorf("CHANGES_RETENTION must be positive, got: %d", retention)
+	}
+
+	if retention > 730 { // 1 year of updates at two per day
+		return fmt.Errorf("CHANGES_RETENTION is too large (max 730), got: %d", retention)
+	}
+
+	return nil
+}
+
+// validateAdminToken validates the ADMIN_TOKEN environment variable
+func validateAdminToken(token string) error {
+	if token == "" { // Admin endpoints disabled
+		return nil
+	}
+
+	if len(token) < 32 {
+		return fmt.Errorf("ADMIN_TOKEN is too short (min 32 characters), got: %d", len(token))
+	}
+
+	return nil
+}
+
+// validateEventsMaxClients validates the EVENTS_MAX_CLIENTS environment variable
+func validateEventsMaxClients(maxClients int) error {
+	if maxClients <= 0 {
+		return fmt.Errorf("EVENTS_MAX_CLIENTS must be positive, got: %d", maxClients)
+	}
+
+	if maxClients > 10000 {
+		return fmt.Errorf("EVENTS_MAX_CLIENTS is too large (max 10000), got: %d", maxClients)
+	}
+
+	return nil
+}
+
+// validateDownloadRetries validates the DOWNLOAD_RETRIES and DOWNLOAD_RETRY_DELAY_SECONDS environment variables
+func validateDownloadRetries(retries int, delaySeconds int) error {
+	if retries < 0 || retries > 10 {
+		return fmt.Errorf("DOWNLOAD_RETRIES must be between 0 and 10, got: %d", retries)
+	}
+
+	// The last retry waits up to delay * 2^(retries-1), keep it within the 12 hours between updates
+	if delaySeconds <= 0 || delaySeconds > 300 {
+		return fmt.Errorf("DOWNLOAD_RETRY_DELAY_SECONDS must be between 1 and 300, got: %d", delaySeconds)
+	}
+
+	return nil
+}
+
+// validateBDPMLocalPath validates the BDPM_LOCAL_PATH environment variable
+func validateBDPMLocalPath(localPath string) error {
+	if localPath == "" { // Files are downloaded
+		return nil
+	}
+
+	if _, err := os.Stat(localPath); err != nil {
+		return fmt.Errorf("BDPM_LOCAL_PATH is not accessible: %w", err)
+	}
+
+	return nil
+}
+
 // getEnvWithDefault gets an environment variable with a default value
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -332,6 +445,16 @@ func GetEnvVars() []string {
 		"MAX_HEADER_SIZE",
 		"ALLOW_DIRECT_ACCESS",
 		"DISABLE_RATE_LIMITER",
+		"CHANGES_RETENTION",
+		"WEBHOOKS_FILE",
+		"WEBHOOKS_ALLOW_PRIVATE",
+		"ADMIN_TOKEN",
+		"EVENTS_MAX_CLIENTS",
+		"SNAPSHOT_PATH",
+		"DISABLE_SNAPSHOT",
+		"BDPM_LOCAL_PATH",
+		"DOWNLOAD_RETRIES",
+		"DOWNLOAD_RETRY_DELAY_SECONDS",
 	}
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -260,6 +261,16 @@ func TestGetEnvVars(t *testing.T) {
 		"MAX_HEADER_SIZE",
 		"ALLOW_DIRECT_ACCESS",
 		"DISABLE_RATE_LIMITER",
+		"CHANGES_RETENTION",
+		"WEBHOOKS_FILE",
+		"WEBHOOKS_ALLOW_PRIVATE",
+		"ADMIN_TOKEN",
+		"EVENTS_MAX_CLIENTS",
+		"SNAPSHOT_PATH",
+		"DISABLE_SNAPSHOT",
+		"BDPM_LOCAL_PATH",
+		"DOWNLOAD_RETRIES",
+		"DOWNLOAD_RETRY_DELAY_SECONDS",
 	}
 
 	if len(envVars) != len(expectedVars) {
@@ -449,6 +460,216 @@ func TestDisableRateLimiter(t *testing.T) {
 	}
 }
 
+func TestChangesRetention(t *testing.T) {
+	tests := []struct {
+		name          string
+		envValue      string
+		expectedValue int
+		expectError   bool
+	}{
+		{"CHANGES_RETENTION not set", "", 14, false},
+		{"CHANGES_RETENTION=30", "30", 30, false},
+		{"CHANGES_RETENTION=0", "0", 0, true},
+		{"CHANGES_RETENTION too large", "1000", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv("PORT", "8003")
+			_ = os.Setenv("ADDRESS", "127.0.0.1")
+			_ = os.Setenv("ENV", "dev")
+			_ = os.Setenv("LOG_LEVEL", "info")
+			_ = os.Setenv("CHANGES_RETENTION", tt.envValue)
+			defer func() { _ = os.Unsetenv("CHANGES_RETENTION") }()
+			defer cleanupEnv()
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %s", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.ChangesRetention != tt.expectedValue {
+				t.Errorf("Expected ChangesRetention=%d, got %d", tt.expectedValue, cfg.ChangesRetention)
+			}
+		})
+	}
+}
+
+func TestEventsMaxClients(t *testing.T) {
+	tests := []struct {
+		name          string
+		envValue      string
+		expectedValue int
+		expectError   bool
+	}{
+		{"EVENTS_MAX_CLIENTS not set", "", 100, false},
+		{"EVENTS_MAX_CLIENTS=500", "500", 500, false},
+		{"EVENTS_MAX_CLIENTS=0", "0", 0, true},
+		{"EVENTS_MAX_CLIENTS too large", "20000", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv("PORT", "8003")
+			_ = os.Setenv("ADDRESS", "127.0.0.1")
+			_ = os.Setenv("ENV", "dev")
+			_ = os.Setenv("LOG_LEVEL", "info")
+			_ = os.Setenv("EVENTS_MAX_CLIENTS", tt.envValue)
+			defer func() { _ = os.Unsetenv("EVENTS_MAX_CLIENTS") }()
+			defer cleanupEnv()
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %s", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.EventsMaxClients != tt.expectedValue {
+				t.Errorf("Expected EventsMaxClients=%d, got %d", tt.expectedValue, cfg.EventsMaxClients)
+			}
+		})
+	}
+}
+
+func TestBDPMLocalPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		envValue    string
+		expectError bool
+	}{
+		{"BDPM_LOCAL_PATH not set", "", false},
+		{"BDPM_LOCAL_PATH existing directory", t.TempDir(), false},
+		{"BDPM_LOCAL_PATH missing", "/nonexistent/bdpm", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv("PORT", "8003")
+			_ = os.Setenv("ADDRESS", "127.0.0.1")
+			_ = os.Setenv("ENV", "dev")
+			_ = os.Setenv("LOG_LEVEL", "info")
+			_ = os.Setenv("BDPM_LOCAL_PATH", tt.envValue)
+			defer func() { _ = os.Unsetenv("BDPM_LOCAL_PATH") }()
+			defer cleanupEnv()
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %s", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.BDPMLocalPath != tt.envValue {
+				t.Errorf("Expected BDPMLocalPath=%q, got %q", tt.envValue, cfg.BDPMLocalPath)
+			}
+		})
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		retries       string
+		delaySeconds  string
+		expectedRetry int
+		expectedDelay int
+		expectError   bool
+	}{
+		{"defaults", "", "", 3, 2, false},
+		{"custom values", "5", "10", 5, 10, false},
+		{"retries disabled", "0", "", 0, 2, false},
+		{"negative retries", "-1", "", 0, 0, true},
+		{"too many retries", "11", "", 0, 0, true},
+		{"zero delay", "", "0", 0, 0, true},
+		{"delay too long", "", "301", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv("PORT", "8003")
+			_ = os.Setenv("ADDRESS", "127.0.0.1")
+			_ = os.Setenv("ENV", "dev")
+			_ = os.Setenv("LOG_LEVEL", "info")
+			_ = os.Setenv("DOWNLOAD_RETRIES", tt.retries)
+			_ = os.Setenv("DOWNLOAD_RETRY_DELAY_SECONDS", tt.delaySeconds)
+			defer func() {
+				_ = os.Unsetenv("DOWNLOAD_RETRIES")
+				_ = os.Unsetenv("DOWNLOAD_RETRY_DELAY_SECONDS")
+			}()
+			defer cleanupEnv()
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %s", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.DownloadRetries != tt.expectedRetry || cfg.DownloadRetryDelaySeconds != tt.expectedDelay {
+				t.Errorf("Expected %d retries after %ds, got %d after %ds", tt.expectedRetry, tt.expectedDelay, cfg.DownloadRetries, cfg.DownloadRetryDelaySeconds)
+			}
+		})
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		envValue    string
+		expectError bool
+	}{
+		{"ADMIN_TOKEN not set", "", false},
+		{"ADMIN_TOKEN long enough", strings.Repeat("a", 32), false},
+		{"ADMIN_TOKEN too short", "secret", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv("PORT", "8003")
+			_ = os.Setenv("ADDRESS", "127.0.0.1")
+			_ = os.Setenv("ENV", "dev")
+			_ = os.Setenv("LOG_LEVEL", "info")
+			_ = os.Setenv("ADMIN_TOKEN", tt.envValue)
+			defer func() { _ = os.Unsetenv("ADMIN_TOKEN") }()
+			defer cleanupEnv()
+
+			cfg, err := Load()
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %s", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if cfg.AdminToken != tt.envValue {
+				t.Errorf("Expected AdminToken=%q, got %q", tt.envValue, cfg.AdminToken)
+			}
+		})
+	}
+}
+
 func TestValidateAddress_0dot0dot0dot0dot0_WithoutAllowDirectAccess(t *testing.T) {
 	cfg := &Config{
 		Address:            "0.0.0.0",
@@ -4,12 +4,20 @@
 package data
 
 import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // Compile-time check to ensure DataContainer implements DataStore
@@ -17,119 +25,186 @@ var _ interfaces.DataStore = (*DataContainer)(nil)
 
 // DataContainer holds all the data with atomic pointers for zero-downtime updates
 type DataContainer struct {
-	medicaments           atomic.Value // []entities.Medicament
-	generiques            atomic.Value // []entities.GeneriqueList
-	medicamentsMap        atomic.Value // map[int]entities.Medicament
-	generiquesMap         atomic.Value // map[int]entities.GeneriqueList
-	presentationsCIP7Map  atomic.Value //map[int]entities.Presentation
-	presentationsCIP13Map atomic.Value //map[int]entities.Presentation
-	lastUpdated           atomic.Value // time.Time
-	updating              atomic.Bool
-	serverStartTime       atomic.Value // time.Time
-	dataQualityReport     atomic.Value // *interfaces.DataQualityReport
+	versioned         atomic.Value // *versionedData
+	lastChecked       atomic.Value // time.Time
+	updating          atomic.Bool
+	serverStartTime   atomic.Value // time.Time
+	dataQualityReport atomic.Value // *interfaces.DataQualityReport
+	changes           atomic.Value // []changes.ChangeSet
+	changesRetention  atomic.Int64
 }
 
+// versionedData pairs the dataset with the exports built from it, swapped as a whole by an update.
+// The exports are built on their first request and then kept with their dataset: an update does not
+// hold a second copy of the data in every encoding, and a version never serves the files of another.
+type versionedData struct {
+	dataset        *interfaces.Dataset
+	exportArtifact func() *artifact.Artifact // nil before the first update
+	exports        func() *export.Exports    // nil before the first update
+}
+
+// newVersionedData prepares the lazy exports of a dataset
+func newVersionedData(dataset *interfaces.Dataset) *versionedData {
+	return &versionedData{
+		dataset: dataset,
+		exportArtifact: sync.OnceValue(func() *artifact.Artifact {
+			return buildExportArtifact(dataset.Medicaments, dataset.Version.LoadedAt)
+		}),
+		exports: sync.OnceValue(func() *export.Exports {
+			return buildTabularExports(dataset.Medicaments)
+		}),
+	}
+}
+
+// DefaultChangesRetention is the number of change sets kept, one week of updates at two per day
+const DefaultChangesRetention = 14
+
 // NewDataContainer creates a new DataContainer with empty data
 func NewDataContainer() *DataContainer {
 	dc := &DataContainer{}
-	dc.medicaments.Store(make([]entities.Medicament, 0))
-	dc.generiques.Store(make([]entities.GeneriqueList, 0))
-	dc.medicamentsMap.Store(make(map[int]entities.Medicament))
-	dc.generiquesMap.Store(make(map[int]entities.GeneriqueList))
-	dc.presentationsCIP7Map.Store(make(map[int]entities.Presentation))
-	dc.presentationsCIP13Map.Store(make(map[int]entities.Presentation))
-	dc.lastUpdated.Store(time.Time{})
+	dc.versioned.Store(&versionedData{dataset: emptyDataset()})
+	dc.lastChecked.Store(time.Time{})
 	dc.serverStartTime.Store(time.Time{}) // Initialize with zero value
 	dc.dataQualityReport.Store(&interfaces.DataQualityReport{})
+	dc.changes.Store(make([]changes.ChangeSet, 0))
+	dc.changesRetention.Store(DefaultChangesRetention)
 	return dc
 }
 
-// Thread-safe getters with type check
+// newDataset builds the derived data of a dataset: shortages, indexes and sort orders
+func newDataset(medicaments []entities.Medicament, generiques []entities.GeneriqueList,
+	medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList,
+	presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation,
+	version interfaces.DataVersion) *interfaces.Dataset {
+	dataset := &interfaces.Dataset{
+		Version:               version,
+		Medicaments:           medicaments,
+		Generiques:            generiques,
+		MedicamentsMap:        medicamentsMap,
+		GeneriquesMap:         generiquesMap,
+		PresentationsCIP7Map:  presentationsCIP7Map,
+		PresentationsCIP13Map: presentationsCIP13Map,
+	}
+	dataset.Disponibilites = collectDisponibilites(dataset.Medicaments)
+	dataset.SearchIndex = search.NewIndex(dataset.Medicaments)
+	dataset.SubstanceIndex = search.NewSubstanceIndex(dataset.Medicaments)
+	dataset.Orders = search.NewOrders(dataset.Medicaments)
+	return dataset
+}
 
-// GetMedicaments returns the list of medicaments
-func (dc *DataContainer) GetMedicaments() []entities.Medicament {
-	if v := dc.medicaments.Load(); v != nil {
-		if medicaments, ok := v.([]entities.Medicament); ok {
-			return medicaments
-		}
+// emptyDataset is the dataset served before the first update
+func emptyDataset() *interfaces.Dataset {
+	return newDataset(make([]entities.Medicament, 0), make([]entities.GeneriqueList, 0),
+		make(map[int]entities.Medicament), make(map[int]entities.GeneriqueList),
+		make(map[int]entities.Presentation), make(map[int]entities.Presentation), interfaces.DataVersion{})
+}
+
+// current returns the data being served
+func (dc *DataContainer) current() *versionedData {
+	if versioned, ok := dc.versioned.Load().(*versionedData); ok {
+		return versioned
 	}
 
-	logging.Warn("Medicaments list is empty or invalid")
-	return []entities.Medicament{}
+	logging.Warn("Dataset is empty or invalid")
+	return &versionedData{dataset: emptyDataset()}
+}
+
+// Thread-safe getters, each reading the dataset being served
+
+// GetDataset returns the dataset being served
+func (dc *DataContainer) GetDataset() *interfaces.Dataset {
+	return dc.current().dataset
+}
+
+// GetMedicaments returns the list of medicaments
+func (dc *DataContainer) GetMedicaments() []entities.Medicament {
+	return dc.GetDataset().Medicaments
 }
 
 // GetGeneriques returns the list of generiques
 func (dc *DataContainer) GetGeneriques() []entities.GeneriqueList {
-	if v := dc.generiques.Load(); v != nil {
-		if generiques, ok := v.([]entities.GeneriqueList); ok {
-			return generiques
-		}
-	}
-
-	logging.Warn("GeneriqueList is empty or invalid")
-	return []entities.GeneriqueList{}
+	return dc.GetDataset().Generiques
 }
 
 // GetMedicamentsMap returns the medicaments map for O(1) lookups
 func (dc *DataContainer) GetMedicamentsMap() map[int]entities.Medicament {
-	if v := dc.medicamentsMap.Load(); v != nil {
-		if medicamentsMap, ok := v.(map[int]entities.Medicament); ok {
-			return medicamentsMap
-		}
-	}
-
-	logging.Warn("MedicamentsMap is empty or invalid")
-	return make(map[int]entities.Medicament)
+	return dc.GetDataset().MedicamentsMap
 }
 
 // GetGeneriquesMap returns the generiques map for O(1) lookups
 func (dc *DataContainer) GetGeneriquesMap() map[int]entities.GeneriqueList {
-	if v := dc.generiquesMap.Load(); v != nil {
-		if generiquesMap, ok := v.(map[int]entities.GeneriqueList); ok {
-			return generiquesMap
-		}
-	}
-
-	logging.Warn("GeneriquesMap is empty or invalid")
-	return make(map[int]entities.GeneriqueList)
+	return dc.GetDataset().GeneriquesMap
 }
 
-// GetPresentationsCIP7Map returns the generiques map for O(1) lookups
+// GetPresentationsCIP7Map returns the presentations map by CIP7 for O(1) lookups
 func (dc *DataContainer) GetPresentationsCIP7Map() map[int]entities.Presentation {
-	if v := dc.presentationsCIP7Map.Load(); v != nil {
-		if presentationsCIP7Map, ok := v.(map[int]entities.Presentation); ok {
-			return presentationsCIP7Map
-		}
-	}
-
-	logging.Warn("presentationsCIP7Map is empty or invalid")
-	return make(map[int]entities.Presentation)
+	return dc.GetDataset().PresentationsCIP7Map
 }
 
-// GetPresentationsCIP13Map returns the generiques map for O(1) lookups
+// GetPresentationsCIP13Map returns the presentations map by CIP13 for O(1) lookups
 func (dc *DataContainer) GetPresentationsCIP13Map() map[int]entities.Presentation {
-	if v := dc.presentationsCIP13Map.Load(); v != nil {
-		if presentationsCIP13Map, ok := v.(map[int]entities.Presentation); ok {
-			return presentationsCIP13Map
-		}
-	}
+	return dc.GetDataset().PresentationsCIP13Map
+}
+
+// GetDisponibilites returns the reported shortages, sorted by CIS then CIP13
+func (dc *DataContainer) GetDisponibilites() []entities.Disponibilite {
+	return dc.GetDataset().Disponibilites
+}
+
+// GetSearchIndex returns the full-text index built from the current medicaments
+func (dc *DataContainer) GetSearchIndex() *search.Index {
+	return dc.GetDataset().SearchIndex
+}
 
-	logging.Warn("presentationsCIP13Map is empty or invalid")
-	return make(map[int]entities.Presentation)
+// GetSubstanceIndex returns the active substances index built from the current medicaments
+func (dc *DataContainer) GetSubstanceIndex() *search.SubstanceIndex {
+	return dc.GetDataset().SubstanceIndex
+}
+
+// GetMedicamentOrders returns the medicaments pre-sorted on every sort field
+func (dc *DataContainer) GetMedicamentOrders() search.Orders {
+	return dc.GetDataset().Orders
 }
 
 // GetLastUpdated returns the timestamp of the last data update
 func (dc *DataContainer) GetLastUpdated() time.Time {
-	if v := dc.lastUpdated.Load(); v != nil {
-		if lastUpdated, ok := v.(time.Time); ok {
-			return lastUpdated
+	return dc.GetDataset().Version.LoadedAt
+}
+
+// GetLastChecked returns when the sources were last checked: the last data update,
+// or a later update that found them unchanged
+func (dc *DataContainer) GetLastChecked() time.Time {
+	if v := dc.lastChecked.Load(); v != nil {
+		if lastChecked, ok := v.(time.Time); ok {
+			return lastChecked
 		}
 	}
 
-	logging.Warn("Could not get the last updated value")
+	logging.Warn("Could not get the last checked value")
 	return time.Time{}
 }
 
+// MarkChecked records that the sources were checked and the current data is still up to date
+func (dc *DataContainer) MarkChecked() {
+	dc.lastChecked.Store(time.Now())
+}
+
+// GetDataVersion returns the version of the loaded dataset, zero before the first update
+func (dc *DataContainer) GetDataVersion() interfaces.DataVersion {
+	return dc.GetDataset().Version
+}
+
+// GetTabularExports returns the CSV and Parquet exports with the version they were built from.
+// The first call for a version builds them.
+// The exports are nil before the first update and when they could not be built.
+func (dc *DataContainer) GetTabularExports() (*export.Exports, interfaces.DataVersion) {
+	versioned := dc.current()
+	if versioned.exports == nil {
+		return nil, versioned.dataset.Version
+	}
+	return versioned.exports(), versioned.dataset.Version
+}
+
 // IsUpdating returns true if a data update is currently in progress
 func (dc *DataContainer) IsUpdating() bool {
 	return dc.updating.Load()
@@ -164,20 +239,57 @@ func (dc *DataContainer) GetDataQualityReport() *interfaces.DataQualityReport {
 	return &interfaces.DataQualityReport{}
 }
 
-// UpdateData atomically updates all data in the container
+// GetChanges returns the retained change sets, oldest first
+func (dc *DataContainer) GetChanges() []changes.ChangeSet {
+	if v := dc.changes.Load(); v != nil {
+		if changeSets, ok := v.([]changes.ChangeSet); ok {
+			return changeSets
+		}
+	}
+
+	logging.Warn("Could not get the changes value")
+	return []changes.ChangeSet{}
+}
+
+// GetExportArtifact returns the precompressed full export with the version it was built from.
+// The export is nil before the first update and when it could not be built, a build failure
+// is logged once by buildExportArtifact.
+func (dc *DataContainer) GetExportArtifact() (*artifact.Artifact, interfaces.DataVersion) {
+	versioned := dc.current()
+	if versioned.exportArtifact == nil {
+		return nil, versioned.dataset.Version
+	}
+	return versioned.exportArtifact(), versioned.dataset.Version
+}
+
+// RecordChanges appends a change set, dropping the oldest ones beyond the retention
+func (dc *DataContainer) RecordChanges(changeSet changes.ChangeSet) {
+	// Copy so that readers keep a consistent slice
+	changeSets := append(slices.Clone(dc.GetChanges()), changeSet)
+	if excess := len(changeSets) - int(dc.changesRetention.Load()); excess > 0 {
+		changeSets = changeSets[excess:]
+	}
+	dc.changes.Store(changeSets)
+}
+
+// SetChangesRetention sets how many change sets are kept
+func (dc *DataContainer) SetChangesRetention(retention int) {
+	dc.changesRetention.Store(int64(retention))
+}
+
+// UpdateData atomically updates all data in the container, loaded as the given version
 func (dc *DataContainer) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList,
 	medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList,
 	presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation,
-	report *interfaces.DataQualityReport) {
+	report *interfaces.DataQualityReport, version interfaces.DataVersion) {
+
+	// Build derived data before the swap so readers never wait on it
+	dataset := newDataset(medicaments, generiques, medicamentsMap, generiquesMap,
+		presentationsCIP7Map, presentationsCIP13Map, version)
 
 	// Atomic swap (zero downtime replacement)
-	dc.medicaments.Store(medicaments)
-	dc.medicamentsMap.Store(medicamentsMap)
-	dc.generiques.Store(generiques)
-	dc.generiquesMap.Store(generiquesMap)
-	dc.presentationsCIP7Map.Store(presentationsCIP7Map)
-	dc.presentationsCIP13Map.Store(presentationsCIP13Map)
-	dc.lastUpdated.Store(time.Now())
+	dc.versioned.Store(newVersionedData(dataset))
+	dc.lastChecked.Store(version.LoadedAt)
 	dc.dataQualityReport.Store(report)
 }
 
@@ -191,3 +303,55 @@ func (dc *DataContainer) BeginUpdate() bool {
 func (dc *DataContainer) EndUpdate() {
 	dc.updating.Store(false)
 }
+
+// buildExportArtifact encodes and compresses the full export once per version, instead of on every request.
+// It returns nil on failure, the export is then encoded for each request.
+func buildExportArtifact(medicaments []entities.Medicament, modTime time.Time) *artifact.Artifact {
+	data, err := json.Marshal(medicaments)
+	if err != nil {
+		logging.Warn("Could not encode the export", "error", err)
+		return nil
+	}
+
+	exportArtifact, err := artifact.New("application/json; charset=utf-8", data, modTime)
+	if err != nil {
+		logging.Warn("Could not compress the export", "error", err)
+		return nil
+	}
+	return exportArtifact
+}
+
+// buildTabularExports encodes the CSV and Parquet exports once per version, instead of on every request.
+// It returns nil on failure, the tabular exports are then unavailable until the next update.
+func buildTabularExports(medicaments []entities.Medicament) *export.Exports {
+	exports, err := export.Build(medicaments)
+	if err != nil {
+		logging.Warn("Could not build the tabular exports", "error", err)
+		return nil
+	}
+	return exports
+}
+
+// collectDisponibilites gathers the shortages attached to the presentations of the medicaments.
+// A status declared for a whole CIS is attached to each of its presentations and only listed once.
+// Statuses are compared by value: presentations restored from a snapshot no longer share a pointer.
+func collectDisponibilites(medicaments []entities.Medicament) []entities.Disponibilite {
+	seen := make(map[entities.Disponibilite]bool)
+	disponibilites := make([]entities.Disponibilite, 0)
+
+	for _, med := range medicaments {
+		for _, pres := range med.Presentation {
+			if pres.Disponibilite == nil || seen[*pres.Disponibilite] {
+				continue
+			}
+			seen[*pres.Disponibilite] = true
+			disponibilites = append(disponibilites, *pres.Disponibilite)
+		}
+	}
+
+	slices.SortFunc(disponibilites, func(a, b entities.Disponibilite) int {
+		return cmp.Or(cmp.Compare(a.Cis, b.Cis), cmp.Compare(a.Cip13, b.Cip13))
+	})
+
+	return disponibilites
+}
@@ -135,7 +135,7 @@ func TestDataContainer_ConcurrentReads(t *testing.T) {
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, nextVersion(container))
 
 	// Concurrent reads
 	var wg sync.WaitGroup
@@ -175,7 +175,7 @@ func TestDataContainer_ConcurrentReadsDuringUpdate(t *testing.T) {
 	presentationsCIP13Map := map[int]entities.Presentation{}
 
 	container.UpdateData(medicaments, generiques, medicamentsMap, generiquesMap,
-		presentationsCIP7Map, presentationsCIP13Map, nil)
+		presentationsCIP7Map, presentationsCIP13Map, nil, nextVersion(container))
 
 	// Begin update
 	container.BeginUpdate()
@@ -210,7 +210,7 @@ func TestDataContainer_UpdateDataWithNil(t *testing.T) {
 	container := NewDataContainer()
 
 	// Update with nil medicaments
-	container.UpdateData(nil, nil, nil, nil, nil, nil, nil)
+	container.UpdateData(nil, nil, nil, nil, nil, nil, nil, nextVersion(container))
 
 	// Get data - should return empty slices (not nil) for safety
 	medicaments := container.GetMedicaments()
@@ -239,7 +239,7 @@ func TestDataContainer_UpdateDataWithEmptySlices(t *testing.T) {
 	container := NewDataContainer()
 
 	// Update with empty slices
-	container.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{}, map[int]entities.Medicament{}, map[int]entities.GeneriqueList{}, map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil)
+	container.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{}, map[int]entities.Medicament{}, map[int]entities.GeneriqueList{}, map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(container))
 
 	// Verify data was stored
 	if len(container.GetMedicaments()) != 0 {
@@ -291,7 +291,7 @@ func TestDataContainer_ThreadSafety(t *testing.T) {
 			newMedicaments[0].Cis = id + 100
 
 			container.UpdateData(newMedicaments, generiques, medicamentsMap, generiquesMap,
-				presentationsCIP7Map, presentationsCIP13Map, nil)
+				presentationsCIP7Map, presentationsCIP13Map, nil, nextVersion(container))
 
 			// Read data
 			_ = container.GetMedicaments()
@@ -323,7 +323,7 @@ func TestDataContainer_GetLastUpdated(t *testing.T) {
 	presentationsCIP13Map := map[int]entities.Presentation{}
 
 	container.UpdateData(medicaments, generiques, medicamentsMap, generiquesMap,
-		presentationsCIP7Map, presentationsCIP13Map, nil)
+		presentationsCIP7Map, presentationsCIP13Map, nil, nextVersion(container))
 
 	// Should now have a time
 	lastUpdated = container.GetLastUpdated()
@@ -336,3 +336,31 @@ func TestDataContainer_GetLastUpdated(t *testing.T) {
 		t.Errorf("Last updated time too old: %v", lastUpdated)
 	}
 }
+
+func TestDataContainer_MarkChecked(t *testing.T) {
+	container := NewDataContainer()
+
+	if !container.GetLastChecked().IsZero() {
+		t.Error("Last checked should initially be zero time")
+	}
+
+	container.UpdateData([]entities.Medicament{{Cis: 1, Denomination: "Test"}}, []entities.GeneriqueList{},
+		map[int]entities.Medicament{1: {Cis: 1, Denomination: "Test"}}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(container))
+
+	lastUpdated := container.GetLastUpdated()
+	if !container.GetLastChecked().Equal(lastUpdated) {
+		t.Errorf("Expected an update to set last checked to %v, got %v", lastUpdated, container.GetLastChecked())
+	}
+	version := container.GetDataVersion()
+
+	time.Sleep(time.Millisecond)
+	container.MarkChecked()
+
+	if !container.GetLastChecked().After(lastUpdated) {
+		t.Error("Expected MarkChecked to move last checked forward")
+	}
+	if !container.GetLastUpdated().Equal(lastUpdated) || container.GetDataVersion() != version {
+		t.Error("Expected MarkChecked to keep the last update and the data version")
+	}
+}
@@ -1,16 +1,26 @@
 package data
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
+// nextVersion returns the version of the next update, as the scheduler numbers them
+func nextVersion(dc *DataContainer) interfaces.DataVersion {
+	return interfaces.DataVersion{Version: dc.GetDataVersion().Version + 1, LoadedAt: time.Now()}
+}
+
 func TestNewDataContainer(t *testing.T) {
 	logging.InitLogger("")
 
@@ -92,7 +102,7 @@ func TestUpdateData(t *testing.T) {
 		MedicamentsWithoutPresentationsCIS: []int{},
 		MedicamentsWithoutCompositionsCIS:  []int{},
 		GeneriqueOnlyCISList:               []int{},
-	})
+	}, nextVersion(dc))
 
 	// Verify data was updated
 	retrievedMedicaments := dc.GetMedicaments()
@@ -200,7 +210,7 @@ func TestConcurrentAccess(t *testing.T) {
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, nextVersion(dc))
 
 	var wg sync.WaitGroup
 	numReaders := 10
@@ -285,7 +295,7 @@ func TestConcurrentAccess(t *testing.T) {
 							MedicamentsWithoutPresentationsCIS: []int{},
 							MedicamentsWithoutCompositionsCIS:  []int{},
 							GeneriqueOnlyCISList:               []int{},
-						})
+						}, nextVersion(dc))
 					dc.EndUpdate()
 				}
 
@@ -328,7 +338,7 @@ func TestAtomicSwapZeroDowntime(t *testing.T) {
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, nextVersion(dc))
 
 	// Start a reader that continuously reads data
 	stop := make(chan bool)
@@ -363,7 +373,7 @@ func TestAtomicSwapZeroDowntime(t *testing.T) {
 		dc.UpdateData(newMedicaments, []entities.GeneriqueList{},
 			map[int]entities.Medicament{i + 2: {Cis: i + 2, Denomination: "Update"}},
 			map[int]entities.GeneriqueList{},
-			map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil)
+			map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
 	}
 
 	// Stop the reader
@@ -569,7 +579,7 @@ func BenchmarkGetMedicaments(b *testing.B) {
 	}
 	dc.UpdateData(medicaments, []entities.GeneriqueList{},
 		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
-		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil)
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -589,7 +599,7 @@ func BenchmarkGetMedicamentsMap(b *testing.B) {
 	}
 	dc.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{},
 		medicamentsMap, map[int]entities.GeneriqueList{},
-		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil)
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
 
 	b.ResetTimer()
 	for b.Loop() {
@@ -625,7 +635,7 @@ func BenchmarkUpdateData(b *testing.B) {
 	b.ResetTimer()
 	for b.Loop() {
 		dc.UpdateData(medicaments, generiques, medicamentsMap, generiquesMap,
-			map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil)
+			map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
 	}
 }
 
@@ -648,7 +658,7 @@ func TestGetPresentationsCIP7Map(t *testing.T) {
 
 	dc.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{},
 		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
-		testPresentations, map[int]entities.Presentation{}, nil)
+		testPresentations, map[int]entities.Presentation{}, nil, nextVersion(dc))
 
 	// Verify data was stored
 	retrievedMap := dc.GetPresentationsCIP7Map()
@@ -685,7 +695,7 @@ func TestGetPresentationsCIP13Map(t *testing.T) {
 
 	dc.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{},
 		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
-		map[int]entities.Presentation{}, testPresentations, nil)
+		map[int]entities.Presentation{}, testPresentations, nil, nextVersion(dc))
 
 	// Verify data was stored
 	retrievedMap := dc.GetPresentationsCIP13Map()
@@ -718,7 +728,7 @@ func TestPresentationMapsConcurrentAccess(t *testing.T) {
 
 	dc.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{},
 		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
-		cip7Map, cip13Map, nil)
+		cip7Map, cip13Map, nil, nextVersion(dc))
 
 	var wg sync.WaitGroup
 	numReaders := 20
@@ -761,7 +771,7 @@ func TestPresentationMapsConcurrentAccess(t *testing.T) {
 				}
 				dc.UpdateData([]entities.Medicament{}, []entities.GeneriqueList{},
 					map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
-					newCIP7, newCIP13, nil)
+					newCIP7, newCIP13, nil, nextVersion(dc))
 			}
 		}(i)
 	}
@@ -830,6 +840,7 @@ func TestGetDataQualityReport(t *testing.T) {
 		map[int]entities.Presentation{},
 		map[int]entities.Presentation{},
 		testReport,
+		nextVersion(dc),
 	)
 
 	// Retrieve and verify the report
@@ -893,6 +904,7 @@ func TestGetDataQualityReportConcurrentAccess(t *testing.T) {
 		map[int]entities.Presentation{},
 		map[int]entities.Presentation{},
 		initialReport,
+		nextVersion(dc),
 	)
 
 	var wg sync.WaitGroup
@@ -933,6 +945,7 @@ func TestGetDataQualityReportConcurrentAccess(t *testing.T) {
 					map[int]entities.Presentation{},
 					map[int]entities.Presentation{},
 					newReport,
+					nextVersion(dc),
 				)
 			}
 		}(i)
@@ -946,3 +959,274 @@ func TestGetDataQualityReportConcurrentAccess(t *testing.T) {
 		t.Error("Final report should not be nil")
 	}
 }
+
+func TestGetDisponibilites(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if len(dc.GetDisponibilites()) != 0 {
+		t.Errorf("Expected no disponibilites initially, got %d", len(dc.GetDisponibilites()))
+	}
+
+	// A CIS-level status is attached to every presentation of the CIS. After a snapshot reload
+	// each presentation has its own copy, so it must not be listed once per presentation.
+	cisLevel := &entities.Disponibilite{Cis: 2, CodeStatut: 2}
+	cisLevelCopy := &entities.Disponibilite{Cis: 2, CodeStatut: 2}
+	cipLevel := &entities.Disponibilite{Cis: 1, Cip13: 3400900000001, CodeStatut: 1}
+
+	medicaments := []entities.Medicament{
+		{Cis: 2, Presentation: []entities.Presentation{
+			{Cis: 2, Cip13: 3400900000002, Disponibilite: cisLevel},
+			{Cis: 2, Cip13: 3400900000003, Disponibilite: cisLevelCopy},
+		}},
+		{Cis: 1, Presentation: []entities.Presentation{
+			{Cis: 1, Cip13: 3400900000001, Disponibilite: cipLevel},
+			{Cis: 1, Cip13: 3400900000004},
+		}},
+	}
+
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	disponibilites := dc.GetDisponibilites()
+	if len(disponibilites) != 2 {
+		t.Fatalf("Expected 2 disponibilites, got %d", len(disponibilites))
+	}
+
+	// Sorted by CIS
+	if disponibilites[0].Cis != 1 || disponibilites[1].Cis != 2 {
+		t.Errorf("Expected disponibilites sorted by CIS, got %d then %d", disponibilites[0].Cis, disponibilites[1].Cis)
+	}
+}
+
+func TestGetSearchIndex(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if dc.GetSearchIndex().Len() != 0 {
+		t.Errorf("Expected empty search index initially, got %d documents", dc.GetSearchIndex().Len())
+	}
+
+	medicaments := []entities.Medicament{
+		{Cis: 1, DenominationNormalized: "doliprane 1000 mg"},
+		{Cis: 2, DenominationNormalized: "efferalgan 500 mg"},
+	}
+
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	index := dc.GetSearchIndex()
+	if index.Len() != 2 {
+		t.Fatalf("Expected 2 indexed documents, got %d", index.Len())
+	}
+
+	results := index.Search("doliprane")
+	if len(results) != 1 || results[0].Cis != 1 {
+		t.Errorf("Expected CIS 1 for 'doliprane', got %+v", results)
+	}
+}
+
+func TestGetSubstanceIndex(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if len(dc.GetSubstanceIndex().Substances()) != 0 {
+		t.Errorf("Expected empty substance index initially, got %d substances", len(dc.GetSubstanceIndex().Substances()))
+	}
+
+	medicaments := []entities.Medicament{
+		{Cis: 1, Composition: []entities.Composition{{CodeSubstance: 2202, DenominationSubstance: "PARACETAMOL"}}},
+		{Cis: 2, Composition: []entities.Composition{{CodeSubstance: 2202, DenominationSubstance: "PARACETAMOL"}}},
+	}
+
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	substance, ok := dc.GetSubstanceIndex().Get(2202)
+	if !ok || substance.MedicamentsCount != 2 {
+		t.Errorf("Expected substance 2202 in 2 medicaments, got %+v", substance)
+	}
+}
+
+func TestGetMedicamentOrders(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if order := dc.GetMedicamentOrders()[search.SortCIS]; order == nil || order.Len() != 0 {
+		t.Errorf("Expected empty cis order initially")
+	}
+
+	medicaments := []entities.Medicament{
+		{Cis: 2, DenominationNormalized: "b"},
+		{Cis: 1, DenominationNormalized: "c"},
+		{Cis: 3, DenominationNormalized: "a"},
+	}
+
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	order := dc.GetMedicamentOrders()[search.SortDenomination]
+	if order == nil || order.Len() != 3 {
+		t.Fatalf("Expected denomination order of 3 medicaments")
+	}
+	if order.At(0, false).Cis != 3 || order.At(0, true).Cis != 1 {
+		t.Errorf("Expected CIS 3 first and CIS 1 last, got %d and %d", order.At(0, false).Cis, order.At(0, true).Cis)
+	}
+}
+
+func TestGetDataset(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+	medicaments := []entities.Medicament{{Cis: 1, Denomination: "Test"}}
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{1: medicaments[0]}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	dataset := dc.GetDataset()
+
+	replacement := []entities.Medicament{{Cis: 2, Denomination: "Replacement"}, {Cis: 3, Denomination: "Other"}}
+	dc.UpdateData(replacement, []entities.GeneriqueList{},
+		map[int]entities.Medicament{2: replacement[0], 3: replacement[1]}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	// A dataset read before an update keeps its version with its own data and derived data
+	if dataset.Version.Version != 1 || len(dataset.Medicaments) != 1 {
+		t.Errorf("Expected version 1 with 1 medicament, got version %d with %d", dataset.Version.Version, len(dataset.Medicaments))
+	}
+	if _, exists := dataset.MedicamentsMap[1]; !exists || len(dataset.MedicamentsMap) != 1 {
+		t.Errorf("Expected the map of version 1, got %v", dataset.MedicamentsMap)
+	}
+	if order := dataset.Orders[search.SortCIS]; order.Len() != 1 || order.At(0, false).Cis != 1 {
+		t.Errorf("Expected the order of version 1, got %d medicaments", order.Len())
+	}
+
+	if current := dc.GetDataset(); current.Version.Version != 2 || current.Orders[search.SortCIS].Len() != 2 {
+		t.Errorf("Expected version 2 with its order, got version %d", current.Version.Version)
+	}
+}
+
+func TestGetDataVersion(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if version := dc.GetDataVersion(); version.Version != 0 || version.Hash != "" {
+		t.Errorf("Expected zero data version initially, got %+v", version)
+	}
+
+	loadedAt := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	version := interfaces.DataVersion{Version: 12, Hash: "abc", LoadedAt: loadedAt}
+	dc.UpdateData([]entities.Medicament{{Cis: 1, Denomination: "Test"}}, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, version)
+
+	if got := dc.GetDataVersion(); got != version {
+		t.Errorf("Expected data version %+v, got %+v", version, got)
+	}
+	if dataset := dc.GetDataset(); len(dataset.Medicaments) != 1 || dataset.Version != version {
+		t.Errorf("Expected the medicaments of version %+v, got %d medicaments of %+v", version, len(dataset.Medicaments), dataset.Version)
+	}
+	if !dc.GetLastUpdated().Equal(loadedAt) || !dc.GetLastChecked().Equal(loadedAt) {
+		t.Errorf("Expected last update and check at %v, got %v and %v", loadedAt, dc.GetLastUpdated(), dc.GetLastChecked())
+	}
+	if exportArtifact, got := dc.GetExportArtifact(); exportArtifact == nil || !exportArtifact.ModTime.Equal(loadedAt) || got != version {
+		t.Errorf("Expected the export artifact to be dated %v", loadedAt)
+	}
+	exports, got := dc.GetTabularExports()
+	if _, ok := exports.File("parquet", ""); !ok || got != version {
+		t.Errorf("Expected the tabular exports of version %+v, got %+v", version, got)
+	}
+}
+
+func TestGetExportArtifact(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+
+	if exportArtifact, _ := dc.GetExportArtifact(); exportArtifact != nil {
+		t.Error("Expected no export artifact before the first update")
+	}
+
+	medicaments := []entities.Medicament{{Cis: 1, Denomination: "Test"}}
+	dc.UpdateData(medicaments, []entities.GeneriqueList{},
+		map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+		map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+
+	exportArtifact, _ := dc.GetExportArtifact()
+	if exportArtifact == nil {
+		t.Fatal("Expected an export artifact after the update")
+	}
+
+	expected, _ := json.Marshal(medicaments)
+	if !bytes.Equal(exportArtifact.Body(artifact.Identity), expected) {
+		t.Errorf("Expected the artifact to hold the JSON export, got %s", exportArtifact.Body(artifact.Identity))
+	}
+	if !exportArtifact.ModTime.Equal(dc.GetLastUpdated()) {
+		t.Errorf("Expected ModTime %v to match last update %v", exportArtifact.ModTime, dc.GetLastUpdated())
+	}
+	if exportArtifact.Size(artifact.Brotli) == 0 {
+		t.Error("Expected the artifact to be precompressed")
+	}
+}
+
+func TestExportsBuiltOncePerVersion(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+	update := func(denomination string) {
+		dc.UpdateData([]entities.Medicament{{Cis: 1, Denomination: denomination}}, []entities.GeneriqueList{},
+			map[int]entities.Medicament{}, map[int]entities.GeneriqueList{},
+			map[int]entities.Presentation{}, map[int]entities.Presentation{}, nil, nextVersion(dc))
+	}
+
+	update("Avant")
+	firstArtifact, _ := dc.GetExportArtifact()
+	firstExports, _ := dc.GetTabularExports()
+	againArtifact, _ := dc.GetExportArtifact()
+	againExports, _ := dc.GetTabularExports()
+	if firstArtifact != againArtifact || firstExports != againExports {
+		t.Error("Expected the exports of a version to be built once and reused")
+	}
+
+	update("Après")
+	nextArtifact, version := dc.GetExportArtifact()
+	nextExports, _ := dc.GetTabularExports()
+	if nextArtifact == firstArtifact || nextExports == firstExports {
+		t.Fatal("Expected an update to rebuild the exports")
+	}
+	if !bytes.Contains(nextArtifact.Body(artifact.Identity), []byte("Après")) || version.Version != 2 {
+		t.Errorf("Expected the export of version 2, got version %d: %s", version.Version, nextArtifact.Body(artifact.Identity))
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	logging.InitLogger("")
+
+	dc := NewDataContainer()
+	dc.SetChangesRetention(2)
+
+	if len(dc.GetChanges()) != 0 {
+		t.Fatalf("Expected no change set initially, got %d", len(dc.GetChanges()))
+	}
+
+	for version := uint64(1); version <= 3; version++ {
+		dc.RecordChanges(changes.ChangeSet{FromVersion: version, ToVersion: version + 1})
+	}
+
+	changeSets := dc.GetChanges()
+	if len(changeSets) != 2 {
+		t.Fatalf("Expected 2 retained change sets, got %d", len(changeSets))
+	}
+	if changeSets[0].FromVersion != 2 || changeSets[1].FromVersion != 3 {
+		t.Errorf("Expected the two most recent change sets, got %+v", changeSets)
+	}
+}
@@ -0,0 +1,258 @@
+// Package events streams dataset update events to clients with Server-Sent Events.
+// The scheduler publishes to a Broker, which fans the events out to every connected client.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/giygas/medicaments-api/interfaces"
+	"github.com/giygas/medicaments-api/logging"
+)
+
+// Compile-time check to ensure Broker implements EventPublisher interface
+var _ interfaces.EventPublisher = (*Broker)(nil)
+
+// Event types published by the scheduler
+const (
+	UpdateStarted   = "update_started"
+	UpdateCompleted = "update_completed"
+	UpdateFailed    = "update_failed"
+)
+
+// UpdateStartedData is the payload of update_started
+type UpdateStartedData struct {
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// UpdateCompletedData is the payload of update_completed
+type UpdateCompletedData struct {
+	DataVersion   uint64    `json:"dataVersion"`
+	DataHash      string    `json:"dataHash"`
+	Medicaments   int       `json:"medicaments"`
+	Generiques    int       `json:"generiques"`
+	Presentations int       `json:"presentations"`
+	StartedAt     time.Time `json:"startedAt"`
+	DurationMs    int64     `json:"durationMs"`
+	Unchanged     bool      `json:"unchanged"` // The sources did not change, the dataset was kept
+}
+
+// UpdateFailedData is the payload of update_failed
+type UpdateFailedData struct {
+	Error     string    `json:"error"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+const (
+	defaultHeartbeat = 30 * time.Second
+	maxClientsPerIP  = 5
+	clientBuffer     = 16    // Events queued per client before it is considered too slow
+	reconnectDelayMs = 30000 // Sent as "retry:" so browsers do not reconnect in a loop
+)
+
+// Broker keeps the connected clients and sends them the published events
+type Broker struct {
+	maxClients int
+	heartbeat  time.Duration
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	perIP   map[string]int
+	nextID  uint64
+	closed  bool
+	done    chan struct{}
+}
+
+type client struct {
+	ip     string
+	events chan []byte
+}
+
+// NewBroker creates a broker accepting at most maxClients concurrent connections
+func NewBroker(maxClients int) *Broker {
+	return &Broker{
+		maxClients: maxClients,
+		heartbeat:  defaultHeartbeat,
+		clients:    make(map[*client]struct{}),
+		perIP:      make(map[string]int),
+		done:       make(chan struct{}),
+	}
+}
+
+// Publish sends an event to every connected client. It never blocks: a client whose
+// queue is full is disconnected and will reconnect.
+func (b *Broker) Publish(eventType string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logging.Error("Failed to marshal event", "event", eventType, "error", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.nextID++
+	message := fmt.Appendf(nil, "id: %d\nevent: %s\ndata: %s\n\n", b.nextID, eventType, payload)
+
+	for c := range b.clients {
+		select {
+		case c.events <- message:
+		default:
+			logging.Warn("Event client too slow, disconnecting", "remote_addr", c.ip)
+			b.removeLocked(c)
+		}
+	}
+}
+
+// Clients returns the number of connected clients
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.clients)
+}
+
+// Close ends every stream and refuses new connections. It is registered on server shutdown
+// because open streams would otherwise keep the graceful shutdown waiting.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+}
+
+// ServeHTTP streams the events to a client until it disconnects
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// RealIPMiddleware already strips the port, SplitHostPort covers direct use
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	c, status, message := b.add(clientIP)
+	if c == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(reconnectDelayMs/1000))
+		respondWithError(w, status, message)
+		return
+	}
+	defer b.remove(c)
+
+	// The server write timeout would cut the stream
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.Warn("Failed to clear write deadline for event stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	if !b.write(w, rc, fmt.Appendf(nil, "retry: %d\n\n", reconnectDelayMs)) {
+		return
+	}
+
+	heartbeat := time.NewTicker(b.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.events:
+			if !ok { // Removed by Publish
+				return
+			}
+			if !b.write(w, rc, message) {
+				return
+			}
+		case <-heartbeat.C:
+			if !b.write(w, rc, []byte(": heartbeat\n\n")) {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// write sends a message and flushes it, reporting whether the client is still reachable
+func (b *Broker) write(w http.ResponseWriter, rc *http.ResponseController, message []byte) bool {
+	if _, err := w.Write(message); err != nil {
+		return false
+	}
+	return rc.Flush() == nil
+}
+
+// add registers a client, or returns the status and message explaining why it cannot connect
+func (b *Broker) add(ip string) (*client, int, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.closed:
+		return nil, http.StatusServiceUnavailable, "Server is shutting down"
+	case len(b.clients) >= b.maxClients:
+		return nil, http.StatusServiceUnavailable, "Too many event stream connections. Try again later"
+	case b.perIP[ip] >= maxClientsPerIP:
+		return nil, http.StatusTooManyRequests, fmt.Sprintf("Too many event stream connections from this address (max %d)", maxClientsPerIP)
+	}
+
+	c := &client{ip: ip, events: make(chan []byte, clientBuffer)}
+	b.clients[c] = struct{}{}
+	b.perIP[ip]++
+	return c, 0, ""
+}
+
+// remove unregisters a client
+func (b *Broker) remove(c *client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(c)
+}
+
+func (b *Broker) removeLocked(c *client) {
+	if _, ok := b.clients[c]; !ok {
+		return
+	}
+	delete(b.clients, c)
+	close(c.events)
+
+	b.perIP[c.ip]--
+	if b.perIP[c.ip] == 0 {
+		delete(b.perIP, c.ip)
+	}
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	data, err := json.Marshal(map[string]any{
+		"error":   http.StatusText(code),
+		"message": message,
+		"code":    code,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		logging.Error("Failed to write response", "error", err)
+	}
+}
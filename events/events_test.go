@@ -0,0 +1,215 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stream is an open /v1/events connection read line by line
+type stream struct {
+	resp   *http.Response
+	lines  *bufio.Scanner
+	cancel context.CancelFunc
+}
+
+func connect(t *testing.T, url string) *stream {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	s := &stream{resp: resp, lines: bufio.NewScanner(resp.Body), cancel: cancel}
+	t.Cleanup(s.close)
+	return s
+}
+
+func (s *stream) close() {
+	s.cancel()
+	_ = s.resp.Body.Close()
+}
+
+// next returns the lines of the next message, up to the blank line ending it
+func (s *stream) next(t *testing.T) []string {
+	t.Helper()
+
+	var message []string
+	for s.lines.Scan() {
+		line := s.lines.Text()
+		if line == "" {
+			return message
+		}
+		message = append(message, line)
+	}
+	t.Fatalf("Stream ended: %v", s.lines.Err())
+	return nil
+}
+
+// waitForClients waits until the broker has registered n clients
+func waitForClients(t *testing.T, b *Broker, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, got %d", n, b.Clients())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBroker_StreamsEvents(t *testing.T) {
+	b := NewBroker(10)
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	s := connect(t, srv.URL)
+	if s.resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", s.resp.StatusCode)
+	}
+	if ct := s.resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", ct)
+	}
+	if retry := s.next(t); len(retry) != 1 || retry[0] != "retry: 30000" {
+		t.Errorf("Expected a retry delay first, got %v", retry)
+	}
+
+	waitForClients(t, b, 1)
+	b.Publish(UpdateCompleted, UpdateCompletedData{DataVersion: 3, Medicaments: 15000})
+
+	message := s.next(t)
+	if len(message) != 3 || message[0] != "id: 1" || message[1] != "event: update_completed" {
+		t.Fatalf("Unexpected message: %v", message)
+	}
+	var data UpdateCompletedData
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(message[2], "data: ")), &data); err != nil {
+		t.Fatalf("Invalid data line %q: %v", message[2], err)
+	}
+	if data.DataVersion != 3 || data.Medicaments != 15000 {
+		t.Errorf("Unexpected data: %+v", data)
+	}
+}
+
+func TestBroker_Heartbeat(t *testing.T) {
+	b := NewBroker(10)
+	b.heartbeat = 10 * time.Millisecond
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	s := connect(t, srv.URL)
+	s.next(t) // retry
+
+	if heartbeat := s.next(t); len(heartbeat) != 1 || heartbeat[0] != ": heartbeat" {
+		t.Errorf("Expected a heartbeat comment, got %v", heartbeat)
+	}
+}
+
+func TestBroker_ConnectionCaps(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxClients     int
+		connections    int
+		expectedStatus int
+	}{
+		{"global cap", 2, 2, http.StatusServiceUnavailable},
+		{"per IP cap", 100, maxClientsPerIP, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker(tt.maxClients)
+			srv := httptest.NewServer(b)
+			t.Cleanup(srv.Close)
+
+			for range tt.connections {
+				connect(t, srv.URL)
+			}
+			waitForClients(t, b, tt.connections)
+
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if resp.Header.Get("Retry-After") == "" {
+				t.Error("Expected a Retry-After header")
+			}
+		})
+	}
+}
+
+func TestBroker_DisconnectFreesSlot(t *testing.T) {
+	b := NewBroker(1)
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	s := connect(t, srv.URL)
+	waitForClients(t, b, 1)
+
+	s.close()
+	waitForClients(t, b, 0)
+
+	if s := connect(t, srv.URL); s.resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after a disconnection, got %d", s.resp.StatusCode)
+	}
+}
+
+func TestBroker_SlowClientIsDropped(t *testing.T) {
+	b := NewBroker(10)
+	c, _, _ := b.add("127.0.0.1")
+	if c == nil {
+		t.Fatal("Expected the client to be added")
+	}
+
+	// Nobody reads the queue, the message after a full buffer drops the client
+	for range clientBuffer + 1 {
+		b.Publish(UpdateStarted, UpdateStartedData{})
+	}
+
+	if b.Clients() != 0 {
+		t.Errorf("Expected the slow client to be removed, got %d clients", b.Clients())
+	}
+}
+
+func TestBroker_CloseEndsStreams(t *testing.T) {
+	b := NewBroker(10)
+	srv := httptest.NewServer(b)
+	t.Cleanup(srv.Close)
+
+	s := connect(t, srv.URL)
+	s.next(t) // retry
+	waitForClients(t, b, 1)
+
+	b.Close()
+
+	for s.lines.Scan() {
+	}
+	waitForClients(t, b, 0)
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after Close, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes a table as RFC 4180 CSV, with a header row of the column names
+func WriteCSV(w io.Writer, table Table) error {
+	cw := csv.NewWriter(w)
+
+	record := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		record[i] = column.Name
+	}
+	if err := cw.Write(record); err != nil {
+		return fmt.Errorf("failed to write %s header: %w", table.Name, err)
+	}
+
+	for _, row := range table.Rows {
+		for i, value := range row {
+			record[i] = formatCSV(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write %s row: %w", table.Name, err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCSV(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WriteBundle writes the tables into a zip archive, one <table><extension> file each,
+// using write to encode every table
+func WriteBundle(w io.Writer, tables []Table, extension string, write func(io.Writer, Table) error) error {
+	zw := zip.NewWriter(w)
+
+	for _, table := range tables {
+		f, err := zw.Create(table.Name + extension)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to the bundle: %w", table.Name, err)
+		}
+		if err := write(f, table); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,576 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden Parquet files in testdata")
+
+func testMedicaments() []entities.Medicament {
+	return []entities.Medicament{
+		{
+			Cis:                  61266250,
+			Denomination:         "DOLIPRANE 1000 mg, comprimé",
+			FormePharmaceutique:  "comprimé",
+			VoiesAdministration:  []string{"orale", "rectale"},
+			EtatComercialisation: "Commercialisée",
+			Mitm:                 true,
+			CodeATC:              "N02BE01",
+			Composition: []entities.Composition{
+				{Cis: 61266250, ElementPharmaceutique: "comprimé", CodeSubstance: 2202, DenominationSubstance: "PARACÉTAMOL", Dosage: "1000 mg"},
+			},
+			Presentation: []entities.Presentation{
+				{Cis: 61266250, Cip7: 3000001, Cip13: 3400930000001, Libelle: "plaquette de 8", Prix: 2.18},
+				{
+					Cis: 61266250, Cip7: 3000002, Cip13: 3400930000002, Libelle: "plaquette de 16", Prix: 3.5,
+					Disponibilite: &entities.Disponibilite{CodeStatut: 2, Statut: "Tension d'approvisionnement", DateDebut: "01/02/2026"},
+				},
+			},
+			Generiques: []entities.Generique{{Cis: 61266250, Group: 1, Libelle: "PARACETAMOL 1000 mg", Type: "Princeps"}},
+			Conditions: []string{"liste II", "réservé à l'usage \"hospitalier\""},
+		},
+		{
+			Cis:          60234100,
+			Denomination: "IBUPROFÈNE 400 mg, comprimé",
+		},
+	}
+}
+
+func TestBuildTables(t *testing.T) {
+	tables := BuildTables(testMedicaments())
+
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.Name
+		for _, row := range table.Rows {
+			if len(row) != len(table.Columns) {
+				t.Fatalf("%s: expected %d values per row, got %d", table.Name, len(table.Columns), len(row))
+			}
+		}
+	}
+	if !reflect.DeepEqual(names, TableNames) {
+		t.Errorf("Expected tables %v, got %v", TableNames, names)
+	}
+
+	expectedRows := map[string]int{"medicaments": 2, "compositions": 1, "presentations": 2, "generiques": 1, "conditions": 2}
+	for _, table := range tables {
+		if len(table.Rows) != expectedRows[table.Name] {
+			t.Errorf("%s: expected %d rows, got %d", table.Name, expectedRows[table.Name], len(table.Rows))
+		}
+	}
+
+	medicaments := tables[0].Rows[0]
+	if medicaments[3] != "orale;rectale" || medicaments[10] != true {
+		t.Errorf("Unexpected medicament row: %v", medicaments)
+	}
+
+	presentations, _ := FindTable(tables, "presentations")
+	if presentations.Rows[0][9] != 2.18 {
+		t.Errorf("Expected price 2.18, got %v", presentations.Rows[0][9])
+	}
+	if presentations.Rows[0][10] != nil || presentations.Rows[1][10] != int64(2) {
+		t.Errorf("Expected the disponibilite only on the second presentation, got %v and %v", presentations.Rows[0][10], presentations.Rows[1][10])
+	}
+
+	if _, err := FindTable(tables, "unknown"); err == nil {
+		t.Error("Expected an error for an unknown table")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	tables := BuildTables(testMedicaments())
+
+	conditions, _ := FindTable(tables, "conditions")
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, conditions); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	expected := "cis,condition\n61266250,liste II\n61266250,\"réservé à l'usage \"\"hospitalier\"\"\"\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	presentations, _ := FindTable(tables, "presentations")
+	buf.Reset()
+	if err := WriteCSV(&buf, presentations); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d lines", len(lines))
+	}
+	if !strings.HasSuffix(lines[1], ",2.18,,,,") {
+		t.Errorf("Expected empty disponibilite fields, got %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], ",3.5,2,Tension d'approvisionnement,01/02/2026,") {
+		t.Errorf("Expected the disponibilite fields, got %q", lines[2])
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, BuildTables(testMedicaments()), ".csv", WriteCSV); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+
+	var names []string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+	}
+	expected := []string{"medicaments.csv", "compositions.csv", "presentations.csv", "generiques.csv", "conditions.csv"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected files %v, got %v", expected, names)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	exports, err := Build(testMedicaments())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	tables := BuildTables(testMedicaments())
+	for _, format := range Formats {
+		write, extension := writer(format)
+
+		for _, table := range tables {
+			var expected bytes.Buffer
+			if err := write(&expected, table); err != nil {
+				t.Fatalf("Failed to write %s: %v", table.Name, err)
+			}
+			data, ok := exports.File(format, table.Name)
+			if !ok || !bytes.Equal(data, expected.Bytes()) {
+				t.Errorf("Expected the %s table %s to be prebuilt", format, table.Name)
+			}
+		}
+
+		var expected bytes.Buffer
+		if err := WriteBundle(&expected, tables, extension, write); err != nil {
+			t.Fatalf("WriteBundle failed: %v", err)
+		}
+		if data, ok := exports.File(format, ""); !ok || !bytes.Equal(data, expected.Bytes()) {
+			t.Errorf("Expected the %s bundle to be prebuilt", format)
+		}
+	}
+
+	if _, ok := exports.File("csv", "unknown"); ok {
+		t.Error("Expected no file for an unknown table")
+	}
+	if _, ok := (*Exports)(nil).File("csv", ""); ok {
+		t.Error("Expected no file without exports")
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	for _, table := range BuildTables(testMedicaments()) {
+		t.Run(table.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteParquet(&buf, table); err != nil {
+				t.Fatalf("WriteParquet failed: %v", err)
+			}
+
+			columns, rows := readParquet(t, buf.Bytes())
+			checkParquetColumns(t, table, columns, rows)
+		})
+	}
+}
+
+// checkParquetColumns compares the decoded columns with the table
+func checkParquetColumns(t *testing.T, table Table, columns []parquetColumn, rows int64) {
+	t.Helper()
+
+	if rows != int64(len(table.Rows)) {
+		t.Errorf("Expected %d rows, got %d", len(table.Rows), rows)
+	}
+	if len(columns) != len(table.Columns) {
+		t.Fatalf("Expected %d columns, got %d", len(table.Columns), len(columns))
+	}
+
+	for i, column := range table.Columns {
+		if columns[i].name != column.Name {
+			t.Errorf("Column %d: expected %s, got %s", i, column.Name, columns[i].name)
+		}
+		for r, row := range table.Rows {
+			if columns[i].values[r] != row[i] {
+				t.Errorf("%s row %d: expected %v, got %v", column.Name, r, row[i], columns[i].values[r])
+			}
+		}
+	}
+}
+
+type parquetColumn struct {
+	name   string
+	values []any
+}
+
+// TestParquetGolden pins the bytes written for each table. After a deliberate format change,
+// rewrite the files with -update and check them with TestParquetReaders.
+func TestParquetGolden(t *testing.T) {
+	for _, table := range BuildTables(testMedicaments()) {
+		t.Run(table.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteParquet(&buf, table); err != nil {
+				t.Fatalf("WriteParquet failed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", table.Name+".parquet")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatalf("Failed to update %s: %v", golden, err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", golden, err)
+			}
+			if !bytes.Equal(buf.Bytes(), expected) {
+				t.Errorf("%s changed, rerun with -update if the change is intended", golden)
+			}
+
+			// The reference file must decode to the table, so that -update cannot pin a broken file
+			columns, rows := readParquet(t, expected)
+			checkParquetColumns(t, table, columns, rows)
+		})
+	}
+}
+
+// parquetReaders print the rows of a Parquet file as a JSON array of objects
+var parquetReaders = map[string][]string{
+	"duckdb":  {"duckdb", "-json", "-c", "SELECT * FROM read_parquet(getenv('PARQUET_FILE'))"},
+	"pyarrow": {"python3", "-c", "import json, os, pyarrow.parquet as pq; print(json.dumps(pq.read_table(os.environ['PARQUET_FILE']).to_pylist()))"},
+}
+
+// TestParquetReaders reads the golden files with DuckDB and pyarrow, each skipped when not installed.
+// In CI, where pyarrow is installed, the test fails if no reader ran.
+func TestParquetReaders(t *testing.T) {
+	ran := 0
+	for name, command := range parquetReaders {
+		t.Run(name, func(t *testing.T) {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				t.Skipf("%s is not installed", command[0])
+			}
+			if name == "pyarrow" && exec.Command("python3", "-c", "import pyarrow").Run() != nil {
+				t.Skip("pyarrow is not installed")
+			}
+
+			for _, table := range BuildTables(testMedicaments()) {
+				cmd := exec.Command(command[0], command[1:]...)
+				cmd.Env = append(os.Environ(), "PARQUET_FILE="+filepath.Join("testdata", table.Name+".parquet"))
+				output, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("%s could not read %s: %v", name, table.Name, err)
+				}
+
+				got := make([]map[string]any, 0)
+				if len(bytes.TrimSpace(output)) > 0 {
+					if err := json.Unmarshal(output, &got); err != nil {
+						t.Fatalf("Invalid %s output for %s: %v\n%s", name, table.Name, err, output)
+					}
+				}
+				if expected := tableJSON(t, table); !reflect.DeepEqual(got, expected) {
+					t.Errorf("%s: expected rows %v, got %v", table.Name, expected, got)
+				}
+			}
+			ran++
+		})
+	}
+
+	if ran == 0 && os.Getenv("CI") != "" {
+		t.Fatal("No Parquet reader ran: install pyarrow or DuckDB")
+	}
+}
+
+// tableJSON returns the rows of a table as a reader prints them in JSON
+func tableJSON(t *testing.T, table Table) []map[string]any {
+	rows := make([]map[string]any, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i] = make(map[string]any, len(row))
+		for j, column := range table.Columns {
+			rows[i][column.Name] = row[j]
+		}
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return decoded
+}
+
+// readParquet decodes a Parquet file from its footer, following the specification rather than
+// the writer: it checks the sizes, offsets and counts declared in the metadata against the bytes
+// actually found, and decodes definition levels written either as RLE or bit-packed runs.
+func readParquet(t *testing.T, data []byte) ([]parquetColumn, int64) {
+	t.Helper()
+
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("Missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	if footerStart < 4 {
+		t.Fatalf("Footer length %d exceeds the file", footerLen)
+	}
+	footerReader := bytes.NewReader(data[footerStart : len(data)-8])
+	footer := readThriftStruct(t, footerReader)
+	if footerReader.Len() != 0 {
+		t.Errorf("%d bytes left after the footer", footerReader.Len())
+	}
+
+	schema := footer[2].([]any)
+	numRows := footer[3].(int64)
+	rowGroups := footer[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("Expected 1 row group, got %d", len(rowGroups))
+	}
+	rowGroup := rowGroups[0].(map[int16]any)
+	chunks := rowGroup[1].([]any)
+
+	root := schema[0].(map[int16]any)
+	if root[5].(int64) != int64(len(schema)-1) || root[5].(int64) != int64(len(chunks)) {
+		t.Fatalf("Schema root declares %v children for %d elements and %d chunks", root[5], len(schema)-1, len(chunks))
+	}
+	if rowGroup[3].(int64) != numRows {
+		t.Errorf("Row group holds %d rows, file declares %d", rowGroup[3], numRows)
+	}
+
+	var columns []parquetColumn
+	var totalSize int64
+	next := int64(4) // Column chunks follow each other from the magic to the footer
+	for i, chunk := range chunks {
+		element := schema[i+1].(map[int16]any)
+		name := string(element[4].([]byte))
+		meta := chunk.(map[int16]any)[3].(map[int16]any)
+
+		if meta[1].(int64) != element[1].(int64) {
+			t.Errorf("%s: chunk type %v differs from schema type %v", name, meta[1], element[1])
+		}
+		if path := meta[3].([]any); len(path) != 1 || string(path[0].([]byte)) != name {
+			t.Errorf("%s: unexpected path in schema %q", name, path)
+		}
+		if meta[5].(int64) != numRows {
+			t.Errorf("%s: chunk holds %d values for %d rows", name, meta[5], numRows)
+		}
+		if element[1].(int64) == 6 && element[6].(int64) != 0 {
+			t.Errorf("%s: byte array without the UTF8 converted type", name)
+		}
+
+		offset := meta[9].(int64)
+		if offset != next {
+			t.Errorf("%s: data page at %d, expected %d", name, offset, next)
+		}
+		r := bytes.NewReader(data[offset:footerStart])
+		header := readThriftStruct(t, r)
+		headerLen := int64(footerStart) - offset - int64(r.Len())
+		if header[1].(int64) != 0 {
+			t.Fatalf("%s: expected a data page, got page type %v", name, header[1])
+		}
+		pageHeader := header[5].(map[int16]any)
+		if pageHeader[1].(int64) != numRows || pageHeader[2].(int64) != 0 {
+			t.Errorf("%s: page holds %v values with encoding %v", name, pageHeader[1], pageHeader[2])
+		}
+
+		compressedLen := header[3].(int64)
+		if headerLen+compressedLen != meta[7].(int64) {
+			t.Errorf("%s: chunk declares %d compressed bytes, found %d", name, meta[7], headerLen+compressedLen)
+		}
+		if headerLen+header[2].(int64) != meta[6].(int64) {
+			t.Errorf("%s: chunk declares %d uncompressed bytes, page header %d", name, meta[6], headerLen+header[2].(int64))
+		}
+		totalSize += meta[6].(int64)
+		next = offset + headerLen + compressedLen
+
+		var raw []byte
+		switch meta[4].(int64) {
+		case 0: // Uncompressed
+			raw = data[offset+headerLen : next]
+		case 2: // Gzip
+			zr, err := gzip.NewReader(bytes.NewReader(data[offset+headerLen : next]))
+			if err != nil {
+				t.Fatalf("%s: invalid page: %v", name, err)
+			}
+			if raw, err = io.ReadAll(zr); err != nil {
+				t.Fatalf("%s: invalid page: %v", name, err)
+			}
+		default:
+			t.Fatalf("%s: unsupported codec %v", name, meta[4])
+		}
+		if int64(len(raw)) != header[2].(int64) {
+			t.Errorf("%s: expected an uncompressed page of %d bytes, got %d", name, header[2], len(raw))
+		}
+
+		present := make([]bool, numRows)
+		switch element[3].(int64) {
+		case 0: // Required
+			for row := range present {
+				present[row] = true
+			}
+		case 1: // Optional
+			if pageHeader[3].(int64) != 3 {
+				t.Fatalf("%s: definition levels with encoding %v", name, pageHeader[3])
+			}
+			n := int(binary.LittleEndian.Uint32(raw))
+			present = readDefinitionLevels(t, raw[4:4+n], numRows)
+			raw = raw[4+n:]
+		default:
+			t.Fatalf("%s: unsupported repetition %v", name, element[3])
+		}
+
+		values := make([]any, numRows)
+		count := 0
+		for row := range values {
+			if !present[row] {
+				continue
+			}
+			switch element[1].(int64) {
+			case 6: // BYTE_ARRAY
+				n := binary.LittleEndian.Uint32(raw)
+				if !utf8.Valid(raw[4 : 4+n]) {
+					t.Errorf("%s row %d: invalid UTF-8", name, row)
+				}
+				values[row] = string(raw[4 : 4+n])
+				raw = raw[4+n:]
+			case 2: // INT64
+				values[row] = int64(binary.LittleEndian.Uint64(raw))
+				raw = raw[8:]
+			case 5: // DOUBLE
+				values[row] = math.Float64frombits(binary.LittleEndian.Uint64(raw))
+				raw = raw[8:]
+			case 0: // BOOLEAN
+				values[row] = raw[count/8]&(1<<(count%8)) != 0
+			default:
+				t.Fatalf("%s: unsupported type %v", name, element[1])
+			}
+			count++
+		}
+		if element[1].(int64) == 0 {
+			raw = raw[(count+7)/8:]
+		}
+		if len(raw) != 0 {
+			t.Errorf("%s: %d bytes left after the values", name, len(raw))
+		}
+
+		columns = append(columns, parquetColumn{name: name, values: values})
+	}
+
+	if next != int64(footerStart) {
+		t.Errorf("Column chunks end at %d, footer starts at %d", next, footerStart)
+	}
+	if rowGroup[2].(int64) != totalSize {
+		t.Errorf("Row group declares %d bytes, chunks hold %d", rowGroup[2], totalSize)
+	}
+
+	return columns, numRows
+}
+
+// readDefinitionLevels decodes levels of bit width 1 with the RLE/bit-packing hybrid encoding
+func readDefinitionLevels(t *testing.T, data []byte, numRows int64) []bool {
+	t.Helper()
+
+	present := make([]bool, 0, numRows)
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		header, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatalf("Invalid run header: %v", err)
+		}
+		if header&1 == 0 { // RLE run, the value fits in one byte
+			level, _ := r.ReadByte()
+			for range header >> 1 {
+				present = append(present, level == 1)
+			}
+			continue
+		}
+		for range header >> 1 { // Bit-packed groups of 8 values, one byte each
+			b, _ := r.ReadByte()
+			for bit := range 8 {
+				present = append(present, b&(1<<bit) != 0)
+			}
+		}
+	}
+
+	// A bit-packed run pads its last group
+	if int64(len(present)) < numRows {
+		t.Fatalf("Expected %d definition levels, got %d", numRows, len(present))
+	}
+	return present[:numRows]
+}
+
+// readThriftStruct decodes a Thrift compact struct into its fields by ID
+func readThriftStruct(t *testing.T, r *bytes.Reader) map[int16]any {
+	t.Helper()
+
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatalf("Truncated struct: %v", err)
+		}
+		if b == 0 {
+			return fields
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, _ := binary.ReadVarint(r)
+			id = int16(v)
+		}
+		fields[id] = readThriftValue(t, r, b&0x0f)
+	}
+}
+
+func readThriftValue(t *testing.T, r *bytes.Reader, typ byte) any {
+	switch typ {
+	case thriftI32, thriftI64:
+		v, _ := binary.ReadVarint(r)
+		return v
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(r)
+		b := make([]byte, n)
+		_, _ = io.ReadFull(r, b)
+		return b
+	case thriftList:
+		header, _ := r.ReadByte()
+		size := uint64(header >> 4)
+		if size == 15 {
+			size, _ = binary.ReadUvarint(r)
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = readThriftValue(t, r, header&0x0f)
+		}
+		return list
+	case thriftStruct:
+		return readThriftStruct(t, r)
+	default:
+		t.Fatalf("Unexpected Thrift type %d", typ)
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// Formats are the tabular export formats
+var Formats = []string{"csv", "parquet"}
+
+// Exports holds every table and bundle of each format, encoded once per dataset version
+// instead of on every request. It is immutable once built.
+type Exports struct {
+	files map[string][]byte
+}
+
+// Build encodes the tables of the medicaments in every format, one file per table
+// and a zip bundle of all of them
+func Build(medicaments []entities.Medicament) (*Exports, error) {
+	tables := BuildTables(medicaments)
+	e := &Exports{files: make(map[string][]byte)}
+
+	for _, format := range Formats {
+		write, extension := writer(format)
+
+		for _, table := range tables {
+			var buf bytes.Buffer
+			if err := write(&buf, table); err != nil {
+				return nil, fmt.Errorf("failed to encode %s as %s: %w", table.Name, format, err)
+			}
+			e.files[fileKey(format, table.Name)] = buf.Bytes()
+		}
+
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, tables, extension, write); err != nil {
+			return nil, fmt.Errorf("failed to bundle the %s tables: %w", format, err)
+		}
+		e.files[fileKey(format, "")] = buf.Bytes()
+	}
+
+	return e, nil
+}
+
+// File returns a table in the given format, or the bundle of all tables for an empty table name.
+// It returns false for an unknown format or table, and on a nil Exports.
+func (e *Exports) File(format, table string) ([]byte, bool) {
+	if e == nil {
+		return nil, false
+	}
+	data, ok := e.files[fileKey(format, table)]
+	return data, ok
+}
+
+// Extension returns the file extension of a format
+func Extension(format string) string {
+	_, extension := writer(format)
+	return extension
+}
+
+func writer(format string) (func(io.Writer, Table) error, string) {
+	if format == "parquet" {
+		return WriteParquet, ".parquet"
+	}
+	return WriteCSV, ".csv"
+}
+
+func fileKey(format, table string) string {
+	return format + "/" + table
+}
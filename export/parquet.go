@@ -0,0 +1,276 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Parquet format constants, see https://github.com/apache/parquet-format
+const (
+	parquetMagic = "PAR1"
+
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetConvertedUTF8 = 0
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecGzip = 2
+
+	parquetDataPage = 0
+)
+
+// parquetCreatedBy identifies the writer in the file metadata
+const parquetCreatedBy = "medicaments-api"
+
+// columnChunk is the position and size of a written column, for the file footer
+type columnChunk struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// WriteParquet writes a table as a Parquet file with a single row group.
+// Each column is one gzip-compressed data page with PLAIN encoded values, which
+// every Parquet reader supports (DuckDB, Arrow, pandas, Spark).
+func WriteParquet(w io.Writer, table Table) error {
+	cw := &countingWriter{w: w}
+
+	if _, err := io.WriteString(cw, parquetMagic); err != nil {
+		return fmt.Errorf("failed to write %s: %w", table.Name, err)
+	}
+
+	chunks := make([]columnChunk, len(table.Columns))
+	for i, column := range table.Columns {
+		chunk, err := writeColumn(cw, table, i)
+		if err != nil {
+			return fmt.Errorf("failed to write %s.%s: %w", table.Name, column.Name, err)
+		}
+		chunks[i] = chunk
+	}
+
+	footer := fileMetadata(table, chunks)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer))) // #nosec G115 -- metadata of a few KB
+	footer = append(footer, parquetMagic...)
+	if _, err := cw.Write(footer); err != nil {
+		return fmt.Errorf("failed to write %s footer: %w", table.Name, err)
+	}
+
+	return nil
+}
+
+// writeColumn writes the data page of the column at index i
+func writeColumn(cw *countingWriter, table Table, i int) (columnChunk, error) {
+	column := table.Columns[i]
+
+	var page bytes.Buffer
+	if column.Optional {
+		levels := definitionLevels(table.Rows, i)
+		page.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))) // #nosec G115 -- bounded by the row count
+		page.Write(levels)
+	}
+	if err := plainValues(&page, column.Type, table.Rows, i); err != nil {
+		return columnChunk{}, err
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(page.Bytes()); err != nil {
+		return columnChunk{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return columnChunk{}, err
+	}
+
+	var header thriftWriter
+	header.beginStruct()
+	header.i32(1, parquetDataPage)
+	header.i32(2, int32(page.Len()))       // #nosec G115 -- a column of the dataset is a few MB
+	header.i32(3, int32(compressed.Len())) // #nosec G115
+	header.structField(5, func() {
+		header.i32(1, int32(len(table.Rows))) // #nosec G115
+		header.i32(2, parquetEncodingPlain)
+		header.i32(3, parquetEncodingRLE)
+		header.i32(4, parquetEncodingRLE)
+	})
+	header.endStruct()
+
+	chunk := columnChunk{
+		offset:           cw.n,
+		uncompressedSize: int64(header.buf.Len() + page.Len()),
+		compressedSize:   int64(header.buf.Len() + compressed.Len()),
+	}
+
+	if _, err := cw.Write(header.buf.Bytes()); err != nil {
+		return columnChunk{}, err
+	}
+	if _, err := cw.Write(compressed.Bytes()); err != nil {
+		return columnChunk{}, err
+	}
+
+	return chunk, nil
+}
+
+// definitionLevels encodes whether each value is present (1) or null (0) with the
+// RLE/bit-packing hybrid encoding, as runs of identical levels of bit width 1
+func definitionLevels(rows [][]any, i int) []byte {
+	var levels []byte
+	for start := 0; start < len(rows); {
+		present := rows[start][i] != nil
+		end := start + 1
+		for end < len(rows) && (rows[end][i] != nil) == present {
+			end++
+		}
+
+		levels = binary.AppendUvarint(levels, uint64(end-start)<<1) // #nosec G115 -- run length
+		if present {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		start = end
+	}
+	return levels
+}
+
+// plainValues writes the non-null values of the column at index i with the PLAIN encoding
+func plainValues(buf *bytes.Buffer, typ ColumnType, rows [][]any, i int) error {
+	var bits []byte // Booleans are bit-packed, least significant bit first
+	count := 0
+
+	for _, row := range rows {
+		value := row[i]
+		if value == nil {
+			continue
+		}
+
+		switch typ {
+		case String:
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("expected string, got %T", value)
+			}
+			buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s)))) // #nosec G115 -- BDPM fields are short
+			buf.WriteString(s)
+		case Int64:
+			v, ok := value.(int64)
+			if !ok {
+				return fmt.Errorf("expected int64, got %T", value)
+			}
+			buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(v))) // #nosec G115 -- two's complement
+		case Float64:
+			v, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("expected float64, got %T", value)
+			}
+			buf.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+		case Bool:
+			v, ok := value.(bool)
+			if !ok {
+				return fmt.Errorf("expected bool, got %T", value)
+			}
+			if count%8 == 0 {
+				bits = append(bits, 0)
+			}
+			if v {
+				bits[len(bits)-1] |= 1 << (count % 8)
+			}
+		}
+		count++
+	}
+
+	buf.Write(bits)
+	return nil
+}
+
+// fileMetadata encodes the FileMetaData footer describing the schema and the column chunks
+func fileMetadata(table Table, chunks []columnChunk) []byte {
+	numRows := int64(len(table.Rows))
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.uncompressedSize
+	}
+
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, 1) // Format version
+	t.structList(2, len(table.Columns)+1, func(i int) {
+		if i == 0 { // Root of the schema
+			t.string(4, "schema")
+			t.i32(5, int32(len(table.Columns))) // #nosec G115 -- a handful of columns
+			return
+		}
+		column := table.Columns[i-1]
+		t.i32(1, parquetType(column.Type))
+		if column.Optional {
+			t.i32(3, parquetOptional)
+		} else {
+			t.i32(3, parquetRequired)
+		}
+		t.string(4, column.Name)
+		if column.Type == String {
+			t.i32(6, parquetConvertedUTF8)
+		}
+	})
+	t.i64(3, numRows)
+	t.structList(4, 1, func(int) {
+		t.structList(1, len(chunks), func(i int) {
+			chunk := chunks[i]
+			column := table.Columns[i]
+			t.i64(2, chunk.offset)
+			t.structField(3, func() {
+				t.i32(1, parquetType(column.Type))
+				t.i32List(2, []int32{parquetEncodingPlain, parquetEncodingRLE})
+				t.stringList(3, []string{column.Name})
+				t.i32(4, parquetCodecGzip)
+				t.i64(5, numRows)
+				t.i64(6, chunk.uncompressedSize)
+				t.i64(7, chunk.compressedSize)
+				t.i64(9, chunk.offset)
+			})
+		})
+		t.i64(2, totalSize)
+		t.i64(3, numRows)
+	})
+	t.string(6, parquetCreatedBy)
+	t.endStruct()
+
+	return t.buf.Bytes()
+}
+
+func parquetType(typ ColumnType) int32 {
+	switch typ {
+	case Int64:
+		return parquetInt64
+	case Float64:
+		return parquetDouble
+	case Bool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// countingWriter tracks the file offset of the column chunks
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
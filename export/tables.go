@@ -0,0 +1,165 @@
+// Package export flattens the medicaments into tables with a stable column schema,
+// and writes them as CSV or Parquet for spreadsheets and analytical databases.
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// ColumnType is the type of the values of a column
+type ColumnType int
+
+const (
+	String ColumnType = iota
+	Int64
+	Float64
+	Bool
+)
+
+// Column describes a column of a table. Optional columns hold nil for missing values,
+// written as an empty CSV field or a Parquet null.
+type Column struct {
+	Name     string
+	Type     ColumnType
+	Optional bool
+}
+
+// Table is a flattened entity type. Each row holds one value per column:
+// string, int64, float64 or bool according to the column type.
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]any
+}
+
+// TableNames lists the tables in the order they are built and bundled
+var TableNames = []string{"medicaments", "compositions", "presentations", "generiques", "conditions"}
+
+// Schemas of the tables. Columns are only ever appended, so existing queries keep working.
+var (
+	medicamentsColumns = []Column{
+		{Name: "cis", Type: Int64},
+		{Name: "elementPharmaceutique", Type: String},
+		{Name: "formePharmaceutique", Type: String},
+		{Name: "voiesAdministration", Type: String}, // Separated by ";"
+		{Name: "statusAutorisation", Type: String},
+		{Name: "typeProcedure", Type: String},
+		{Name: "etatComercialisation", Type: String},
+		{Name: "dateAMM", Type: String},
+		{Name: "titulaire", Type: String},
+		{Name: "surveillanceRenforcee", Type: String},
+		{Name: "mitm", Type: Bool},
+		{Name: "codeATC", Type: String},
+	}
+
+	compositionsColumns = []Column{
+		{Name: "cis", Type: Int64},
+		{Name: "elementPharmaceutique", Type: String},
+		{Name: "codeSubstance", Type: Int64},
+		{Name: "denominationSubstance", Type: String},
+		{Name: "dosage", Type: String},
+		{Name: "referenceDosage", Type: String},
+		{Name: "natureComposant", Type: String},
+	}
+
+	presentationsColumns = []Column{
+		{Name: "cis", Type: Int64},
+		{Name: "cip7", Type: Int64},
+		{Name: "cip13", Type: Int64},
+		{Name: "libelle", Type: String},
+		{Name: "statusAdministratif", Type: String},
+		{Name: "etatComercialisation", Type: String},
+		{Name: "dateDeclaration", Type: String},
+		{Name: "agreement", Type: String},
+		{Name: "tauxRemboursement", Type: String},
+		{Name: "prix", Type: Float64},
+		// Shortage reported for the presentation, null when there is none
+		{Name: "disponibiliteCodeStatut", Type: Int64, Optional: true},
+		{Name: "disponibiliteStatut", Type: String, Optional: true},
+		{Name: "disponibiliteDateDebut", Type: String, Optional: true},
+		{Name: "disponibiliteDateRemiseDisposition", Type: String, Optional: true},
+	}
+
+	generiquesColumns = []Column{
+		{Name: "cis", Type: Int64},
+		{Name: "group", Type: Int64},
+		{Name: "libelle", Type: String},
+		{Name: "type", Type: String},
+	}
+
+	conditionsColumns = []Column{
+		{Name: "cis", Type: Int64},
+		{Name: "condition", Type: String},
+	}
+)
+
+// BuildTables flattens the medicaments into the tables listed in TableNames
+func BuildTables(medicaments []entities.Medicament) []Table {
+	tables := []Table{
+		{Name: "medicaments", Columns: medicamentsColumns, Rows: make([][]any, 0, len(medicaments))},
+		{Name: "compositions", Columns: compositionsColumns},
+		{Name: "presentations", Columns: presentationsColumns},
+		{Name: "generiques", Columns: generiquesColumns},
+		{Name: "conditions", Columns: conditionsColumns},
+	}
+
+	for i := range medicaments {
+		m := &medicaments[i]
+		cis := int64(m.Cis)
+
+		tables[0].Rows = append(tables[0].Rows, []any{
+			cis, m.Denomination, m.FormePharmaceutique, strings.Join(m.VoiesAdministration, ";"),
+			m.StatusAutorisation, m.TypeProcedure, m.EtatComercialisation, m.DateAMM,
+			m.Titulaire, m.SurveillanceRenforcee, m.Mitm, m.CodeATC,
+		})
+
+		for _, c := range m.Composition {
+			tables[1].Rows = append(tables[1].Rows, []any{
+				cis, c.ElementPharmaceutique, int64(c.CodeSubstance), c.DenominationSubstance,
+				c.Dosage, c.ReferenceDosage, c.NatureComposant,
+			})
+		}
+
+		for _, p := range m.Presentation {
+			row := []any{
+				cis, int64(p.Cip7), int64(p.Cip13), p.Libelle, p.StatusAdministratif,
+				p.EtatComercialisation, p.DateDeclaration, p.Agreement, p.TauxRemboursement,
+				price(p.Prix), nil, nil, nil, nil,
+			}
+			if d := p.Disponibilite; d != nil {
+				row[10], row[11], row[12], row[13] = int64(d.CodeStatut), d.Statut, d.DateDebut, d.DateRemiseDisposition
+			}
+			tables[2].Rows = append(tables[2].Rows, row)
+		}
+
+		for _, g := range m.Generiques {
+			tables[3].Rows = append(tables[3].Rows, []any{cis, int64(g.Group), g.Libelle, g.Type})
+		}
+
+		for _, condition := range m.Conditions {
+			tables[4].Rows = append(tables[4].Rows, []any{cis, condition})
+		}
+	}
+
+	return tables
+}
+
+// price widens a price to float64 without the float32 rounding noise (2.18, not 2.180000066757202)
+func price(p float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(p), 'f', -1, 32), 64)
+	return v
+}
+
+// FindTable returns the table with the given name
+func FindTable(tables []Table, name string) (Table, error) {
+	for _, table := range tables {
+		if table.Name == name {
+			return table, nil
+		}
+	}
+	return Table{}, fmt.Errorf("unknown table %q", name)
+}
@@ -0,0 +1,424 @@
+// Package fieldset selects the fields of the medicaments written in responses (sparse fieldsets).
+// The selection is applied while the response is encoded, the dataset is never copied.
+package fieldset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// medicamentType is where a selection starts to apply by default, at any depth of a response
+var medicamentType = reflect.TypeFor[entities.Medicament]()
+
+// Selection is a tree of JSON field names. A node without children selects the whole field.
+type Selection struct {
+	fields map[string]*Selection
+	root   reflect.Type // Type the selection starts to apply at, set on the root node
+}
+
+// Parse reads a comma-separated list of medicament fields, with dotted paths for the
+// fields of nested objects: "cis,elementPharmaceutique,presentation.prix".
+// Fields are validated against the JSON names of entities.Medicament.
+func Parse(spec string) (*Selection, error) {
+	return ParseFor(spec, entities.Medicament{})
+}
+
+// ParseFor reads a list of fields like Parse, for responses that reshape the medicament, such as
+// a medicament whose relations are replaced by references. Fields are validated against shape
+// and the selection applies to the values of its type. Fields declared as interfaces are
+// validated against the value they hold in shape, and cannot be selected when it is nil.
+func ParseFor(spec string, shape any) (*Selection, error) {
+	root := &Selection{fields: make(map[string]*Selection), root: reflect.TypeOf(shape)}
+
+	for path := range strings.SplitSeq(spec, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, errors.New("Invalid fields: empty field name")
+		}
+
+		node, typ, value := root, root.root, reflect.ValueOf(shape)
+		segments := strings.Split(path, ".")
+		for i, name := range segments {
+			if typ == nil {
+				return nil, fmt.Errorf("Invalid fields: %s has no subfields", strings.Join(segments[:i], "."))
+			}
+
+			field, ok := findField(typ, name)
+			if ok {
+				typ, value = fieldShape(field, value)
+				ok = typ != nil
+			}
+			if !ok {
+				return nil, fmt.Errorf("Invalid fields: unknown field %s", strings.Join(segments[:i+1], "."))
+			}
+
+			child, exists := node.fields[name]
+			if !exists {
+				child = &Selection{fields: make(map[string]*Selection)}
+				node.fields[name] = child
+			} else if child.fields == nil {
+				break // The whole field is already selected
+			}
+
+			if i == len(segments)-1 {
+				child.fields = nil // Selects the whole field, including subfields selected before
+			}
+			node, typ = child, structElem(typ)
+			if typ == nil || value.Kind() != reflect.Struct {
+				value = reflect.Value{} // Elements of slices are only known by their type
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// fieldShape returns the type of a field and its value in the shape, when known. The type of an
+// interface is the type of the value it holds, nil when the shape does not hold one.
+func fieldShape(f field, parent reflect.Value) (reflect.Type, reflect.Value) {
+	var value reflect.Value
+	if parent.IsValid() {
+		value = parent.FieldByIndex(f.index)
+	}
+	if f.typ.Kind() != reflect.Interface {
+		return f.typ, value
+	}
+	if !value.IsValid() || value.IsNil() {
+		return nil, reflect.Value{}
+	}
+	return value.Elem().Type(), value.Elem()
+}
+
+// String returns the selection in a canonical form, sorted, for cache keys
+func (s *Selection) String() string {
+	var paths []string
+	var walk func(prefix string, node *Selection)
+	walk = func(prefix string, node *Selection) {
+		if node.fields == nil {
+			paths = append(paths, prefix)
+			return
+		}
+		for name, child := range node.fields {
+			walk(strings.TrimPrefix(prefix+"."+name, "."), child)
+		}
+	}
+	walk("", s)
+
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+// Project wraps a response payload so that the medicaments it contains are encoded with
+// only the selected fields. The payload is returned unchanged when the selection is nil.
+func Project(payload any, sel *Selection) any {
+	if sel == nil {
+		return payload
+	}
+	return projection{payload: payload, sel: sel}
+}
+
+type projection struct {
+	payload any
+	sel     *Selection
+}
+
+func (p projection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, reflect.ValueOf(p.payload), p.sel, false); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes the JSON encoding of v with the selection applied, without a trailing newline
+func Encode(w io.Writer, v any, sel *Selection) error {
+	var buf bytes.Buffer
+	if err := encode(&buf, reflect.ValueOf(v), sel, false); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// encode writes v as encoding/json would, keeping only the selected fields of medicaments.
+// active is set once inside a value of the root type of the selection, where struct fields
+// are filtered by sel. Until then sel is the root node of the selection.
+func encode(buf *bytes.Buffer, v reflect.Value, sel *Selection, active bool) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if !active && v.Type() == sel.root {
+		active = true
+	}
+	// Fully selected values and values that cannot contain a medicament use encoding/json
+	if (active && sel.fields == nil) || (!active && !mayContain(v.Type(), sel.root)) {
+		return marshal(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encode(buf, v.Elem(), sel, active)
+
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		for _, f := range typeFields(v.Type()) {
+			fieldValue := v.FieldByIndex(f.index)
+			if f.omitEmpty && isEmptyValue(fieldValue) {
+				continue
+			}
+
+			fieldSel, fieldActive := sel, active
+			if active || f.inMedicament {
+				child, selected := sel.fields[f.name]
+				if !selected {
+					continue
+				}
+				fieldSel, fieldActive = child, true
+			}
+
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(f.quotedName)
+			buf.WriteByte(':')
+			if err := encode(buf, fieldValue, fieldSel, fieldActive); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(buf, v.Index(i), sel, active); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return marshal(buf, v)
+		}
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := marshal(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encode(buf, v.MapIndex(key), sel, active); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	default:
+		return marshal(buf, v)
+	}
+}
+
+func marshal(buf *bytes.Buffer, v reflect.Value) error {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
+
+var containsCache sync.Map // [2]reflect.Type{type, root} -> bool
+
+// mayContain reports whether values of the type can hold a value of the root type of a selection,
+// or a medicament. Interfaces and maps of interfaces, such as the paginated responses, are walked
+// to find out.
+func mayContain(t, root reflect.Type) bool {
+	key := [2]reflect.Type{t, root}
+	if cached, ok := containsCache.Load(key); ok {
+		return cached.(bool)
+	}
+	contains := containsType(t, root, make(map[reflect.Type]bool))
+	containsCache.Store(key, contains)
+	return contains
+}
+
+// containsType walks the type, visiting ends the recursion on self-referencing types
+func containsType(t, root reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return containsType(t.Elem(), root, visiting)
+	case reflect.Struct:
+		if t == root || t == medicamentType {
+			return true
+		}
+		for _, f := range typeFields(t) {
+			if f.inMedicament || containsType(f.typ, root, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field is a JSON-encoded struct field, with the fields of embedded structs promoted
+type field struct {
+	name         string
+	quotedName   []byte
+	index        []int
+	typ          reflect.Type
+	omitEmpty    bool
+	inMedicament bool // Promoted from an embedded entities.Medicament
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+// typeFields lists the fields of a struct as encoding/json encodes them
+func typeFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			for _, promoted := range typeFields(sf.Type) {
+				promoted.index = append([]int{i}, promoted.index...)
+				promoted.inMedicament = promoted.inMedicament || sf.Type == medicamentType
+				fields = append(fields, promoted)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		quoted, _ := json.Marshal(name)
+		fields = append(fields, field{
+			name:       name,
+			quotedName: quoted,
+			index:      []int{i},
+			typ:        sf.Type,
+			omitEmpty:  strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	fields = dominantFields(fields)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// dominantFields applies the rule of encoding/json for promoted fields of the same name: the
+// shallowest one is encoded, and none when several are at the same depth. A field shadowing a
+// medicament field is selected like the field it replaces.
+func dominantFields(fields []field) []field {
+	depth := make(map[string]int)
+	count := make(map[string]int)
+	shadowsMedicament := make(map[string]bool)
+	for _, f := range fields {
+		if d, seen := depth[f.name]; !seen || len(f.index) < d {
+			depth[f.name], count[f.name] = len(f.index), 1
+		} else if len(f.index) == d {
+			count[f.name]++
+		}
+		shadowsMedicament[f.name] = shadowsMedicament[f.name] || f.inMedicament
+	}
+
+	dominant := fields[:0]
+	for _, f := range fields {
+		if len(f.index) != depth[f.name] || count[f.name] > 1 {
+			continue
+		}
+		f.inMedicament = shadowsMedicament[f.name]
+		dominant = append(dominant, f)
+	}
+	return dominant
+}
+
+func findField(t reflect.Type, name string) (field, bool) {
+	for _, f := range typeFields(t) {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// structElem returns the struct held by a field, through pointers and slices, or nil
+func structElem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// isEmptyValue matches the omitempty rules of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package fieldset
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func testMedicament() entities.Medicament {
+	return entities.Medicament{
+		Cis:                 61266250,
+		Denomination:        "DOLIPRANE 1000 mg, comprimé",
+		FormePharmaceutique: "comprimé",
+		Presentation: []entities.Presentation{
+			{Cis: 61266250, Cip7: 3000001, Libelle: "plaquette de 8", Prix: 2.18},
+			{
+				Cis: 61266250, Cip7: 3000002, Libelle: "plaquette de 16", Prix: 3.5,
+				Disponibilite: &entities.Disponibilite{CodeStatut: 2, Statut: "Tension d'approvisionnement"},
+			},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+		err      string
+	}{
+		{spec: "cis", expected: "cis"},
+		{spec: "elementPharmaceutique, cis", expected: "cis,elementPharmaceutique"},
+		{spec: "cis,presentation.prix,presentation.cip13", expected: "cis,presentation.cip13,presentation.prix"},
+		{spec: "presentation.prix,presentation", expected: "presentation"},
+		{spec: "presentation,presentation.prix", expected: "presentation"},
+		{spec: "presentation.disponibilite.statut", expected: "presentation.disponibilite.statut"},
+		{spec: "", err: "Invalid fields: empty field name"},
+		{spec: "cis,,presentation", err: "Invalid fields: empty field name"},
+		{spec: "nom", err: "Invalid fields: unknown field nom"},
+		{spec: "presentation.nom", err: "Invalid fields: unknown field presentation.nom"},
+		{spec: "cis.value", err: "Invalid fields: cis has no subfields"},
+		{spec: "denominationNormalized", err: "Invalid fields: unknown field denominationNormalized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sel, err := Parse(tt.spec)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if sel.String() != tt.expected {
+				t.Errorf("Expected selection %s, got %s", tt.expected, sel.String())
+			}
+		})
+	}
+}
+
+// reshaped is a medicament whose relations are replaced, as the handlers do with expand
+type reshaped struct {
+	entities.Medicament
+	Presentation any `json:"presentation"`
+	Groups       any `json:"groups,omitempty"`
+}
+
+type presentationRef struct {
+	Cip7 int    `json:"cip7"`
+	Href string `json:"href"`
+}
+
+func TestParseFor(t *testing.T) {
+	shape := reshaped{Presentation: []presentationRef{}}
+
+	for spec, expectedErr := range map[string]string{
+		"cis,presentation.href": "",
+		"presentation":          "",
+		"presentation.prix":     "Invalid fields: unknown field presentation.prix",
+		"groups":                "Invalid fields: unknown field groups",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseFor(spec, shape)
+			if expectedErr == "" && err != nil {
+				t.Errorf("ParseFor failed: %v", err)
+			}
+			if expectedErr != "" && (err == nil || err.Error() != expectedErr) {
+				t.Errorf("Expected error %q, got %v", expectedErr, err)
+			}
+		})
+	}
+
+	sel, err := ParseFor("cis,presentation.href", shape)
+	if err != nil {
+		t.Fatalf("ParseFor failed: %v", err)
+	}
+	med := testMedicament()
+	payload := reshaped{
+		Medicament:   med,
+		Presentation: []presentationRef{{Cip7: 3000001, Href: "/v1/presentations/3000001"}},
+		Groups:       []int{1},
+	}
+	data, err := json.Marshal(Project(payload, sel))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if expected := `{"cis":61266250,"presentation":[{"href":"/v1/presentations/3000001"}]}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestProject(t *testing.T) {
+	med := testMedicament()
+
+	tests := []struct {
+		name     string
+		spec     string
+		payload  any
+		expected string
+	}{
+		{
+			name:     "medicament",
+			spec:     "cis,elementPharmaceutique",
+			payload:  med,
+			expected: `{"cis":61266250,"elementPharmaceutique":"DOLIPRANE 1000 mg, comprimé"}`,
+		},
+		{
+			name:     "nested fields",
+			spec:     "cis,presentation.prix,presentation.disponibilite.codeStatut",
+			payload:  &med,
+			expected: `{"cis":61266250,"presentation":[{"prix":2.18,"disponibilite":null},{"prix":3.5,"disponibilite":{"codeStatut":2}}]}`,
+		},
+		{
+			name:     "page",
+			spec:     "cis",
+			payload:  map[string]any{"data": []entities.Medicament{med}, "page": 1, "next": nil},
+			expected: `{"data":[{"cis":61266250}],"next":null,"page":1}`,
+		},
+		{
+			name: "embedded medicament",
+			spec: "cis",
+			payload: []struct {
+				entities.Medicament
+				Similarity float64 `json:"similarity"`
+			}{{Medicament: med, Similarity: 0.5}},
+			expected: `[{"cis":61266250,"similarity":0.5}]`,
+		},
+		{
+			name: "shadowed field",
+			spec: "cis,presentation",
+			payload: struct {
+				entities.Medicament
+				Presentation []int `json:"presentation"`
+				Links        []int `json:"links"`
+			}{Medicament: med, Presentation: []int{3000001}, Links: []int{1}},
+			expected: `{"cis":61266250,"presentation":[3000001],"links":[1]}`,
+		},
+		{
+			name:     "no medicament",
+			spec:     "cis",
+			payload:  map[string]any{"status": "ok"},
+			expected: `{"status":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			data, err := json.Marshal(Project(tt.payload, sel))
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, data)
+			}
+		})
+	}
+
+	// Without a selection the payload is encoded as is
+	expected, _ := json.Marshal(med)
+	data, _ := json.Marshal(Project(med, nil))
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	sel, _ := Parse("cis")
+	med := testMedicament()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, &med, sel); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if buf.String() != `{"cis":61266250}` {
+		t.Errorf(`Expected {"cis":61266250}, got %s`, buf.String())
+	}
+}
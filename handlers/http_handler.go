@@ -3,29 +3,58 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/export"
+	"github.com/giygas/medicaments-api/fieldset"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 const (
-	maxMedicamentSearchResults = 250
-	maxGeneriqueSearchResults  = 100
+	maxGeneriqueSearchResults = 100
 
-	maxPageSize     = 200
-	defaultPageSize = 10
+	maxPageSize           = 200
+	defaultPageSize       = 10
+	defaultSearchPageSize = 20
 
-	errTooManyMedicamentsResults = "Search too broad. Maximum 250 results returned. Use more specific search terms or /export for full dataset"
-	errTooManyGeneriquesResults  = "Search too broad. Maximum 100 results returned. Use more specific search terms or /export for full dataset"
+	maxSubstanceSuggestions = 20
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 20
+
+	// NDJSON export: lines buffered before each flush and the time allowed to write them
+	ndjsonFlushEvery   = 100
+	ndjsonBufferSize   = 32 * 1024
+	ndjsonWriteTimeout = 15 * time.Second
+
+	errTooManyGeneriquesResults = "Search too broad. Maximum 100 results returned. Use more specific search terms or /export for full dataset"
+	errFieldsUnsupported        = "fields is only supported on /v1/medicaments, /v1/medicaments/{cis}, /v1/substances/{code}/medicaments and /v1/medicaments/export"
+)
+
+// Relations that expand can embed, per resource
+var (
+	medicamentRelations = []string{"presentation", "generiques", "generiqueGroups"}
+	generiqueRelations  = []string{"medicaments"}
 )
 
 // Handler implements the interfaces.HTTPHandler interface
@@ -65,8 +94,11 @@ func (h *Handler) RespondWithJSON(w http.ResponseWriter, code int, payload any)
 		return
 	}
 
+	// Handlers serving data set the version they checked the pin against, keep it
+	if w.Header().Get("X-Data-Version") == "" {
+		setVersionHeaders(w, h.dataStore.GetDataVersion())
+	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("Last-Modified", h.dataStore.GetLastUpdated().UTC().Format(http.TimeFormat))
 	w.WriteHeader(code)
 	if _, err := w.Write(data); err != nil {
 		logging.Error("Failed to write response", "error", err)
@@ -97,22 +129,20 @@ func CheckETag(r *http.Request, etag string) bool {
 	return clientETag != "" && clientETag == serverETag
 }
 
-// findMedicamentByCIP searches for a medicament by CIP7 or CIP13
+// findMedicamentByCIP searches the dataset for a medicament by CIP7 or CIP13
 // Returns (medicament, true) if found, (nil, false) if not found
-func (h *Handler) findMedicamentByCIP(cip int) (*entities.Medicament, bool) {
-	medicamentsMap := h.dataStore.GetMedicamentsMap()
+func findMedicamentByCIP(dataset *interfaces.Dataset, cip int) (*entities.Medicament, bool) {
+	medicamentsMap := dataset.MedicamentsMap
 
 	// Search in CIP7 map first (O(1) lookup)
-	presentationsCIP7 := h.dataStore.GetPresentationsCIP7Map()
-	if pres, ok := presentationsCIP7[cip]; ok {
+	if pres, ok := dataset.PresentationsCIP7Map[cip]; ok {
 		if med, exists := medicamentsMap[pres.Cis]; exists {
 			return &med, true
 		}
 	}
 
 	// If not found, try CIP13 map (O(1) lookup)
-	presentationsCIP13 := h.dataStore.GetPresentationsCIP13Map()
-	if pres, ok := presentationsCIP13[cip]; ok {
+	if pres, ok := dataset.PresentationsCIP13Map[cip]; ok {
 		if med, exists := medicamentsMap[pres.Cis]; exists {
 			return &med, true
 		}
@@ -121,9 +151,14 @@ func (h *Handler) findMedicamentByCIP(cip int) (*entities.Medicament, bool) {
 	return nil, false
 }
 
-// RespondWithJSONAndETag writes a JSON response with ETag and cache validation
+// RespondWithJSONAndETag writes a JSON response with ETag and cache validation.
+// Handlers call checkVersion with the dataset the payload was read from beforehand.
 func (h *Handler) RespondWithJSONAndETag(w http.ResponseWriter, r *http.Request, code int, payload any) {
-	data, err := json.Marshal(payload)
+	if w.Header().Get("X-Data-Version") == "" {
+		setVersionHeaders(w, h.dataStore.GetDataVersion())
+	}
+
+	data, err := json.Marshal(fieldset.Project(payload, selectedFields(r)))
 	if err != nil {
 		logging.Error("Failed to marshal JSON response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -136,7 +171,6 @@ func (h *Handler) RespondWithJSONAndETag(w http.ResponseWriter, r *http.Request,
 	if CheckETag(r, etag) && code == http.StatusOK {
 		// Add cache headers to 304 response as well
 		w.Header().Set("ETag", etag)
-		w.Header().Set("Last-Modified", h.dataStore.GetLastUpdated().UTC().Format(http.TimeFormat))
 		w.Header().Set("Cache-Control", "public, max-age=3600") // 1 hour cache
 		w.WriteHeader(http.StatusNotModified)
 		return
@@ -144,7 +178,6 @@ func (h *Handler) RespondWithJSONAndETag(w http.ResponseWriter, r *http.Request,
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("ETag", etag)
-	w.Header().Set("Last-Modified", h.dataStore.GetLastUpdated().UTC().Format(http.TimeFormat))
 	w.Header().Set("Cache-Control", "public, max-age=3600") // 1 hour cache
 	w.WriteHeader(code)
 
@@ -153,6 +186,71 @@ func (h *Handler) RespondWithJSONAndETag(w http.ResponseWriter, r *http.Request,
 	}
 }
 
+// checkVersion sets the version headers of the dataset a response is read from and enforces the
+// dataVersion pin of the request. Handlers call it before looking anything up in the dataset.
+// It responds with an error and returns false when the request cannot be served.
+func (h *Handler) checkVersion(w http.ResponseWriter, r *http.Request, version interfaces.DataVersion) bool {
+	setVersionHeaders(w, version)
+
+	// dataVersion pins the response to a dataset, to detect an update in the middle of a crawl
+	if pinned := r.URL.Query().Get("dataVersion"); pinned != "" {
+		pinnedVersion, err := strconv.ParseUint(pinned, 10, 64)
+		if err != nil {
+			h.RespondWithError(w, http.StatusBadRequest, "Invalid dataVersion")
+			return false
+		}
+		if pinnedVersion != version.Version {
+			h.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Data changed: dataVersion is now %d", version.Version))
+			return false
+		}
+	}
+
+	return true
+}
+
+// setVersionHeaders sets X-Data-Version, which lets clients tell which BDPM snapshot a
+// response comes from, and Last-Modified from the load time of that snapshot
+func setVersionHeaders(w http.ResponseWriter, version interfaces.DataVersion) {
+	w.Header().Set("X-Data-Version", strconv.FormatUint(version.Version, 10))
+	w.Header().Set("Last-Modified", version.LoadedAt.UTC().Format(http.TimeFormat))
+}
+
+// fieldsKey is the request context key of the fields selection
+type fieldsKey struct{}
+
+// withFields parses the fields parameter and keeps the selection in the request context, where
+// RespondWithJSONAndETag applies it to the medicaments of the response. Fields are validated
+// against shape, the medicament as the response encodes it. It responds with an error and
+// returns false when fields is invalid.
+func (h *Handler) withFields(w http.ResponseWriter, r *http.Request, shape any) (*http.Request, bool) {
+	if !r.URL.Query().Has("fields") {
+		return r, true
+	}
+
+	sel, err := fieldset.ParseFor(r.URL.Query().Get("fields"), shape)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), fieldsKey{}, sel)), true
+}
+
+// fieldsUnsupported responds with an error when fields is set on a route that does not return
+// medicaments, instead of ignoring it. It returns true when it responded.
+func (h *Handler) fieldsUnsupported(w http.ResponseWriter, r *http.Request) bool {
+	if !r.URL.Query().Has("fields") {
+		return false
+	}
+	h.RespondWithError(w, http.StatusBadRequest, errFieldsUnsupported)
+	return true
+}
+
+// selectedFields returns the fields selection of the request, nil when every field is returned
+func selectedFields(r *http.Request) *fieldset.Selection {
+	sel, _ := r.Context().Value(fieldsKey{}).(*fieldset.Selection)
+	return sel
+}
+
 func (h *Handler) AddDeprecationHeaders(w http.ResponseWriter, r *http.Request, newPath string) {
 	oldPath := r.URL.Path
 	// Primary deprecation header (HTTP/1.1 standard)
@@ -191,8 +289,224 @@ func (h *Handler) ExportMedicaments(w http.ResponseWriter, r *http.Request) {
 		h.AddDeprecationHeaders(w, r, "/v1/medicaments/export")
 	}
 
-	medicaments := h.dataStore.GetMedicaments()
-	h.RespondWithJSONAndETag(w, r, http.StatusOK, medicaments)
+	format := r.URL.Query().Get("format")
+	if r.URL.Query().Has("table") && format != "csv" && format != "parquet" {
+		h.RespondWithError(w, http.StatusBadRequest, "table is only supported with format=csv or format=parquet")
+		return
+	}
+	if r.URL.Query().Has("fields") && (format == "csv" || format == "parquet") {
+		h.RespondWithError(w, http.StatusBadRequest, "fields is only supported with format=json or format=ndjson")
+		return
+	}
+
+	r, ok := h.withFields(w, r, entities.Medicament{})
+	if !ok {
+		return
+	}
+
+	switch format {
+	case "", "json":
+		// The precompressed artifact holds every field, a selection is encoded on demand
+		if selectedFields(r) == nil {
+			if exportArtifact, version := h.dataStore.GetExportArtifact(); exportArtifact != nil {
+				h.serveArtifact(w, r, exportArtifact, version)
+				return
+			}
+		}
+		dataset := h.dataStore.GetDataset()
+		if !h.checkVersion(w, r, dataset.Version) {
+			return
+		}
+		h.RespondWithJSONAndETag(w, r, http.StatusOK, dataset.Medicaments)
+	case "ndjson":
+		h.streamMedicamentsNDJSON(w, r)
+	case "csv", "parquet":
+		h.serveTabularExport(w, r, format)
+	default:
+		h.RespondWithError(w, http.StatusBadRequest, "Invalid format. Use json, ndjson, csv or parquet")
+	}
+}
+
+// serveArtifact sends a response precompressed at the last update, in the coding negotiated with
+// Accept-Encoding. http.ServeContent handles Content-Length, Range, If-Range and the conditional
+// requests, with the strong ETag of the chosen representation so that downloads can be resumed.
+func (h *Handler) serveArtifact(w http.ResponseWriter, r *http.Request, exportArtifact *artifact.Artifact, version interfaces.DataVersion) {
+	if !h.checkVersion(w, r, version) {
+		return
+	}
+
+	coding := artifact.Negotiate(r.Header.Get("Accept-Encoding"))
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Set("Content-Type", exportArtifact.ContentType)
+	w.Header().Set("ETag", exportArtifact.ETag(coding))
+	w.Header().Set("Cache-Control", "public, max-age=3600") // 1 hour cache
+	if coding != artifact.Identity {
+		w.Header().Set("Content-Encoding", coding)
+		w = &contentLengthWriter{ResponseWriter: w, size: exportArtifact.Size(coding)}
+	}
+
+	http.ServeContent(w, r, "", exportArtifact.ModTime, bytes.NewReader(exportArtifact.Body(coding)))
+}
+
+// contentLengthWriter sets the Content-Length that http.ServeContent leaves out when a
+// Content-Encoding is set, from the size of the representation or of the requested range
+type contentLengthWriter struct {
+	http.ResponseWriter
+	size int
+}
+
+func (w *contentLengthWriter) WriteHeader(code int) {
+	if w.Header().Get("Content-Length") == "" {
+		switch code {
+		case http.StatusOK:
+			w.Header().Set("Content-Length", strconv.Itoa(w.size))
+		case http.StatusPartialContent:
+			// Multipart responses to several ranges have no Content-Range and keep chunked encoding
+			var first, last, total int
+			if _, err := fmt.Sscanf(w.Header().Get("Content-Range"), "bytes %d-%d/%d", &first, &last, &total); err == nil {
+				w.Header().Set("Content-Length", strconv.Itoa(last-first+1))
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *contentLengthWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// respondNotModified sets the cache headers of an export whose ETag is derived from the dataset
+// version, and answers 304 when the client already has this version
+func (h *Handler) respondNotModified(w http.ResponseWriter, r *http.Request, version interfaces.DataVersion, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=3600") // 1 hour cache
+
+	if version.Hash != "" && CheckETag(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// serveTabularExport writes the medicaments flattened into tables, as CSV or Parquet, encoded once per version.
+// table selects a single table, otherwise all of them are bundled in a zip archive.
+func (h *Handler) serveTabularExport(w http.ResponseWriter, r *http.Request, format string) {
+	table := r.URL.Query().Get("table")
+	if table != "" && !slices.Contains(export.TableNames, table) {
+		h.RespondWithError(w, http.StatusBadRequest, "Invalid table. Use one of: "+strings.Join(export.TableNames, ", "))
+		return
+	}
+
+	// The ETag and X-Data-Version describe the files served, even if an update lands meanwhile
+	exports, version := h.dataStore.GetTabularExports()
+	if !h.checkVersion(w, r, version) {
+		return
+	}
+
+	name := table
+	if name == "" {
+		name = "bundle"
+	}
+	if h.respondNotModified(w, r, version, fmt.Sprintf(`W/"%s-%s-%s"`, format, name, version.Hash)) {
+		return
+	}
+
+	data, ok := exports.File(format, table)
+	if !ok {
+		// The build failure was logged once when the exports were built
+		h.RespondWithError(w, http.StatusServiceUnavailable, "Export not available")
+		return
+	}
+
+	filename, contentType := table+export.Extension(format), "text/csv; charset=utf-8"
+	if format == "parquet" {
+		contentType = "application/vnd.apache.parquet"
+	}
+	if table == "" {
+		filename, contentType = "medicaments-"+format+".zip", "application/zip"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		logging.Error("Failed to write response", "error", err)
+	}
+}
+
+// streamMedicamentsNDJSON writes one medicament per line, flushing every ndjsonFlushEvery lines,
+// so memory use does not grow with the dataset. The ETag comes from the dataset version
+// since the payload is never held in memory as a whole.
+func (h *Handler) streamMedicamentsNDJSON(w http.ResponseWriter, r *http.Request) {
+	// The ETag and X-Data-Version describe the medicaments streamed, even if an update lands meanwhile
+	dataset := h.dataStore.GetDataset()
+	medicaments, version := dataset.Medicaments, dataset.Version
+	if !h.checkVersion(w, r, version) {
+		return
+	}
+
+	etag := fmt.Sprintf(`W/"ndjson-%s"`, version.Hash)
+	sel := selectedFields(r)
+	if sel != nil {
+		sum := sha256.Sum256([]byte(sel.String()))
+		etag = fmt.Sprintf(`W/"ndjson-%s-%x"`, version.Hash, sum[:4])
+	}
+	if h.respondNotModified(w, r, version, etag) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	buf := bufio.NewWriterSize(w, ndjsonBufferSize)
+	encoder := json.NewEncoder(buf)
+
+	// The slice is replaced, never modified, by an update: the stream stays consistent
+	for i := range medicaments {
+		var err error
+		if sel != nil {
+			if err = fieldset.Encode(buf, &medicaments[i], sel); err == nil {
+				err = buf.WriteByte('\n')
+			}
+		} else {
+			err = encoder.Encode(&medicaments[i])
+		}
+		if err != nil {
+			logging.Warn("NDJSON export interrupted", "error", err, "written", i)
+			return
+		}
+
+		if (i+1)%ndjsonFlushEvery == 0 {
+			if r.Context().Err() != nil {
+				return
+			}
+			if !flushNDJSON(buf, rc) {
+				return
+			}
+		}
+	}
+
+	flushNDJSON(buf, rc)
+}
+
+// flushNDJSON sends the buffered lines to the client and pushes the write deadline back,
+// so the server write timeout applies to each chunk rather than to the whole export
+func flushNDJSON(buf *bufio.Writer, rc *http.ResponseController) bool {
+	if err := buf.Flush(); err != nil {
+		return false
+	}
+	if err := rc.SetWriteDeadline(time.Now().Add(ndjsonWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return false
+	}
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return false
+	}
+	return true
 }
 
 // ServePagedMedicaments returns paginated medicaments
@@ -209,22 +523,39 @@ func (h *Handler) ServePagedMedicaments(w http.ResponseWriter, r *http.Request)
 	newPath := fmt.Sprintf("/v1/medicaments?page=%v", page)
 	h.AddDeprecationHeaders(w, r, newPath)
 
-	medicaments := h.dataStore.GetMedicaments()
+	dataset := h.dataStore.GetDataset()
+	order, desc, err := parseSort(r.URL.Query(), dataset.Orders)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	// The sorted order holds its own medicaments, so the page is bounded by the same dataset
+	medicaments := dataset.Medicaments
+	totalItems := len(medicaments)
+	if order != nil {
+		totalItems = order.Len()
+	}
+
 	pageSize := 10
 	start := (page - 1) * pageSize
-	end := start + pageSize
+	end := min(start+pageSize, totalItems)
 
-	if start >= len(medicaments) {
+	if start >= totalItems {
 		h.RespondWithError(w, http.StatusNotFound, "Page not found")
 		return
 	}
 
-	if end > len(medicaments) {
-		end = len(medicaments)
+	var pagedMedicaments []entities.Medicament
+	if order != nil {
+		pagedMedicaments = orderRange(order, desc, start, end)
+	} else {
+		pagedMedicaments = medicaments[start:end]
 	}
-
-	pagedMedicaments := medicaments[start:end]
-	totalItems := len(medicaments)
 	maxPage := (totalItems + pageSize - 1) / pageSize
 
 	response := map[string]any{
@@ -252,18 +583,22 @@ func (h *Handler) FindMedicament(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Sanitize input (replace + with space for flexible matching)
-	sanitizedElement := strings.ToLower(element)
-	sanitizedElement = strings.ReplaceAll(sanitizedElement, "+", " ")
+	// Sanitize input (fold accents, replace + with space for flexible matching)
+	sanitizedElement := search.Normalize(element)
 
 	// Add deprecation headers
 	newPath := fmt.Sprintf("/v1/medicament?search=%v", element)
 	h.AddDeprecationHeaders(w, r, newPath)
 
-	medicaments := h.dataStore.GetMedicaments()
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	// The legacy route keeps its substring matching in dataset order, ranked search is /v1/medicaments?search
 	var results []entities.Medicament
 
-	for _, med := range medicaments {
+	for _, med := range dataset.Medicaments {
 		if strings.Contains(med.DenominationNormalized, sanitizedElement) {
 			results = append(results, med)
 		}
@@ -296,14 +631,72 @@ func (h *Handler) FindMedicamentByCIS(w http.ResponseWriter, r *http.Request) {
 		h.AddDeprecationHeaders(w, r, newPath)
 	}
 
-	medicamentsMap := h.dataStore.GetMedicamentsMap()
-	med, exists := medicamentsMap[cis]
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	expand, err := parseExpand(r.URL.Query(), medicamentRelations)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// With expand, fields select among the references and relations actually returned
+	var shape any = entities.Medicament{}
+	if expand != nil {
+		shape = expandMedicament(&interfaces.Dataset{}, entities.Medicament{}, expand)
+	}
+	r, ok := h.withFields(w, r, shape)
+	if !ok {
+		return
+	}
+
+	med, exists := dataset.MedicamentsMap[cis]
+	if !exists {
+		h.RespondWithError(w, http.StatusNotFound, "Medicament not found")
+		return
+	}
+
+	if expand == nil {
+		h.RespondWithJSON(w, http.StatusOK, fieldset.Project(med, selectedFields(r)))
+		return
+	}
+	h.RespondWithJSON(w, http.StatusOK, fieldset.Project(expandMedicament(dataset, med, expand), selectedFields(r)))
+}
+
+// ServeMedicamentAlertsV1 returns the safety information currently in effect for a medicament
+func (h *Handler) ServeMedicamentAlertsV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
+
+	cis, err := h.validator.ValidateCIS(r.PathValue("cis"))
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	med, exists := dataset.MedicamentsMap[cis]
 	if !exists {
 		h.RespondWithError(w, http.StatusNotFound, "Medicament not found")
 		return
 	}
 
-	h.RespondWithJSON(w, http.StatusOK, med)
+	now := time.Now()
+	alerts := make([]entities.InfoImportante, 0, len(med.InfosImportantes))
+	for _, info := range med.InfosImportantes {
+		if isInfoImportanteActive(info, now) {
+			alerts = append(alerts, info)
+		}
+	}
+
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, alerts)
 }
 
 // FindMedicamentByCIP finds a medicament by its presentation cip7 or cip13
@@ -319,7 +712,12 @@ func (h *Handler) FindMedicamentByCIP(w http.ResponseWriter, r *http.Request) {
 	newPath := fmt.Sprintf("/v1/medicament?cip=%v", cip)
 	h.AddDeprecationHeaders(w, r, newPath)
 
-	med, found := h.findMedicamentByCIP(cip)
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	med, found := findMedicamentByCIP(dataset, cip)
 	if !found {
 		h.RespondWithError(w, http.StatusNotFound, "Medicament not found")
 		return
@@ -342,19 +740,22 @@ func (h *Handler) FindGeneriques(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Sanitize input and convert to lowercase for case-insensitive search
-	sanitizedLibelle := strings.ToLower(libelle)
-	// Normalize: replace + with space for flexible matching
-	sanitizedLibelle = strings.ReplaceAll(sanitizedLibelle, "+", " ")
+	// Normalize input the same way as LibelleNormalized: fold accents, lowercase,
+	// replace + with space for flexible matching
+	sanitizedLibelle := search.Normalize(libelle)
 
 	// Add deprecation headers
 	newPath := fmt.Sprintf("/v1/generiques?libelle=%v", libelle)
 	h.AddDeprecationHeaders(w, r, newPath)
 
-	generiques := h.dataStore.GetGeneriques()
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
 	var results []entities.GeneriqueList
 
-	for _, gen := range generiques {
+	for _, gen := range dataset.Generiques {
 		if strings.Contains(gen.LibelleNormalized, sanitizedLibelle) {
 			results = append(results, gen)
 		}
@@ -370,6 +771,10 @@ func (h *Handler) FindGeneriques(w http.ResponseWriter, r *http.Request) {
 
 // FindGeneriquesByGroupID finds generiques by group ID
 func (h *Handler) FindGeneriquesByGroupID(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
+
 	// Support both v1 path parameter (groupID) and legacy path parameter (groupId)
 	groupIDStr := r.PathValue("groupID")
 	if groupIDStr == "" {
@@ -390,14 +795,28 @@ func (h *Handler) FindGeneriquesByGroupID(w http.ResponseWriter, r *http.Request
 		h.AddDeprecationHeaders(w, r, newPath)
 	}
 
-	generiquesMap := h.dataStore.GetGeneriquesMap()
-	gen, exists := generiquesMap[groupID]
+	expand, err := parseExpand(r.URL.Query(), generiqueRelations)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	gen, exists := dataset.GeneriquesMap[groupID]
 	if !exists {
 		h.RespondWithError(w, http.StatusNotFound, "Generique group not found")
 		return
 	}
 
-	h.RespondWithJSONAndETag(w, r, http.StatusOK, gen)
+	if expand == nil {
+		h.RespondWithJSONAndETag(w, r, http.StatusOK, gen)
+		return
+	}
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, expandGeneriqueList(dataset, gen, expand))
 }
 
 // HealthCheck returns server health information
@@ -418,6 +837,8 @@ type DiagnosticsResponseImpl struct {
 	UptimeSeconds float64        `json:"uptime_seconds"`
 	NextUpdate    string         `json:"next_update"`
 	DataAgeHours  float64        `json:"data_age_hours"`
+	DataVersion   uint64         `json:"data_version"`
+	DataHash      string         `json:"data_hash"`
 	System        map[string]any `json:"system"`
 	DataIntegrity map[string]any `json:"data_integrity"`
 }
@@ -441,6 +862,7 @@ func (h *Handler) ServeDiagnosticsV1(w http.ResponseWriter, r *http.Request) {
 	// Get data statistics
 	lastUpdate := h.dataStore.GetLastUpdated()
 	dataAge := time.Since(lastUpdate)
+	dataVersion := h.dataStore.GetDataVersion()
 
 	// Get cached data quality report (no recomputation)
 	report := h.dataStore.GetDataQualityReport()
@@ -471,6 +893,14 @@ func (h *Handler) ServeDiagnosticsV1(w http.ResponseWriter, r *http.Request) {
 			"count":      report.PresentationsWithOrphanedCIS,
 			"sample_cip": report.PresentationsWithOrphanedCISCIPList,
 		},
+		"stale_source_files": map[string]any{
+			"count": len(report.StaleSourceFiles),
+			"files": report.StaleSourceFiles,
+		},
+		"skipped_source_files": map[string]any{
+			"count": len(report.SkippedSourceFiles),
+			"files": report.SkippedSourceFiles,
+		},
 	}
 
 	response := DiagnosticsResponseImpl{
@@ -478,6 +908,8 @@ func (h *Handler) ServeDiagnosticsV1(w http.ResponseWriter, r *http.Request) {
 		UptimeSeconds: uptime.Seconds(),
 		NextUpdate:    h.healthChecker.CalculateNextUpdate().Format(time.RFC3339),
 		DataAgeHours:  dataAge.Hours(),
+		DataVersion:   dataVersion.Version,
+		DataHash:      dataVersion.Hash,
 		System: map[string]any{
 			"goroutines": runtime.NumGoroutine(),
 			"memory": map[string]any{
@@ -497,6 +929,10 @@ func (h *Handler) ServeDiagnosticsV1(w http.ResponseWriter, r *http.Request) {
 // NEW v1 handlers
 
 func (h *Handler) ServePresentationsV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
+
 	cipStr := r.PathValue("cip")
 
 	// Validate the CIP
@@ -506,17 +942,19 @@ func (h *Handler) ServePresentationsV1(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
 	// Search first in the CIP7
-	presentationsCIP7 := h.dataStore.GetPresentationsCIP7Map()
-	if pres, ok := presentationsCIP7[cip]; ok {
+	if pres, ok := dataset.PresentationsCIP7Map[cip]; ok {
 		h.RespondWithJSONAndETag(w, r, http.StatusOK, pres)
 		return
 	}
 
 	// If not, in the CIP13
-	presentationsCIP13 := h.dataStore.GetPresentationsCIP13Map()
-
-	if pres, ok := presentationsCIP13[cip]; ok {
+	if pres, ok := dataset.PresentationsCIP13Map[cip]; ok {
 		h.RespondWithJSONAndETag(w, r, http.StatusOK, pres)
 		return
 	}
@@ -525,175 +963,447 @@ func (h *Handler) ServePresentationsV1(w http.ResponseWriter, r *http.Request) {
 	h.RespondWithError(w, http.StatusNotFound, "Presentation not found")
 }
 
-// ServePresentationsMissingCIP handles requests to /v1/presentations/ without a CIP path parameter.
-// Returns a 400 Bad Request error indicating that the CIP path parameter is required.
-func (h *Handler) ServePresentationsMissingCIP(w http.ResponseWriter, r *http.Request) {
-	h.RespondWithError(w, http.StatusBadRequest, "CIP path parameter is required")
-}
-
-func (h *Handler) ServeGeneriquesV1(w http.ResponseWriter, r *http.Request) {
-	libelle := r.URL.Query().Get("libelle")
-
-	if libelle == "" {
-		h.RespondWithError(w, http.StatusBadRequest, "Needs libelle param")
+// ServeDisponibilitesV1 returns the reported shortages and supply tensions.
+// Results can be narrowed with the optional status (1-4) and cis query parameters.
+func (h *Handler) ServeDisponibilitesV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
 		return
 	}
 
-	// Validate user input using the validator
-	if err := h.validator.ValidateInput(libelle); err != nil {
-		h.RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
+	q := r.URL.Query()
+
+	status := 0
+	if statusStr := q.Get("status"); statusStr != "" {
+		var err error
+		status, err = strconv.Atoi(statusStr)
+		if err != nil || status < 1 || status > 4 {
+			h.RespondWithError(w, http.StatusBadRequest, "Invalid status. Must be between 1 and 4")
+			return
+		}
 	}
 
-	// Sanitize input and convert to lowercase for case-insensitive search
-	sanitizedLibelle := strings.ToLower(libelle)
-	// Normalize: replace + with space for flexible matching
-	sanitizedLibelle = strings.ReplaceAll(sanitizedLibelle, "+", " ")
+	cis := 0
+	if cisStr := q.Get("cis"); cisStr != "" {
+		var err error
+		cis, err = h.validator.ValidateCIS(cisStr)
+		if err != nil {
+			h.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
-	// Split search query into individual words for multi-word search
-	searchWords := strings.Fields(sanitizedLibelle)
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
 
-	generiques := h.dataStore.GetGeneriques()
-	var results []entities.GeneriqueList
+	results := make([]entities.Disponibilite, 0)
 
-	for _, gen := range generiques {
-		// Check if ALL search words exist in libelle (AND logic)
-		allMatch := true
-		for _, word := range searchWords {
-			if !strings.Contains(gen.LibelleNormalized, word) {
-				allMatch = false
-				break
-			}
+	for _, dispo := range dataset.Disponibilites {
+		if status != 0 && dispo.CodeStatut != status {
+			continue
 		}
-
-		if allMatch {
-			results = append(results, gen)
-			// Check if there are more results than the maximum, return error
-			if len(results) > maxGeneriqueSearchResults {
-				h.RespondWithError(w, http.StatusBadRequest, errTooManyGeneriquesResults)
-				return
-
-			}
+		if cis != 0 && dispo.Cis != cis {
+			continue
 		}
+		results = append(results, dispo)
 	}
 
-	if len(results) != 0 {
-		h.RespondWithJSONAndETag(w, r, http.StatusOK, results)
+	if len(results) == 0 {
+		h.RespondWithError(w, http.StatusNotFound, "No disponibilites found")
 		return
 	}
-	h.RespondWithError(w, http.StatusNotFound, "No generiques found")
+
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, results)
 }
 
-func (h *Handler) ServeMedicamentsV1(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
+// ServeSuggestV1 returns the denominations matching a partially typed query, for search-as-you-type.
+// The number of suggestions defaults to 10 and can be set with limit (1-20).
+func (h *Handler) ServeSuggestV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
 
-	totalParams := 0
-	for _, v := range []string{q.Get("cip"), q.Get("search"), q.Get("page")} {
-		if v != "" {
-			totalParams++
+	q := r.URL.Query()
+
+	query := q.Get("q")
+	if query == "" {
+		h.RespondWithError(w, http.StatusBadRequest, "Needs q param")
+		return
+	}
+
+	if err := h.validator.ValidateInput(query); err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if limitStr := q.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSuggestLimit {
+			h.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit. Must be between 1 and %d", maxSuggestLimit))
+			return
 		}
 	}
 
-	if q.Get("pageSize") != "" && q.Get("page") == "" {
-		h.RespondWithError(w, http.StatusBadRequest, "pageSize can only be used with page")
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	suggestions := dataset.SearchIndex.Suggest(query, limit)
+	if len(suggestions) == 0 {
+		// An empty list is a normal outcome while the user is typing
+		suggestions = []search.Suggestion{}
 	}
 
-	if totalParams == 0 {
-		h.RespondWithError(w, http.StatusBadRequest, "Needs at least one param. See documentation")
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, suggestions)
+}
+
+// ServeChangesV1 returns the changes between the retained successive datasets.
+// since is a dataVersion or an RFC 3339 timestamp; without it every retained change set is returned.
+// When the changes since that point are no longer retained, the client has to download the export again.
+func (h *Handler) ServeChangesV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
 		return
 	}
 
-	if totalParams > 1 {
-		h.RespondWithError(w, http.StatusBadRequest, "Only one parameter allowed at a time. Choose: page, cip, search")
+	current := h.dataStore.GetDataVersion()
+	if !h.checkVersion(w, r, current) {
 		return
 	}
+	changeSets := h.dataStore.GetChanges()
 
-	// Paginated results
-	if pageNumber := q.Get("page"); pageNumber != "" {
-		page, err := strconv.Atoi(pageNumber)
-		if err != nil || page < 1 {
-			logging.Warn("Unusual user input", "pageNumber", pageNumber)
-			h.RespondWithError(w, http.StatusBadRequest, "Invalid page number")
+	selected := changeSets
+	if since := r.URL.Query().Get("since"); since != "" {
+		var covered func(changes.ChangeSet) bool
+		var retained bool
+
+		if version, err := strconv.ParseUint(since, 10, 64); err == nil {
+			oldest := current.Version
+			if len(changeSets) > 0 {
+				oldest = changeSets[0].FromVersion
+			}
+			retained = version >= oldest && version <= current.Version
+			covered = func(changeSet changes.ChangeSet) bool { return changeSet.FromVersion >= version }
+		} else if timestamp, err := time.Parse(time.RFC3339, since); err == nil {
+			oldest := current.LoadedAt
+			if len(changeSets) > 0 {
+				oldest = changeSets[0].FromLoadedAt
+			}
+			retained = !timestamp.Before(oldest)
+			covered = func(changeSet changes.ChangeSet) bool { return changeSet.ToLoadedAt.After(timestamp) }
+		} else {
+			h.RespondWithError(w, http.StatusBadRequest, "Invalid since. Use a dataVersion or an RFC 3339 timestamp")
+			return
+		}
+
+		if !retained {
+			h.RespondWithError(w, http.StatusGone, fmt.Sprintf("Changes since %s are no longer available. Download the full export", since))
 			return
 		}
 
-		pageSize := defaultPageSize
+		selected = make([]changes.ChangeSet, 0, len(changeSets))
+		for _, changeSet := range changeSets {
+			if covered(changeSet) {
+				selected = append(selected, changeSet)
+			}
+		}
+	}
+
+	response := map[string]any{
+		"dataVersion": current.Version,
+		"changes":     selected,
+	}
 
-		if pageSizeStr := q.Get("pageSize"); pageSizeStr != "" {
-			pageSize, err = strconv.Atoi(pageSizeStr)
-			if err != nil || pageSize < 1 || pageSize > maxPageSize {
-				msg := fmt.Sprintf("Invalid pageSize. Must be between 1 and %d", maxPageSize)
-				h.RespondWithError(w, http.StatusBadRequest, msg)
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, response)
+}
+
+// ServeSubstancesV1 lists the active substances sorted by denomination.
+// With the search query parameter, it returns the substances whose name starts with it instead.
+func (h *Handler) ServeSubstancesV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
+
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+	index := dataset.SubstanceIndex
+
+	searchQuery := r.URL.Query().Get("search")
+	if searchQuery == "" {
+		h.RespondWithJSONAndETag(w, r, http.StatusOK, index.Substances())
+		return
+	}
+
+	if err := h.validator.ValidateInput(searchQuery); err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	suggestions := index.Suggest(searchQuery, maxSubstanceSuggestions)
+	if len(suggestions) == 0 {
+		h.RespondWithError(w, http.StatusNotFound, "No substances found")
+		return
+	}
+
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, suggestions)
+}
+
+// ServeSubstanceMedicamentsV1 returns the medicaments containing a substance, paginated with page and pageSize
+func (h *Handler) ServeSubstanceMedicamentsV1(w http.ResponseWriter, r *http.Request) {
+	code, err := strconv.Atoi(r.PathValue("code"))
+	if err != nil || code <= 0 {
+		h.RespondWithError(w, http.StatusBadRequest, "Invalid substance code")
+		return
+	}
+
+	page, pageSize, err := parsePagination(r.URL.Query(), defaultSearchPageSize)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	r, ok := h.withFields(w, r, entities.Medicament{})
+	if !ok {
+		return
+	}
+
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	index := dataset.SubstanceIndex
+	if _, exists := index.Get(code); !exists {
+		h.RespondWithError(w, http.StatusNotFound, "Substance not found")
+		return
+	}
+
+	cisList := index.Medicaments(code)
+	results := make([]entities.Medicament, 0, len(cisList))
+	for _, cis := range cisList {
+		if med, exists := dataset.MedicamentsMap[cis]; exists {
+			results = append(results, med)
+		}
+	}
+
+	respondWithPage(h, w, r, results, page, pageSize)
+}
+
+// ServePresentationsMissingCIP handles requests to /v1/presentations/ without a CIP path parameter.
+// Returns a 400 Bad Request error indicating that the CIP path parameter is required.
+func (h *Handler) ServePresentationsMissingCIP(w http.ResponseWriter, r *http.Request) {
+	h.RespondWithError(w, http.StatusBadRequest, "CIP path parameter is required")
+}
+
+func (h *Handler) ServeGeneriquesV1(w http.ResponseWriter, r *http.Request) {
+	if h.fieldsUnsupported(w, r) {
+		return
+	}
+
+	libelle := r.URL.Query().Get("libelle")
+
+	if libelle == "" {
+		h.RespondWithError(w, http.StatusBadRequest, "Needs libelle param")
+		return
+	}
+
+	// Validate user input using the validator
+	if err := h.validator.ValidateInput(libelle); err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Normalize input the same way as LibelleNormalized: fold accents, lowercase,
+	// replace + with space for flexible matching
+	sanitizedLibelle := search.Normalize(libelle)
+
+	// Split search query into individual words for multi-word search
+	searchWords := strings.Fields(sanitizedLibelle)
+
+	dataset := h.dataStore.GetDataset()
+	if !h.checkVersion(w, r, dataset.Version) {
+		return
+	}
+
+	var results []entities.GeneriqueList
+
+	for _, gen := range dataset.Generiques {
+		// Check if ALL search words exist in libelle (AND logic)
+		allMatch := true
+		for _, word := range searchWords {
+			if !strings.Contains(gen.LibelleNormalized, word) {
+				allMatch = false
+				break
+			}
+		}
+
+		if allMatch {
+			results = append(results, gen)
+			// Check if there are more results than the maximum, return error
+			if len(results) > maxGeneriqueSearchResults {
+				h.RespondWithError(w, http.StatusBadRequest, errTooManyGeneriquesResults)
 				return
+
 			}
+		}
+	}
+
+	if len(results) != 0 {
+		h.RespondWithJSONAndETag(w, r, http.StatusOK, results)
+		return
+	}
+	h.RespondWithError(w, http.StatusNotFound, "No generiques found")
+}
+
+func (h *Handler) ServeMedicamentsV1(w http.ResponseWriter, r *http.Request) {
+	// fields selects the medicament fields of every response below
+	r, ok := h.withFields(w, r, entities.Medicament{})
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	searchQuery := q.Get("search")
 
+	totalParams := 0
+	for _, v := range []string{q.Get("cip"), searchQuery, q.Get("page")} {
+		if v != "" {
+			totalParams++
 		}
+	}
+
+	// page selects a page of the ranked results when combined with search
+	if searchQuery != "" && q.Get("page") != "" {
+		totalParams--
+	}
 
-		medicaments := h.dataStore.GetMedicaments()
+	// Filters apply on top of page and search, or list the first page of matching medicaments on their own
+	filter, err := h.parseMedicamentFilter(q)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-		start := (page - 1) * pageSize
-		end := start + pageSize
+	if q.Get("pageSize") != "" && q.Get("page") == "" && searchQuery == "" && !q.Has("cursor") && !filter.active() {
+		h.RespondWithError(w, http.StatusBadRequest, "pageSize can only be used with page, search, cursor or filters")
+		return
+	}
+	if filter.active() && q.Get("cip") != "" {
+		h.RespondWithError(w, http.StatusBadRequest, filter.params[0]+" can only be combined with page or search")
+		return
+	}
 
-		if start >= len(medicaments) {
-			h.RespondWithError(w, http.StatusNotFound, "Page not found")
+	// Every response below is read from this dataset, sort included
+	dataset := h.dataStore.GetDataset()
+
+	// sort orders page and filter listings using the orders computed on data load
+	order, desc, err := parseSort(q, dataset.Orders)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if order != nil && (searchQuery != "" || q.Get("cip") != "") {
+		h.RespondWithError(w, http.StatusBadRequest, "sort can only be combined with page or filters")
+		return
+	}
+
+	// cursor walks the medicaments by CIS, an empty cursor starts from the first one
+	if q.Has("cursor") {
+		if totalParams > 0 || order != nil {
+			h.RespondWithError(w, http.StatusBadRequest, "cursor can only be combined with pageSize or filters")
 			return
 		}
 
-		if end > len(medicaments) {
-			end = len(medicaments)
+		if !h.checkVersion(w, r, dataset.Version) {
+			return
 		}
+		h.serveMedicamentsCursor(w, r, dataset, filter)
+		return
+	}
 
-		pagedMedicaments := medicaments[start:end]
-		totalItems := len(medicaments)
-		maxPage := (totalItems + pageSize - 1) / pageSize
-
-		response := map[string]any{
-			"data":       pagedMedicaments,
-			"page":       page,
-			"pageSize":   pageSize,
-			"totalItems": totalItems,
-			"maxPage":    maxPage,
+	// fuzzy switches search to typo-tolerant matching
+	fuzzyStr := q.Get("fuzzy")
+	var fuzzy bool
+	if fuzzyStr != "" {
+		var err error
+		fuzzy, err = strconv.ParseBool(fuzzyStr)
+		if err != nil {
+			h.RespondWithError(w, http.StatusBadRequest, "Invalid fuzzy value. Must be true or false")
+			return
 		}
+		if searchQuery == "" {
+			h.RespondWithError(w, http.StatusBadRequest, "fuzzy can only be used with search")
+			return
+		}
+	}
+
+	if totalParams == 0 && !filter.active() {
+		h.RespondWithError(w, http.StatusBadRequest, "Needs at least one param. See documentation")
+		return
+	}
+
+	if totalParams > 1 {
+		h.RespondWithError(w, http.StatusBadRequest, "Only one parameter allowed at a time. Choose: page, cip, search")
+		return
+	}
 
-		h.RespondWithJSONAndETag(w, r, http.StatusOK, response)
+	if !h.checkVersion(w, r, dataset.Version) {
 		return
 	}
 
-	// Search query
-	if searchQuery := q.Get("search"); searchQuery != "" {
+	// Ranked search query
+	if searchQuery != "" {
 		// Validate input using the validator
 		if err := h.validator.ValidateInput(searchQuery); err != nil {
 			h.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		// Sanitize and normalize input (replace + with space for flexible matching)
-		sanitizedElement := strings.ToLower(searchQuery)
-		sanitizedElement = strings.ReplaceAll(sanitizedElement, "+", " ")
-
-		// Split search query into individual words for multi-word search
-		searchWords := strings.Fields(sanitizedElement)
+		page, pageSize, err := parsePagination(q, defaultSearchPageSize)
+		if err != nil {
+			h.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
-		medicaments := h.dataStore.GetMedicaments()
-		var results []entities.Medicament
+		medicamentsMap := dataset.MedicamentsMap
+		index := dataset.SearchIndex
 
-		for _, med := range medicaments {
-			// Check if ALL search words exist in denomination (AND logic)
-			allMatch := true
-			for _, word := range searchWords {
-				if !strings.Contains(med.DenominationNormalized, word) {
-					allMatch = false
-					break // Early termination - skip this medicament immediately
+		if fuzzy {
+			hits := index.FuzzySearch(searchQuery)
+			results := make([]scoredMedicament, 0, len(hits))
+			for _, hit := range hits {
+				med, exists := medicamentsMap[hit.Cis]
+				if !exists || !filter.matches(med) {
+					continue
 				}
+				results = append(results, scoredMedicament{
+					Medicament: med,
+					Similarity: math.Round(hit.Similarity*1000) / 1000,
+				})
 			}
-			if allMatch {
-				results = append(results, med)
-				if len(results) > maxMedicamentSearchResults {
-					h.RespondWithError(w, http.StatusBadRequest, errTooManyMedicamentsResults)
-					return
-				}
 
+			if len(results) == 0 {
+				h.RespondWithError(w, http.StatusNotFound, "No medicaments found")
+				return
+			}
+
+			respondWithPage(h, w, r, results, page, pageSize)
+			return
+		}
+
+		hits := index.Search(searchQuery)
+		results := make([]entities.Medicament, 0, len(hits))
+		for _, hit := range hits {
+			med, exists := medicamentsMap[hit.Cis]
+			if !exists || !filter.matches(med) {
+				continue
 			}
+			results = append(results, med)
 		}
 
 		// Return 404 if no results found
@@ -702,7 +1412,7 @@ func (h *Handler) ServeMedicamentsV1(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		h.RespondWithJSONAndETag(w, r, http.StatusOK, results)
+		respondWithPage(h, w, r, results, page, pageSize)
 		return
 	}
 
@@ -714,7 +1424,7 @@ func (h *Handler) ServeMedicamentsV1(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		med, found := h.findMedicamentByCIP(cip)
+		med, found := findMedicamentByCIP(dataset, cip)
 		if !found {
 			h.RespondWithError(w, http.StatusNotFound, "Medicament not found")
 			return
@@ -724,6 +1434,528 @@ func (h *Handler) ServeMedicamentsV1(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.RespondWithError(w, http.StatusBadRequest, "Unexpected error")
+	// Paginated results, filters used alone list their first page
+	page, pageSize, err := parsePagination(q, defaultPageSize)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Without filters only the requested page is read from the sorted order
+	if order != nil && !filter.active() {
+		start := (page - 1) * pageSize
+		if start >= order.Len() {
+			h.RespondWithError(w, http.StatusNotFound, "Page not found")
+			return
+		}
+
+		items := orderRange(order, desc, start, min(start+pageSize, order.Len()))
+		writePage(h, w, r, items, page, pageSize, order.Len())
+		return
+	}
+
+	medicaments := listMedicaments(dataset, filter, order, desc)
+	if len(medicaments) == 0 && filter.active() {
+		h.RespondWithError(w, http.StatusNotFound, "No medicaments found")
+		return
+	}
+
+	respondWithPage(h, w, r, medicaments, page, pageSize)
+}
+
+// scoredMedicament is a fuzzy search hit with its similarity to the query (0 to 1)
+type scoredMedicament struct {
+	entities.Medicament
+	Similarity float64 `json:"similarity"`
+}
+
+// expandedMedicament is a medicament whose relations are embedded or referenced as selected by
+// expand. Its fields shadow the relations of the embedded medicament when encoded.
+type expandedMedicament struct {
+	entities.Medicament
+	Presentation    any `json:"presentation"`
+	Generiques      any `json:"generiques"`
+	GeneriqueGroups any `json:"generiqueGroups,omitempty"`
+}
+
+// expandedGeneriqueList is a generique group whose medicaments are embedded or referenced as selected by expand
+type expandedGeneriqueList struct {
+	entities.GeneriqueList
+	Medicaments any `json:"medicaments"`
+}
+
+// generiqueMember is a full medicament of a generique group, with its type in the group
+type generiqueMember struct {
+	entities.Medicament
+	Type string `json:"type"`
+}
+
+// References replace a related entity by its identifiers and the link to fetch it
+type (
+	medicamentRef struct {
+		Cis  int    `json:"cis"`
+		Type string `json:"type"`
+		Href string `json:"href"`
+	}
+	presentationRef struct {
+		Cip7  int    `json:"cip7"`
+		Cip13 int    `json:"cip13"`
+		Href  string `json:"href"`
+	}
+	generiqueRef struct {
+		Group int    `json:"group"`
+		Type  string `json:"type"`
+		Href  string `json:"href"`
+	}
+)
+
+// parseExpand reads the expand parameter. It returns nil when expand is absent, so that responses
+// keep their default shape, otherwise the relations to embed, the others being referenced.
+func parseExpand(q url.Values, relations []string) (map[string]bool, error) {
+	if !q.Has("expand") {
+		return nil, nil
+	}
 
+	expand := make(map[string]bool)
+	value := q.Get("expand")
+	if value == "" {
+		return expand, nil
+	}
+	for relation := range strings.SplitSeq(value, ",") {
+		relation = strings.TrimSpace(relation)
+		if !slices.Contains(relations, relation) {
+			return nil, fmt.Errorf("Invalid expand: unknown relation %q. Use one of: %s", relation, strings.Join(relations, ", "))
+		}
+		expand[relation] = true
+	}
+	return expand, nil
+}
+
+// expandMedicament embeds or references the presentations and generiques of a medicament, and
+// embeds the details of its generique groups with generiqueGroups
+func expandMedicament(dataset *interfaces.Dataset, med entities.Medicament, expand map[string]bool) expandedMedicament {
+	expanded := expandedMedicament{Medicament: med, Presentation: med.Presentation, Generiques: med.Generiques}
+
+	if !expand["presentation"] {
+		refs := make([]presentationRef, len(med.Presentation))
+		for i, pres := range med.Presentation {
+			refs[i] = presentationRef{Cip7: pres.Cip7, Cip13: pres.Cip13, Href: fmt.Sprintf("/v1/presentations/%07d", pres.Cip7)}
+		}
+		expanded.Presentation = refs
+	}
+
+	if !expand["generiques"] {
+		refs := make([]generiqueRef, len(med.Generiques))
+		for i, gen := range med.Generiques {
+			refs[i] = generiqueRef{Group: gen.Group, Type: gen.Type, Href: fmt.Sprintf("/v1/generiques/%d", gen.Group)}
+		}
+		expanded.Generiques = refs
+	}
+
+	if expand["generiqueGroups"] {
+		generiquesMap := dataset.GeneriquesMap
+		groups := make([]entities.GeneriqueList, 0, len(med.Generiques))
+		seen := make(map[int]bool, len(med.Generiques))
+		for _, gen := range med.Generiques {
+			group, exists := generiquesMap[gen.Group]
+			if !exists || seen[gen.Group] {
+				continue
+			}
+			seen[gen.Group] = true
+			groups = append(groups, group)
+		}
+		expanded.GeneriqueGroups = groups
+	}
+
+	return expanded
+}
+
+// expandGeneriqueList embeds the full medicaments of a generique group or references them
+func expandGeneriqueList(dataset *interfaces.Dataset, gen entities.GeneriqueList, expand map[string]bool) expandedGeneriqueList {
+	if !expand["medicaments"] {
+		refs := make([]medicamentRef, len(gen.Medicaments))
+		for i, med := range gen.Medicaments {
+			refs[i] = medicamentRef{Cis: med.Cis, Type: med.Type, Href: fmt.Sprintf("/v1/medicaments/%08d", med.Cis)}
+		}
+		return expandedGeneriqueList{GeneriqueList: gen, Medicaments: refs}
+	}
+
+	medicamentsMap := dataset.MedicamentsMap
+	members := make([]generiqueMember, 0, len(gen.Medicaments))
+	for _, med := range gen.Medicaments {
+		if full, exists := medicamentsMap[med.Cis]; exists {
+			members = append(members, generiqueMember{Medicament: full, Type: med.Type})
+		}
+	}
+	return expandedGeneriqueList{GeneriqueList: gen, Medicaments: members}
+}
+
+// respondWithPage writes one page of items with the pagination metadata
+func respondWithPage[T any](h *Handler, w http.ResponseWriter, r *http.Request, items []T, page, pageSize int) {
+	start := (page - 1) * pageSize
+	end := start + pageSize
+
+	if start >= len(items) {
+		h.RespondWithError(w, http.StatusNotFound, "Page not found")
+		return
+	}
+
+	if end > len(items) {
+		end = len(items)
+	}
+
+	writePage(h, w, r, items[start:end], page, pageSize, len(items))
+}
+
+// writePage writes the items of an already sliced page with the pagination metadata
+func writePage[T any](h *Handler, w http.ResponseWriter, r *http.Request, pageItems []T, page, pageSize, totalItems int) {
+	maxPage := (totalItems + pageSize - 1) / pageSize
+
+	response := map[string]any{
+		"data":       pageItems,
+		"page":       page,
+		"pageSize":   pageSize,
+		"totalItems": totalItems,
+		"maxPage":    maxPage,
+	}
+
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, response)
+}
+
+// medicamentCursor is the position of a cursor page, encoded as opaque base64 JSON.
+// Version is the dataset the cursor was issued for, so a cursor from a replaced
+// dataset is rejected instead of silently skipping or repeating medicaments.
+type medicamentCursor struct {
+	Version uint64 `json:"v"`
+	Cis     int    `json:"c"`
+	Before  bool   `json:"b,omitempty"` // Page ends just before Cis instead of starting just after
+}
+
+func encodeCursor(c medicamentCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (medicamentCursor, error) {
+	var c medicamentCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// serveMedicamentsCursor writes one cursor page of medicaments in CIS order with next and prev
+// links. Unlike page numbers, cursors do not shift when medicaments are added or removed.
+func (h *Handler) serveMedicamentsCursor(w http.ResponseWriter, r *http.Request, dataset *interfaces.Dataset, filter medicamentFilter) {
+	q := r.URL.Query()
+
+	_, pageSize, err := parsePagination(q, defaultPageSize)
+	if err != nil {
+		h.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The cursor version and the order it walks come from the same dataset
+	version := dataset.Version.Version
+	order := dataset.Orders[search.SortCIS]
+
+	var cursor medicamentCursor
+	if token := q.Get("cursor"); token != "" {
+		cursor, err = decodeCursor(token)
+		if err != nil {
+			h.RespondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		if cursor.Version != version {
+			h.RespondWithError(w, http.StatusGone, "Cursor refers to a dataset that has been replaced. Restart with an empty cursor")
+			return
+		}
+	}
+
+	var items []entities.Medicament
+	var hasPrev, hasNext bool
+	if cursor.Before {
+		end := order.Find(func(med entities.Medicament) bool { return med.Cis >= cursor.Cis })
+		items, hasPrev = scanOrder(order, filter, end-1, -1, pageSize)
+		slices.Reverse(items)
+		_, hasNext = scanOrder(order, filter, end, 1, 0)
+	} else {
+		start := order.Find(func(med entities.Medicament) bool { return med.Cis > cursor.Cis })
+		items, hasNext = scanOrder(order, filter, start, 1, pageSize)
+		_, hasPrev = scanOrder(order, filter, start-1, -1, 0)
+	}
+
+	link := func(c medicamentCursor) string {
+		q.Set("cursor", encodeCursor(c))
+		return r.URL.Path + "?" + q.Encode()
+	}
+
+	response := map[string]any{
+		"data":     items,
+		"pageSize": pageSize,
+		"next":     nil,
+		"prev":     nil,
+	}
+	if len(items) > 0 && hasNext {
+		response["next"] = link(medicamentCursor{Version: version, Cis: items[len(items)-1].Cis})
+	}
+	if len(items) > 0 && hasPrev {
+		response["prev"] = link(medicamentCursor{Version: version, Cis: items[0].Cis, Before: true})
+	}
+
+	h.RespondWithJSONAndETag(w, r, http.StatusOK, response)
+}
+
+// scanOrder collects up to limit medicaments matching filter from position i of the
+// ascending order, moving by step. It also reports whether another match follows them.
+func scanOrder(order *search.Order, filter medicamentFilter, i, step, limit int) ([]entities.Medicament, bool) {
+	items := make([]entities.Medicament, 0, limit)
+	for ; i >= 0 && i < order.Len(); i += step {
+		med := order.At(i, false)
+		if !filter.matches(med) {
+			continue
+		}
+		if len(items) == limit {
+			return items, true
+		}
+		items = append(items, med)
+	}
+	return items, false
+}
+
+// parseSort reads the sort query parameter, a field name prefixed with - for descending order.
+// It returns a nil order when no sort is requested.
+func parseSort(q url.Values, orders search.Orders) (*search.Order, bool, error) {
+	sortField := q.Get("sort")
+	if sortField == "" {
+		return nil, false, nil
+	}
+
+	field, desc := strings.CutPrefix(sortField, "-")
+	order, ok := orders[field]
+	if !ok {
+		return nil, false, fmt.Errorf("Invalid sort. Must be one of: %s, prefixed with - for descending order", strings.Join(search.SortFields, ", "))
+	}
+
+	return order, desc, nil
+}
+
+// listMedicaments returns the medicaments matching filter, in the sorted order when one is given
+func listMedicaments(dataset *interfaces.Dataset, filter medicamentFilter, order *search.Order, desc bool) []entities.Medicament {
+	if order == nil {
+		medicaments := dataset.Medicaments
+		if filter.active() {
+			medicaments = filterMedicaments(medicaments, filter)
+		}
+		return medicaments
+	}
+
+	medicaments := make([]entities.Medicament, 0)
+	for i := range order.Len() {
+		if med := order.At(i, desc); filter.matches(med) {
+			medicaments = append(medicaments, med)
+		}
+	}
+	return medicaments
+}
+
+// orderRange returns the medicaments from start to end (excluded) of the sorted order
+func orderRange(order *search.Order, desc bool, start, end int) []entities.Medicament {
+	medicaments := make([]entities.Medicament, 0, end-start)
+	for i := start; i < end; i++ {
+		medicaments = append(medicaments, order.At(i, desc))
+	}
+	return medicaments
+}
+
+// parsePagination reads the page and pageSize query parameters.
+// page defaults to 1 and pageSize to defaultSize.
+func parsePagination(q url.Values, defaultSize int) (int, int, error) {
+	page := 1
+	if pageNumber := q.Get("page"); pageNumber != "" {
+		var err error
+		page, err = strconv.Atoi(pageNumber)
+		if err != nil || page < 1 {
+			logging.Warn("Unusual user input", "pageNumber", pageNumber)
+			return 0, 0, errors.New("Invalid page number")
+		}
+	}
+
+	pageSize := defaultSize
+	if pageSizeStr := q.Get("pageSize"); pageSizeStr != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeStr)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return 0, 0, fmt.Errorf("Invalid pageSize. Must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	return page, pageSize, nil
+}
+
+// medicamentFilter holds the optional filters of /v1/medicaments. Text filters are compared
+// case and accent insensitively, against the whole value or one of the administration routes.
+type medicamentFilter struct {
+	params []string // Filter parameters present in the query, in declaration order
+
+	mitm                  *bool
+	surveillanceRenforcee *bool
+	formePharmaceutique   string
+	voieAdministration    string
+	titulaire             string
+	etatComercialisation  string
+	statusAutorisation    string
+	typeProcedure         string
+	dateAMMFrom           time.Time
+	dateAMMTo             time.Time
+}
+
+// parseMedicamentFilter reads and validates the filter query parameters
+func (h *Handler) parseMedicamentFilter(q url.Values) (medicamentFilter, error) {
+	var filter medicamentFilter
+
+	boolFilters := []struct {
+		param string
+		dest  **bool
+	}{
+		{"mitm", &filter.mitm},
+		{"surveillanceRenforcee", &filter.surveillanceRenforcee},
+	}
+	for _, f := range boolFilters {
+		value := q.Get(f.param)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid %s value. Must be true or false", f.param)
+		}
+		*f.dest = &b
+		filter.params = append(filter.params, f.param)
+	}
+
+	textFilters := []struct {
+		param string
+		dest  *string
+	}{
+		{"formePharmaceutique", &filter.formePharmaceutique},
+		{"voiesAdministration", &filter.voieAdministration},
+		{"titulaire", &filter.titulaire},
+		{"etatComercialisation", &filter.etatComercialisation},
+		{"statusAutorisation", &filter.statusAutorisation},
+		{"typeProcedure", &filter.typeProcedure},
+	}
+	for _, f := range textFilters {
+		value := q.Get(f.param)
+		if value == "" {
+			continue
+		}
+		if err := h.validator.ValidateInput(value); err != nil {
+			return filter, fmt.Errorf("Invalid %s: %w", f.param, err)
+		}
+		*f.dest = normalizeFilterValue(value)
+		filter.params = append(filter.params, f.param)
+	}
+
+	dateFilters := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"dateAMMFrom", &filter.dateAMMFrom},
+		{"dateAMMTo", &filter.dateAMMTo},
+	}
+	for _, f := range dateFilters {
+		value := q.Get(f.param)
+		if value == "" {
+			continue
+		}
+		date, ok := entities.ParseDate(value)
+		if !ok {
+			return filter, fmt.Errorf("Invalid %s. Use YYYY-MM-DD or DD/MM/YYYY", f.param)
+		}
+		*f.dest = date
+		filter.params = append(filter.params, f.param)
+	}
+
+	if !filter.dateAMMFrom.IsZero() && !filter.dateAMMTo.IsZero() && filter.dateAMMTo.Before(filter.dateAMMFrom) {
+		return filter, errors.New("dateAMMTo must not be before dateAMMFrom")
+	}
+
+	return filter, nil
+}
+
+// active reports whether at least one filter is set
+func (f medicamentFilter) active() bool {
+	return len(f.params) > 0
+}
+
+// matches reports whether the medicament satisfies every filter
+func (f medicamentFilter) matches(med entities.Medicament) bool {
+	if f.mitm != nil && med.Mitm != *f.mitm {
+		return false
+	}
+	if f.surveillanceRenforcee != nil && (normalizeFilterValue(med.SurveillanceRenforcee) == "oui") != *f.surveillanceRenforcee {
+		return false
+	}
+
+	textFilters := []struct {
+		want  string
+		value string
+	}{
+		{f.formePharmaceutique, med.FormePharmaceutique},
+		{f.titulaire, med.Titulaire},
+		{f.etatComercialisation, med.EtatComercialisation},
+		{f.statusAutorisation, med.StatusAutorisation},
+		{f.typeProcedure, med.TypeProcedure},
+	}
+	for _, tf := range textFilters {
+		if tf.want != "" && normalizeFilterValue(tf.value) != tf.want {
+			return false
+		}
+	}
+
+	if f.voieAdministration != "" && !slices.ContainsFunc(med.VoiesAdministration, func(voie string) bool {
+		return normalizeFilterValue(voie) == f.voieAdministration
+	}) {
+		return false
+	}
+
+	if !f.dateAMMFrom.IsZero() || !f.dateAMMTo.IsZero() {
+		dateAMM, ok := entities.ParseDate(med.DateAMM)
+		if !ok || (!f.dateAMMFrom.IsZero() && dateAMM.Before(f.dateAMMFrom)) || (!f.dateAMMTo.IsZero() && dateAMM.After(f.dateAMMTo)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeFilterValue folds accents and case so that "Commercialisée" matches "commercialisee"
+func normalizeFilterValue(value string) string {
+	return strings.TrimSpace(search.Normalize(value))
+}
+
+// filterMedicaments keeps the medicaments matching the filter
+func filterMedicaments(medicaments []entities.Medicament, filter medicamentFilter) []entities.Medicament {
+	filtered := make([]entities.Medicament, 0, len(medicaments))
+	for _, med := range medicaments {
+		if filter.matches(med) {
+			filtered = append(filtered, med)
+		}
+	}
+	return filtered
+}
+
+// isInfoImportanteActive reports whether now falls within the notice validity period.
+// Both bounds are inclusive and an empty or unparsable end date means the notice is open-ended.
+func isInfoImportanteActive(info entities.InfoImportante, now time.Time) bool {
+	if debut, ok := entities.ParseDate(info.DateDebut); ok && now.Before(debut) {
+		return false
+	}
+	if fin, ok := entities.ParseDate(info.DateFin); ok && !now.Before(fin.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
 }
@@ -1,15 +1,20 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -256,6 +261,365 @@ func TestExportMedicaments(t *testing.T) {
 	}
 }
 
+// TestExportMedicaments_NDJSON tests the streamed NDJSON export
+func TestExportMedicaments_NDJSON(t *testing.T) {
+	factory := NewTestDataFactory()
+
+	// More medicaments than a flush chunk, to cover the intermediate flushes
+	medicaments := make([]entities.Medicament, ndjsonFlushEvery*2+5)
+	for i := range medicaments {
+		medicaments[i] = factory.CreateMedicament(i+1, fmt.Sprintf("Test Med %d", i+1))
+	}
+
+	mockStore := &MockDataStore{
+		medicaments: medicaments,
+		dataVersion: interfaces.DataVersion{Version: 7, Hash: "0123456789abcdef"},
+	}
+	handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+	req := httptest.NewRequest("GET", "/v1/medicaments/export?format=ndjson", nil)
+	rr := httptest.NewRecorder()
+	handler.ExportMedicaments(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected Content-Type application/x-ndjson, got %s", ct)
+	}
+	if version := rr.Header().Get("X-Data-Version"); version != "7" {
+		t.Errorf("Expected X-Data-Version 7, got %q", version)
+	}
+
+	etag := rr.Header().Get("ETag")
+	if etag != `W/"ndjson-0123456789abcdef"` {
+		t.Errorf("Expected an ETag derived from the dataset hash, got %s", etag)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+	if len(lines) != len(medicaments) {
+		t.Fatalf("Expected %d lines, got %d", len(medicaments), len(lines))
+	}
+	for i, line := range lines {
+		var med entities.Medicament
+		if err := json.Unmarshal([]byte(line), &med); err != nil {
+			t.Fatalf("Line %d is not a JSON object: %v", i+1, err)
+		}
+		if med.Cis != medicaments[i].Cis {
+			t.Errorf("Line %d: expected CIS %d, got %d", i+1, medicaments[i].Cis, med.Cis)
+		}
+	}
+
+	// The same dataset version is not sent again
+	req = httptest.NewRequest("GET", "/v1/medicaments/export?format=ndjson", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ExportMedicaments(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got %d bytes", rr.Body.Len())
+	}
+}
+
+// TestExportMedicaments_Format tests the format and dataVersion parameters of the export
+func TestExportMedicaments_Format(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().Build()
+	mockStore.dataVersion = interfaces.DataVersion{Version: 3, Hash: "abc"}
+	handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedType string
+	}{
+		{"default format", "", http.StatusOK, "application/json; charset=utf-8"},
+		{"json format", "?format=json", http.StatusOK, "application/json; charset=utf-8"},
+		{"ndjson format", "?format=ndjson", http.StatusOK, "application/x-ndjson"},
+		{"unknown format", "?format=xml", http.StatusBadRequest, ""},
+		{"ndjson with current version", "?format=ndjson&dataVersion=3", http.StatusOK, "application/x-ndjson"},
+		{"ndjson with replaced version", "?format=ndjson&dataVersion=2", http.StatusConflict, ""},
+		{"csv bundle", "?format=csv", http.StatusOK, "application/zip"},
+		{"csv table", "?format=csv&table=presentations", http.StatusOK, "text/csv; charset=utf-8"},
+		{"parquet bundle", "?format=parquet", http.StatusOK, "application/zip"},
+		{"parquet table", "?format=parquet&table=medicaments", http.StatusOK, "application/vnd.apache.parquet"},
+		{"unknown table", "?format=csv&table=unknown", http.StatusBadRequest, ""},
+		{"table without tabular format", "?format=ndjson&table=medicaments", http.StatusBadRequest, ""},
+		{"csv with replaced version", "?format=csv&dataVersion=2", http.StatusConflict, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments/export"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ExportMedicaments(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if tt.expectedType != "" && rr.Header().Get("Content-Type") != tt.expectedType {
+				t.Errorf("Expected Content-Type %s, got %s", tt.expectedType, rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestExportMedicaments_CSV tests the CSV export of a single table
+func TestExportMedicaments_CSV(t *testing.T) {
+	factory := NewTestDataFactory()
+	mockStore := &MockDataStore{
+		medicaments: []entities.Medicament{
+			factory.CreateMedicament(1, "Test Med 1"),
+			factory.CreateMedicament(2, "Test Med 2"),
+		},
+		dataVersion: interfaces.DataVersion{Version: 4, Hash: "feedc0de"},
+	}
+	handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+	req := httptest.NewRequest("GET", "/v1/medicaments/export?format=csv&table=medicaments", nil)
+	rr := httptest.NewRecorder()
+	handler.ExportMedicaments(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != `attachment; filename="medicaments.csv"` {
+		t.Errorf("Unexpected Content-Disposition: %s", cd)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "cis,elementPharmaceutique,") || !strings.HasPrefix(lines[1], "1,Test Med 1,") {
+		t.Errorf("Unexpected CSV: %q", lines[:2])
+	}
+
+	etag := rr.Header().Get("ETag")
+	if etag != `W/"csv-medicaments-feedc0de"` {
+		t.Errorf("Expected an ETag derived from the format, table and dataset hash, got %s", etag)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/medicaments/export?format=csv&table=medicaments", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ExportMedicaments(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rr.Code)
+	}
+}
+
+// TestExportMedicaments_Artifact tests the precompressed export, with encoding negotiation and ranges
+func TestExportMedicaments_Artifact(t *testing.T) {
+	factory := NewTestDataFactory()
+	medicaments := []entities.Medicament{
+		factory.CreateMedicament(1, "Test Med 1"),
+		factory.CreateMedicament(2, "Test Med 2"),
+	}
+	data, _ := json.Marshal(medicaments)
+	modTime := time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC)
+	exportArtifact, err := artifact.New("application/json; charset=utf-8", data, modTime)
+	if err != nil {
+		t.Fatalf("artifact.New failed: %v", err)
+	}
+
+	mockStore := &MockDataStore{
+		medicaments:    medicaments,
+		exportArtifact: exportArtifact,
+		dataVersion:    interfaces.DataVersion{Version: 5, Hash: "abc"},
+	}
+	handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+	export := func(query string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/medicaments/export"+query, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ExportMedicaments(rr, req)
+		return rr
+	}
+
+	t.Run("negotiated encoding", func(t *testing.T) {
+		rr := export("", map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rr.Code)
+		}
+		if rr.Header().Get("Content-Encoding") != "br" {
+			t.Errorf("Expected Content-Encoding br, got %q", rr.Header().Get("Content-Encoding"))
+		}
+		if rr.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Expected Vary Accept-Encoding, got %q", rr.Header().Get("Vary"))
+		}
+		if rr.Header().Get("Content-Length") != strconv.Itoa(exportArtifact.Size(artifact.Brotli)) {
+			t.Errorf("Expected Content-Length %d, got %s", exportArtifact.Size(artifact.Brotli), rr.Header().Get("Content-Length"))
+		}
+		if rr.Header().Get("ETag") != exportArtifact.ETag(artifact.Brotli) {
+			t.Errorf("Expected the ETag of the brotli representation, got %s", rr.Header().Get("ETag"))
+		}
+		if rr.Header().Get("X-Data-Version") != "5" {
+			t.Errorf("Expected X-Data-Version 5, got %q", rr.Header().Get("X-Data-Version"))
+		}
+		if !bytes.Equal(rr.Body.Bytes(), exportArtifact.Body(artifact.Brotli)) {
+			t.Error("Expected the precompressed body")
+		}
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		rr := export("", nil)
+
+		if rr.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected no Content-Encoding, got %q", rr.Header().Get("Content-Encoding"))
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("Expected Content-Type application/json; charset=utf-8, got %s", ct)
+		}
+		if rr.Header().Get("Last-Modified") != "Thu, 15 Jan 2026 06:00:00 GMT" {
+			t.Errorf("Unexpected Last-Modified: %s", rr.Header().Get("Last-Modified"))
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data) {
+			t.Errorf("Expected the JSON export, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		rr := export("", map[string]string{
+			"Range":    "bytes=10-",
+			"If-Range": exportArtifact.ETag(artifact.Identity),
+		})
+
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", rr.Code)
+		}
+		expected := fmt.Sprintf("bytes 10-%d/%d", len(data)-1, len(data))
+		if rr.Header().Get("Content-Range") != expected {
+			t.Errorf("Expected Content-Range %s, got %s", expected, rr.Header().Get("Content-Range"))
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data[10:]) {
+			t.Error("Expected the rest of the export")
+		}
+	})
+
+	t.Run("range after an update", func(t *testing.T) {
+		rr := export("", map[string]string{
+			"Range":    "bytes=10-",
+			"If-Range": `"outdated"`,
+		})
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected the full export with status 200, got %d", rr.Code)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data) {
+			t.Error("Expected the full export")
+		}
+	})
+
+	t.Run("range of an encoded representation", func(t *testing.T) {
+		rr := export("", map[string]string{
+			"Accept-Encoding": "zstd",
+			"Range":           "bytes=0-9",
+		})
+
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("Expected status 206, got %d", rr.Code)
+		}
+		if rr.Header().Get("Content-Length") != "10" {
+			t.Errorf("Expected Content-Length 10, got %q", rr.Header().Get("Content-Length"))
+		}
+		if !bytes.Equal(rr.Body.Bytes(), exportArtifact.Body(artifact.Zstd)[:10]) {
+			t.Error("Expected the first bytes of the zstd representation")
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		rr := export("", map[string]string{
+			"Accept-Encoding": "gzip",
+			"If-None-Match":   exportArtifact.ETag(artifact.Gzip),
+		})
+
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("Expected status 304, got %d", rr.Code)
+		}
+	})
+
+	t.Run("pinned version", func(t *testing.T) {
+		rr := export("?dataVersion=4", nil)
+
+		if rr.Code != http.StatusConflict {
+			t.Errorf("Expected status 409, got %d", rr.Code)
+		}
+	})
+}
+
+// TestExportMedicaments_Fields tests the fields selection of the JSON and NDJSON exports
+func TestExportMedicaments_Fields(t *testing.T) {
+	factory := NewTestDataFactory()
+	medicaments := []entities.Medicament{
+		factory.CreateMedicament(1, "Test Med 1"),
+		factory.CreateMedicament(2, "Test Med 2"),
+	}
+	data, _ := json.Marshal(medicaments)
+	exportArtifact, err := artifact.New("application/json; charset=utf-8", data, time.Now())
+	if err != nil {
+		t.Fatalf("artifact.New failed: %v", err)
+	}
+
+	mockStore := &MockDataStore{
+		medicaments:    medicaments,
+		exportArtifact: exportArtifact,
+		dataVersion:    interfaces.DataVersion{Version: 5, Hash: "abc"},
+	}
+	handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"json", "?fields=cis", http.StatusOK, `[{"cis":1},{"cis":2}]`},
+		{"ndjson", "?format=ndjson&fields=cis,elementPharmaceutique", http.StatusOK, "{\"cis\":1,\"elementPharmaceutique\":\"Test Med 1\"}\n{\"cis\":2,\"elementPharmaceutique\":\"Test Med 2\"}\n"},
+		{"invalid field", "?format=ndjson&fields=nom", http.StatusBadRequest, ""},
+		{"tabular format", "?format=csv&fields=cis", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments/export"+tt.query, nil)
+			req.Header.Set("Accept-Encoding", "br")
+			rr := httptest.NewRecorder()
+			handler.ExportMedicaments(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			// The selection is encoded on demand, not taken from the precompressed artifact
+			if rr.Header().Get("Content-Encoding") != "" {
+				t.Errorf("Expected an unencoded response, got %s", rr.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+
+	// The NDJSON ETag depends on the selection
+	etags := make(map[string]bool)
+	for _, query := range []string{"?format=ndjson", "?format=ndjson&fields=cis", "?format=ndjson&fields=elementPharmaceutique"} {
+		rr := httptest.NewRecorder()
+		handler.ExportMedicaments(rr, httptest.NewRequest("GET", "/v1/medicaments/export"+query, nil))
+		etags[rr.Header().Get("ETag")] = true
+	}
+	if len(etags) != 3 {
+		t.Errorf("Expected one ETag per selection, got %v", etags)
+	}
+}
+
 // TestServePagedMedicaments tests pagination
 func TestServePagedMedicaments(t *testing.T) {
 	factory := NewTestDataFactory()
@@ -364,6 +728,89 @@ func TestServePagedMedicaments(t *testing.T) {
 	}
 }
 
+// TestServePagedMedicaments_Sort tests the sort parameter of the legacy pagination
+func TestServePagedMedicaments_Sort(t *testing.T) {
+	factory := NewTestDataFactory()
+	medicaments := []entities.Medicament{
+		factory.CreateMedicament(3, "Beta"),
+		factory.CreateMedicament(1, "Gamma"),
+		factory.CreateMedicament(2, "Alpha"),
+	}
+	for i := range medicaments {
+		medicaments[i].DenominationNormalized = strings.ToLower(medicaments[i].Denomination)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedCIS  []int
+	}{
+		{"no sort keeps load order", "", http.StatusOK, []int{3, 1, 2}},
+		{"sort by cis", "?sort=cis", http.StatusOK, []int{1, 2, 3}},
+		{"sort by denomination desc", "?sort=-denomination", http.StatusOK, []int{1, 3, 2}},
+		{"invalid sort", "?sort=prix", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := &MockDataStore{medicaments: medicaments}
+			handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+			router := chi.NewRouter()
+			router.Get("/database/{pageNumber}", handler.ServePagedMedicaments)
+
+			req := httptest.NewRequest("GET", "/database/1"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if tt.expectedCIS == nil {
+				return
+			}
+
+			var response struct {
+				Data []entities.Medicament `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			if len(response.Data) != len(tt.expectedCIS) {
+				t.Fatalf("Expected %d medicaments, got %d", len(tt.expectedCIS), len(response.Data))
+			}
+			for i, med := range response.Data {
+				if med.Cis != tt.expectedCIS[i] {
+					t.Errorf("Expected CIS %d at position %d, got %d", tt.expectedCIS[i], i, med.Cis)
+				}
+			}
+		})
+	}
+
+	// An update between reading the orders and the medicaments must not page past the order
+	t.Run("order from a previous dataset", func(t *testing.T) {
+		updated := make([]entities.Medicament, 0, 12)
+		for i := range 12 {
+			updated = append(updated, factory.CreateMedicament(i+1, "Medicament"))
+		}
+		mockStore := &MockDataStore{medicaments: updated, medicamentOrders: search.NewOrders(medicaments)}
+		handler := NewHTTPHandler(mockStore, &MockDataValidator{}, NewMockHealthCheckerBuilder().Build())
+
+		router := chi.NewRouter()
+		router.Get("/database/{pageNumber}", handler.ServePagedMedicaments)
+
+		req := httptest.NewRequest("GET", "/database/2?sort=cis", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
 // TestFindMedicament tests medicament search
 func TestFindMedicament(t *testing.T) {
 	factory := NewTestDataFactory()
@@ -384,6 +831,15 @@ func TestFindMedicament(t *testing.T) {
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:    "substring inside a word",
+			element: "lipran",
+			medicaments: []entities.Medicament{
+				factory.CreateMedicament(1, "Doliprane"),
+				factory.CreateMedicament(2, "Ibuprofène"),
+			},
+			expectedCode: http.StatusOK,
+		},
 		{
 			name:         "no results",
 			element:      "NonExistent",
@@ -395,7 +851,7 @@ func TestFindMedicament(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockStore := &MockDataStore{medicaments: tt.medicaments}
+			mockStore := NewMockDataStoreBuilder().WithMedicaments(tt.medicaments).Build()
 			mockValidator := &MockDataValidator{}
 			handler := NewHTTPHandler(mockStore, mockValidator, NewMockHealthCheckerBuilder().Build())
 
@@ -518,6 +974,44 @@ func TestFindMedicamentByCIS(t *testing.T) {
 	}
 }
 
+// TestFindMedicamentByCIS_DataVersion tests that the lookup reports and enforces the data version
+func TestFindMedicamentByCIS_DataVersion(t *testing.T) {
+	factory := NewTestDataFactory()
+	mockStore := NewMockDataStoreBuilder().Build()
+	mockStore.medicamentsMap = map[int]entities.Medicament{1: factory.CreateMedicament(1, "Doliprane")}
+	mockStore.dataVersion = interfaces.DataVersion{Version: 3, Hash: "abc"}
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/v1/medicaments/{cis}", handler.FindMedicamentByCIS)
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+	}{
+		{"no pin", "/v1/medicaments/00000001", http.StatusOK},
+		{"current version", "/v1/medicaments/00000001?dataVersion=3", http.StatusOK},
+		{"replaced version", "/v1/medicaments/00000001?dataVersion=2", http.StatusConflict},
+		{"not found", "/v1/medicaments/99999999", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if version := rr.Header().Get("X-Data-Version"); version != "3" {
+				t.Errorf("Expected X-Data-Version 3, got %q", version)
+			}
+		})
+	}
+}
+
 // TestFindGeneriques tests generique search
 func TestFindGeneriques(t *testing.T) {
 	factory := NewTestDataFactory()
@@ -945,6 +1439,125 @@ func TestHealthCheck(t *testing.T) {
 	}
 }
 
+// TestCheckVersion tests the X-Data-Version header and dataVersion pinning
+func TestCheckVersion(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().Build()
+	handler := &Handler{dataStore: mockStore}
+	loadedAt := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	version := interfaces.DataVersion{Version: 3, Hash: "abc", LoadedAt: loadedAt}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectError  string
+	}{
+		{"no pin", "", http.StatusOK, ""},
+		{"current version", "?dataVersion=3", http.StatusOK, ""},
+		{"replaced version", "?dataVersion=2", http.StatusConflict, "Data changed: dataVersion is now 3"},
+		{"invalid version", "?dataVersion=abc", http.StatusBadRequest, "Invalid dataVersion"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments/1"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			if handler.checkVersion(rr, req, version) {
+				handler.RespondWithJSONAndETag(rr, req, http.StatusOK, map[string]int{"cis": 1})
+			}
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+			if version := rr.Header().Get("X-Data-Version"); version != "3" {
+				t.Errorf("Expected X-Data-Version 3, got %q", version)
+			}
+			if lastModified := rr.Header().Get("Last-Modified"); lastModified != loadedAt.Format(http.TimeFormat) {
+				t.Errorf("Expected Last-Modified of the checked version, got %q", lastModified)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error %q, got %v", tt.expectError, response["message"])
+				}
+			}
+		})
+	}
+}
+
+// TestDataVersionPin tests that every data route checks the pin before looking anything up,
+// so that a replaced dataset answers 409 rather than the 404 of the new one
+func TestDataVersionPin(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().Build()
+	mockStore.dataVersion = interfaces.DataVersion{Version: 3, Hash: "abc"}
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/medicament/cip/{cip}", handler.FindMedicamentByCIP)
+	router.Get("/medicament/{element}", handler.FindMedicament)
+	router.Get("/generiques/{libelle}", handler.FindGeneriques)
+	router.Get("/database/{pageNumber}", handler.ServePagedMedicaments)
+	router.Get("/v1/medicaments", handler.ServeMedicamentsV1)
+	router.Get("/v1/medicaments/export", handler.ExportMedicaments)
+	router.Get("/v1/medicaments/{cis}", handler.FindMedicamentByCIS)
+	router.Get("/v1/medicaments/{cis}/alerts", handler.ServeMedicamentAlertsV1)
+	router.Get("/v1/presentations/{cip}", handler.ServePresentationsV1)
+	router.Get("/v1/generiques", handler.ServeGeneriquesV1)
+	router.Get("/v1/generiques/{groupID}", handler.FindGeneriquesByGroupID)
+	router.Get("/v1/disponibilites", handler.ServeDisponibilitesV1)
+	router.Get("/v1/suggest", handler.ServeSuggestV1)
+	router.Get("/v1/substances", handler.ServeSubstancesV1)
+	router.Get("/v1/substances/{code}/medicaments", handler.ServeSubstanceMedicamentsV1)
+	router.Get("/v1/changes", handler.ServeChangesV1)
+
+	// None of these exist in the empty dataset
+	paths := []string{
+		"/medicament/cip/3400930000099",
+		"/medicament/unknown",
+		"/generiques/unknown",
+		"/database/1",
+		"/v1/medicaments?cip=3400930000099",
+		"/v1/medicaments?search=unknown",
+		"/v1/medicaments?page=1",
+		"/v1/medicaments?cursor=",
+		"/v1/medicaments/export",
+		"/v1/medicaments/export?format=csv",
+		"/v1/medicaments/99999999",
+		"/v1/medicaments/99999999/alerts",
+		"/v1/presentations/3400930000099",
+		"/v1/generiques?libelle=unknown",
+		"/v1/generiques/99",
+		"/v1/disponibilites",
+		"/v1/suggest?q=unknown",
+		"/v1/substances",
+		"/v1/substances/99/medicaments",
+		"/v1/changes",
+	}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			req := httptest.NewRequest("GET", path+separator+"dataVersion=2", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusConflict {
+				t.Errorf("Expected status 409, got %d", rr.Code)
+			}
+			if version := rr.Header().Get("X-Data-Version"); version != "3" {
+				t.Errorf("Expected X-Data-Version 3, got %q", version)
+			}
+		})
+	}
+}
+
 // ============================================================================
 // ETag UTILITY FUNCTION TESTS
 // ============================================================================
@@ -12,9 +12,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
 	"github.com/giygas/medicaments-api/data"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -137,10 +141,23 @@ func (f *TestDataFactory) CreateDataContainer(medicamentsCount int, generiquesCo
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 	return dataContainer
 }
 
+// decodeMedicamentsPage extracts the medicaments of a paginated response
+func decodeMedicamentsPage(t *testing.T, body []byte) []entities.Medicament {
+	t.Helper()
+
+	var response struct {
+		Data []entities.Medicament `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to unmarshal paginated JSON: %v", err)
+	}
+	return response.Data
+}
+
 // ============================================================================
 // MOCK BUILDERS
 // ============================================================================
@@ -182,6 +199,9 @@ func NewMockDataStoreBuilder() *MockDataStoreBuilder {
 
 func (b *MockDataStoreBuilder) WithMedicaments(medicaments []entities.Medicament) *MockDataStoreBuilder {
 	b.mock.medicaments = medicaments
+	b.mock.searchIndex = search.NewIndex(medicaments)
+	b.mock.substanceIndex = search.NewSubstanceIndex(medicaments)
+	b.mock.medicamentOrders = search.NewOrders(medicaments)
 	b.mock.medicamentsMap = make(map[int]entities.Medicament)
 	for _, med := range medicaments {
 		b.mock.medicamentsMap[med.Cis] = med
@@ -214,6 +234,11 @@ func (b *MockDataStoreBuilder) WithPresentationsCIP13Map(presentationsCIP13Map m
 	return b
 }
 
+func (b *MockDataStoreBuilder) WithDisponibilites(disponibilites []entities.Disponibilite) *MockDataStoreBuilder {
+	b.mock.disponibilites = disponibilites
+	return b
+}
+
 func (b *MockDataStoreBuilder) WithGeneriquesMap(generiquesMap map[int]entities.GeneriqueList) *MockDataStoreBuilder {
 	b.mock.generiquesMap = generiquesMap
 	return b
@@ -375,7 +400,15 @@ type MockDataStore struct {
 	generiquesMap         map[int]entities.GeneriqueList
 	presentationsCIP7Map  map[int]entities.Presentation
 	presentationsCIP13Map map[int]entities.Presentation
+	disponibilites        []entities.Disponibilite
+	searchIndex           *search.Index
+	substanceIndex        *search.SubstanceIndex
+	medicamentOrders      search.Orders
 	lastUpdated           time.Time
+	lastChecked           time.Time
+	dataVersion           interfaces.DataVersion
+	changeSets            []changes.ChangeSet
+	exportArtifact        *artifact.Artifact
 	updating              bool
 	serverStartTime       time.Time
 	dataQualityReport     *interfaces.DataQualityReport
@@ -418,10 +451,85 @@ func (m *MockDataStore) GetPresentationsCIP13Map() map[int]entities.Presentation
 	return m.presentationsCIP13Map
 }
 
+func (m *MockDataStore) GetDisponibilites() []entities.Disponibilite {
+	return m.disponibilites
+}
+
+// GetSearchIndex builds the index on the fly for stores created without the builder
+func (m *MockDataStore) GetSearchIndex() *search.Index {
+	if m.searchIndex == nil {
+		return search.NewIndex(m.medicaments)
+	}
+	return m.searchIndex
+}
+
+// GetSubstanceIndex builds the index on the fly for stores created without the builder
+func (m *MockDataStore) GetSubstanceIndex() *search.SubstanceIndex {
+	if m.substanceIndex == nil {
+		return search.NewSubstanceIndex(m.medicaments)
+	}
+	return m.substanceIndex
+}
+
+// GetMedicamentOrders sorts on the fly for stores created without the builder
+func (m *MockDataStore) GetMedicamentOrders() search.Orders {
+	if m.medicamentOrders == nil {
+		return search.NewOrders(m.medicaments)
+	}
+	return m.medicamentOrders
+}
+
 func (m *MockDataStore) GetLastUpdated() time.Time {
 	return m.lastUpdated
 }
 
+func (m *MockDataStore) GetLastChecked() time.Time {
+	return m.lastChecked
+}
+
+func (m *MockDataStore) MarkChecked() {
+	m.lastChecked = time.Now()
+}
+
+// GetDataset gathers the fields through the getters, which build the indexes on the fly
+func (m *MockDataStore) GetDataset() *interfaces.Dataset {
+	return &interfaces.Dataset{
+		Version:               m.GetDataVersion(),
+		Medicaments:           m.GetMedicaments(),
+		Generiques:            m.GetGeneriques(),
+		MedicamentsMap:        m.GetMedicamentsMap(),
+		GeneriquesMap:         m.GetGeneriquesMap(),
+		PresentationsCIP7Map:  m.GetPresentationsCIP7Map(),
+		PresentationsCIP13Map: m.GetPresentationsCIP13Map(),
+		Disponibilites:        m.GetDisponibilites(),
+		SearchIndex:           m.GetSearchIndex(),
+		SubstanceIndex:        m.GetSubstanceIndex(),
+		Orders:                m.GetMedicamentOrders(),
+	}
+}
+
+// GetTabularExports builds the exports on each call, the handler tests change the medicaments freely
+func (m *MockDataStore) GetTabularExports() (*export.Exports, interfaces.DataVersion) {
+	exports, _ := export.Build(m.medicaments)
+	return exports, m.dataVersion
+}
+
+func (m *MockDataStore) GetDataVersion() interfaces.DataVersion {
+	return m.dataVersion
+}
+
+func (m *MockDataStore) GetChanges() []changes.ChangeSet {
+	return m.changeSets
+}
+
+func (m *MockDataStore) GetExportArtifact() (*artifact.Artifact, interfaces.DataVersion) {
+	return m.exportArtifact, m.GetDataVersion()
+}
+
+func (m *MockDataStore) RecordChanges(changeSet changes.ChangeSet) {
+	m.changeSets = append(m.changeSets, changeSet)
+}
+
 func (m *MockDataStore) IsUpdating() bool {
 	return m.updating
 }
@@ -429,15 +537,19 @@ func (m *MockDataStore) IsUpdating() bool {
 func (m *MockDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList,
 	medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList,
 	presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation,
-	report *interfaces.DataQualityReport) {
+	report *interfaces.DataQualityReport, version interfaces.DataVersion) {
 	m.updateDataCalled = true
 	m.medicaments = medicaments
+	m.searchIndex = search.NewIndex(medicaments)
+	m.substanceIndex = search.NewSubstanceIndex(medicaments)
+	m.medicamentOrders = search.NewOrders(medicaments)
 	m.generiques = generiques
 	m.medicamentsMap = medicamentsMap
 	m.generiquesMap = generiquesMap
 	m.presentationsCIP7Map = presentationsCIP7Map
 	m.presentationsCIP13Map = presentationsCIP13Map
-	m.lastUpdated = time.Now()
+	m.lastUpdated = version.LoadedAt
+	m.dataVersion = version
 }
 
 func (m *MockDataStore) BeginUpdate() bool {
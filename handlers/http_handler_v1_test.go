@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/changes"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
 	"github.com/giygas/medicaments-api/validation"
@@ -621,11 +623,7 @@ func TestServeMedicamentsV1_Success(t *testing.T) {
 				}
 
 			case "search":
-				var response []entities.Medicament
-				err := json.Unmarshal(rr.Body.Bytes(), &response)
-				if err != nil {
-					t.Fatalf("Failed to unmarshal JSON: %v", err)
-				}
+				response := decodeMedicamentsPage(t, rr.Body.Bytes())
 
 				// Search with results
 				if len(response) == 0 {
@@ -1230,10 +1228,7 @@ func TestServeMedicamentsV1_MultiWordSearch(t *testing.T) {
 					t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 				}
 
-				var response []entities.Medicament
-				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-					t.Fatalf("Failed to parse response: %v", err)
-				}
+				response := decodeMedicamentsPage(t, w.Body.Bytes())
 
 				if len(response) != tt.expectedCount {
 					t.Errorf("Expected %d results, got %d", tt.expectedCount, len(response))
@@ -1566,7 +1561,7 @@ func TestServeDiagnosticsV1_ResponseStructure(t *testing.T) {
 	// Check required top-level fields
 	requiredFields := []string{
 		"timestamp", "uptime_seconds", "next_update",
-		"data_age_hours", "system", "data_integrity",
+		"data_age_hours", "data_version", "data_hash", "system", "data_integrity",
 	}
 	for _, field := range requiredFields {
 		if _, ok := response[field]; !ok {
@@ -1737,6 +1732,8 @@ func TestServeDiagnosticsV1_DataIntegrityValues(t *testing.T) {
 		MedicamentsWithoutCompositionsCIS:   []int{19, 20},
 		GeneriqueOnlyCISList:                []int{21, 22, 23, 24},
 		PresentationsWithOrphanedCISCIPList: []int{100, 200, 300},
+		StaleSourceFiles:                    []string{"CIS_CIP_Dispo_Spec.txt"},
+		SkippedSourceFiles:                  []string{"CIS_MITM.txt"},
 	}
 
 	handler := NewHTTPHandler(
@@ -1809,6 +1806,20 @@ func TestServeDiagnosticsV1_DataIntegrityValues(t *testing.T) {
 	} else if len(sampleCIP) != 3 {
 		t.Errorf("presentations_with_orphaned_cis: expected 3 sample CIP, got %d", len(sampleCIP))
 	}
+
+	// Verify the source files served from their last good copy
+	staleCat := dataIntegrity["stale_source_files"].(map[string]any)
+	staleFiles, ok := staleCat["files"].([]any)
+	if !ok || staleCat["count"] != float64(1) || len(staleFiles) != 1 || staleFiles[0] != "CIS_CIP_Dispo_Spec.txt" {
+		t.Errorf("stale_source_files: expected CIS_CIP_Dispo_Spec.txt, got %v", staleCat)
+	}
+
+	// Verify the supplementary source files left out of the data
+	skippedCat := dataIntegrity["skipped_source_files"].(map[string]any)
+	skippedFiles, ok := skippedCat["files"].([]any)
+	if !ok || skippedCat["count"] != float64(1) || len(skippedFiles) != 1 || skippedFiles[0] != "CIS_MITM.txt" {
+		t.Errorf("skipped_source_files: expected CIS_MITM.txt, got %v", skippedCat)
+	}
 }
 
 // TestServeDiagnosticsV1_EdgeCases tests edge cases and boundary conditions
@@ -2092,7 +2103,8 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
-func TestServeMedicamentsV1_SearchLimitExceeded(t *testing.T) {
+// TestServeMedicamentsV1_BroadSearchPaginated tests that broad searches return ranked pages instead of an error
+func TestServeMedicamentsV1_BroadSearchPaginated(t *testing.T) {
 	medicaments := make([]entities.Medicament, 300)
 	for i := 0; i < 300; i++ {
 		medicaments[i] = entities.Medicament{
@@ -2113,21 +2125,255 @@ func TestServeMedicamentsV1_SearchLimitExceeded(t *testing.T) {
 	mockValidator := NewMockDataValidatorBuilder().Build()
 	handler := NewHTTPHandler(mockStore, mockValidator, NewMockHealthCheckerBuilder().Build())
 
-	req := httptest.NewRequest("GET", "/v1/medicaments?search=a", nil)
+	tests := []struct {
+		name             string
+		query            string
+		expectedCode     int
+		expectedPageSize int
+		expectedItems    int
+	}{
+		{"default page size", "?search=a", http.StatusOK, 20, 20},
+		{"custom page size", "?search=a&pageSize=50", http.StatusOK, 50, 50},
+		{"last page", "?search=a&page=15", http.StatusOK, 20, 20},
+		{"page out of range", "?search=a&page=16", http.StatusNotFound, 0, 0},
+		{"invalid page size", "?search=a&pageSize=500", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			if int(response["totalItems"].(float64)) != 300 {
+				t.Errorf("Expected 300 total items, got %v", response["totalItems"])
+			}
+			if int(response["pageSize"].(float64)) != tt.expectedPageSize {
+				t.Errorf("Expected page size %d, got %v", tt.expectedPageSize, response["pageSize"])
+			}
+			if data := response["data"].([]any); len(data) != tt.expectedItems {
+				t.Errorf("Expected %d items, got %d", tt.expectedItems, len(data))
+			}
+		})
+	}
+}
+
+// TestServeMedicamentsV1_SearchRanking tests that results are ordered by relevance
+func TestServeMedicamentsV1_SearchRanking(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{
+			Cis:                    60000001,
+			Denomination:           "DOLIPRANE 1000 mg, comprimé",
+			DenominationNormalized: "doliprane 1000 mg, comprimé",
+			Composition:            []entities.Composition{{DenominationSubstance: "PARACÉTAMOL"}},
+			Titulaire:              "OPELLA HEALTHCARE FRANCE",
+		},
+		{
+			Cis:                    60000002,
+			Denomination:           "PARACETAMOL BIOGARAN 1000 mg, comprimé",
+			DenominationNormalized: "paracetamol biogaran 1000 mg, comprimé",
+			Composition:            []entities.Composition{{DenominationSubstance: "PARACETAMOL"}},
+			Titulaire:              "BIOGARAN",
+		},
+		{
+			Cis:                    60000003,
+			Denomination:           "PARACETAMOL CODEINE ARROW 500 mg/30 mg, comprimé",
+			DenominationNormalized: "paracetamol codeine arrow 500 mg/30 mg, comprimé",
+			Composition: []entities.Composition{
+				{DenominationSubstance: "PARACETAMOL"},
+				{DenominationSubstance: "CODEINE"},
+			},
+			Titulaire: "ARROW GENERIQUES",
+		},
+		{
+			Cis:                    60000004,
+			Denomination:           "IBUPROFENE BIOGARAN 400 mg, comprimé",
+			DenominationNormalized: "ibuprofene biogaran 400 mg, comprimé",
+			Composition:            []entities.Composition{{DenominationSubstance: "IBUPROFENE"}},
+			Titulaire:              "BIOGARAN",
+		},
+	}
+
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	req := httptest.NewRequest("GET", "/v1/medicaments?search=paracetamol", nil)
 	w := httptest.NewRecorder()
 	handler.ServeMedicamentsV1(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var errorResponse map[string]any
-	if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
-		t.Fatalf("Failed to parse error response: %v", err)
+	// DOLIPRANE matches through its accented substance
+	results := decodeMedicamentsPage(t, w.Body.Bytes())
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	// The shorter denomination is the better match
+	if results[0].Cis != 60000002 {
+		t.Errorf("Expected CIS 60000002 first, got %d", results[0].Cis)
+	}
+
+	// Titulaire is indexed as well
+	req = httptest.NewRequest("GET", "/v1/medicaments?search=biogaran", nil)
+	w = httptest.NewRecorder()
+	handler.ServeMedicamentsV1(w, req)
+
+	if results := decodeMedicamentsPage(t, w.Body.Bytes()); len(results) != 2 {
+		t.Errorf("Expected 2 results for titulaire search, got %d", len(results))
+	}
+}
+
+// TestServeMedicamentsV1_FuzzySearch tests typo-tolerant search and the similarity score
+func TestServeMedicamentsV1_FuzzySearch(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 60000001, Denomination: "DOLIPRANE 1000 mg, comprimé", DenominationNormalized: "doliprane 1000 mg, comprimé"},
+		{Cis: 60000002, Denomination: "IBUPROFENE BIOGARAN 400 mg, comprimé", DenominationNormalized: "ibuprofene biogaran 400 mg, comprimé"},
+	}
+
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedCIS  int
+		expectError  string
+	}{
+		{"typo", "?search=dolipane&fuzzy=true", http.StatusOK, 60000001, ""},
+		{"glued dosage", "?search=doliprane+1000mg&fuzzy=true", http.StatusOK, 60000001, ""},
+		{"missing letter", "?search=ibuprofne&fuzzy=true", http.StatusOK, 60000002, ""},
+		{"typo without fuzzy", "?search=dolipane", http.StatusNotFound, 0, "No medicaments found"},
+		{"invalid fuzzy value", "?search=dolipane&fuzzy=yes", http.StatusBadRequest, 0, "Invalid fuzzy value. Must be true or false"},
+		{"fuzzy without search", "?page=1&fuzzy=true", http.StatusBadRequest, 0, "fuzzy can only be used with search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var response struct {
+				Data []struct {
+					Cis        int      `json:"cis"`
+					Similarity *float64 `json:"similarity"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			if len(response.Data) != 1 || response.Data[0].Cis != tt.expectedCIS {
+				t.Fatalf("Expected CIS %d, got %+v", tt.expectedCIS, response.Data)
+			}
+			if sim := response.Data[0].Similarity; sim == nil || *sim <= 0 || *sim > 1 {
+				t.Errorf("Expected similarity between 0 and 1, got %v", sim)
+			}
+		})
+	}
+}
+
+// TestServeMedicamentsV1_AccentInsensitiveSearch tests that accented and unaccented queries match identically
+func TestServeMedicamentsV1_AccentInsensitiveSearch(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 60000001, Denomination: "IBUPROFENE BIOGARAN 400 mg, comprimé", DenominationNormalized: "ibuprofene biogaran 400 mg, comprime"},
+		{Cis: 60000002, Denomination: "CAFÉINE COOPER 250 mg, gélule", DenominationNormalized: "cafeine cooper 250 mg, gelule"},
+	}
+
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name        string
+		query       string
+		expectedCIS int
+	}{
+		{"accented query, unaccented data", "ibuprofène", 60000001},
+		{"unaccented query, accented data", "cafeine", 60000002},
+		{"accented query, accented data", "CAFÉINE gélule", 60000002},
+		{"unaccented query, unaccented data", "ibuprofene", 60000001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/medicaments?search="+url.QueryEscape(tt.query), nil)
+			w := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response struct {
+				Data []entities.Medicament `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			if len(response.Data) != 1 || response.Data[0].Cis != tt.expectedCIS {
+				t.Errorf("Expected CIS %d, got %+v", tt.expectedCIS, response.Data)
+			}
+		})
+	}
+}
+
+// TestServeGeneriquesV1_AccentInsensitiveSearch tests that accented libelle queries match the normalized libelle
+func TestServeGeneriquesV1_AccentInsensitiveSearch(t *testing.T) {
+	generiques := []entities.GeneriqueList{
+		{GroupID: 1, Libelle: "IBUPROFENE 400 mg", LibelleNormalized: "ibuprofene 400 mg"},
+		{GroupID: 2, Libelle: "PARACETAMOL 500 mg", LibelleNormalized: "paracetamol 500 mg"},
 	}
 
-	if message, ok := errorResponse["message"].(string); !ok || !strings.Contains(message, "Maximum 250 results") {
-		t.Errorf("Expected error message about 250 limit, got: %s", message)
+	mockStore := NewMockDataStoreBuilder().WithGeneriques(generiques).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	req := httptest.NewRequest("GET", "/v1/generiques?libelle="+url.QueryEscape("Ibuprofène 400"), nil)
+	w := httptest.NewRecorder()
+	handler.ServeGeneriquesV1(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response []entities.GeneriqueList
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+
+	if len(response) != 1 || response[0].GroupID != 1 {
+		t.Errorf("Expected group 1, got %+v", response)
 	}
 }
 
@@ -2164,3 +2410,1118 @@ func TestServeGeneriquesV1_SearchLimitExceeded(t *testing.T) {
 		t.Errorf("Expected error message about 100 limit, got: %s", message)
 	}
 }
+
+// ============================================================================
+// SUGGEST V1 TESTS
+// ============================================================================
+
+func TestServeSuggestV1(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 60000001, Denomination: "DOLIPRANE 1000 mg, comprimé", DenominationNormalized: "doliprane 1000 mg, comprime"},
+		{Cis: 60000002, Denomination: "DOLIPRANE 500 mg, gélule", DenominationNormalized: "doliprane 500 mg, gelule"},
+		{Cis: 60000003, Denomination: "DOLIPRANE VITAMINE C, comprimé effervescent", DenominationNormalized: "doliprane vitamine c, comprime effervescent"},
+		{Cis: 60000004, Denomination: "IBUPROFENE BIOGARAN 400 mg, comprimé", DenominationNormalized: "ibuprofene biogaran 400 mg, comprime"},
+	}
+
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedCIS  []int
+		expectError  string
+	}{
+		{"prefix in alphabetical order", "?q=dolip", http.StatusOK, []int{60000001, 60000002, 60000003}, ""},
+		{"limit", "?q=dolip&limit=2", http.StatusOK, []int{60000001, 60000002}, ""},
+		{"accented prefix", "?q=doliprane+500+mg+g%C3%A9l", http.StatusOK, []int{60000002}, ""},
+		{"no match is an empty list", "?q=xylocaine", http.StatusOK, []int{}, ""},
+		{"missing q", "", http.StatusBadRequest, nil, "Needs q param"},
+		{"invalid limit", "?q=dolip&limit=21", http.StatusBadRequest, nil, "Invalid limit. Must be between 1 and 20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/suggest"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.ServeSuggestV1(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var response []struct {
+				Cis          int    `json:"cis"`
+				Denomination string `json:"elementPharmaceutique"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			cis := make([]int, len(response))
+			for i, suggestion := range response {
+				cis[i] = suggestion.Cis
+				if suggestion.Denomination == "" {
+					t.Errorf("Expected a denomination for CIS %d", suggestion.Cis)
+				}
+			}
+			if fmt.Sprint(cis) != fmt.Sprint(tt.expectedCIS) {
+				t.Errorf("Expected CIS %v, got %v", tt.expectedCIS, cis)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// SUBSTANCES V1 TESTS
+// ============================================================================
+
+func substancesTestMedicaments() []entities.Medicament {
+	return []entities.Medicament{
+		{Cis: 60000001, Denomination: "AMOXICILLINE BIOGARAN 500 mg, gélule", Composition: []entities.Composition{
+			{CodeSubstance: 1234, DenominationSubstance: "AMOXICILLINE TRIHYDRATÉE"},
+		}},
+		{Cis: 60000002, Denomination: "AUGMENTIN 1 g/125 mg, poudre", Composition: []entities.Composition{
+			{CodeSubstance: 1234, DenominationSubstance: "AMOXICILLINE TRIHYDRATÉE"},
+			{CodeSubstance: 5678, DenominationSubstance: "CLAVULANATE DE POTASSIUM"},
+		}},
+		{Cis: 60000003, Denomination: "DOLIPRANE 1000 mg, comprimé", Composition: []entities.Composition{
+			{CodeSubstance: 2202, DenominationSubstance: "PARACÉTAMOL"},
+		}},
+	}
+}
+
+func TestServeSubstancesV1(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(substancesTestMedicaments()).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCodes []int
+	}{
+		{"list all", "", http.StatusOK, []int{1234, 5678, 2202}},
+		{"autocomplete", "?search=amox", http.StatusOK, []int{1234}},
+		{"autocomplete on word", "?search=potassium", http.StatusOK, []int{5678}},
+		{"autocomplete with accent", "?search=parac%C3%A9t", http.StatusOK, []int{2202}},
+		{"no match", "?search=xyz", http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/substances"+tt.query, nil)
+			w := httptest.NewRecorder()
+			handler.ServeSubstancesV1(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var response []entities.Substance
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			codes := make([]int, len(response))
+			for i, substance := range response {
+				codes[i] = substance.Code
+			}
+			if fmt.Sprint(codes) != fmt.Sprint(tt.expectedCodes) {
+				t.Errorf("Expected substances %v, got %v", tt.expectedCodes, codes)
+			}
+		})
+	}
+}
+
+func TestServeSubstancesV1_InvalidSearch(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(substancesTestMedicaments()).Build()
+	mockValidator := NewMockDataValidatorBuilder().WithInputError(fmt.Errorf("input too short: minimum 3 characters")).Build()
+	handler := NewHTTPHandler(mockStore, mockValidator, NewMockHealthCheckerBuilder().Build())
+
+	req := httptest.NewRequest("GET", "/v1/substances?search=am", nil)
+	w := httptest.NewRecorder()
+	handler.ServeSubstancesV1(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestServeSubstanceMedicamentsV1(t *testing.T) {
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(substancesTestMedicaments()).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	tests := []struct {
+		name         string
+		code         string
+		query        string
+		expectedCode int
+		expectedCIS  []int
+		expectError  string
+	}{
+		{"substance in two medicaments", "1234", "", http.StatusOK, []int{60000001, 60000002}, ""},
+		{"paginated", "1234", "?page=2&pageSize=1", http.StatusOK, []int{60000002}, ""},
+		{"unknown substance", "9999", "", http.StatusNotFound, nil, "Substance not found"},
+		{"invalid code", "abc", "", http.StatusBadRequest, nil, "Invalid substance code"},
+		{"invalid page", "1234", "?page=0", http.StatusBadRequest, nil, "Invalid page number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/substances/"+tt.code+"/medicaments"+tt.query, nil)
+			req.SetPathValue("code", tt.code)
+			w := httptest.NewRecorder()
+			handler.ServeSubstanceMedicamentsV1(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			results := decodeMedicamentsPage(t, w.Body.Bytes())
+			cis := make([]int, len(results))
+			for i, med := range results {
+				cis[i] = med.Cis
+			}
+			if fmt.Sprint(cis) != fmt.Sprint(tt.expectedCIS) {
+				t.Errorf("Expected CIS %v, got %v", tt.expectedCIS, cis)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// DISPONIBILITES V1 TESTS
+// ============================================================================
+
+func TestServeDisponibilitesV1(t *testing.T) {
+	disponibilites := []entities.Disponibilite{
+		{Cis: 60002283, Cip13: 3400949497294, CodeStatut: 1, Statut: "Rupture de stock"},
+		{Cis: 60002283, Cip13: 3400949497300, CodeStatut: 2, Statut: "Tension d'approvisionnement"},
+		{Cis: 61266250, CodeStatut: 2, Statut: "Tension d'approvisionnement"},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCount int
+		expectError   string
+	}{
+		{"no filter", "", http.StatusOK, 3, ""},
+		{"filter by status", "?status=2", http.StatusOK, 2, ""},
+		{"filter by cis", "?cis=60002283", http.StatusOK, 2, ""},
+		{"filter by status and cis", "?status=2&cis=61266250", http.StatusOK, 1, ""},
+		{"no match", "?status=4", http.StatusNotFound, 0, "No disponibilites found"},
+		{"invalid status", "?status=5", http.StatusBadRequest, 0, "Invalid status. Must be between 1 and 4"},
+		{"non-numeric status", "?status=abc", http.StatusBadRequest, 0, "Invalid status. Must be between 1 and 4"},
+		{"invalid cis", "?cis=123", http.StatusBadRequest, 0, "CIS should have 8 digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().WithDisponibilites(disponibilites).Build()
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			req := httptest.NewRequest("GET", "/v1/disponibilites"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeDisponibilitesV1(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var results []entities.Disponibilite
+			if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+			if len(results) != tt.expectedCount {
+				t.Errorf("Expected %d disponibilites, got %d", tt.expectedCount, len(results))
+			}
+			if rr.Header().Get("ETag") == "" {
+				t.Error("Expected ETag header to be set")
+			}
+		})
+	}
+}
+
+func TestServePresentationsV1_Disponibilite(t *testing.T) {
+	presentation := entities.Presentation{
+		Cis:   60002283,
+		Cip7:  4949729,
+		Cip13: 3400949497294,
+		Disponibilite: &entities.Disponibilite{
+			Cis:        60002283,
+			Cip13:      3400949497294,
+			CodeStatut: 1,
+			Statut:     "Rupture de stock",
+			DateDebut:  "02/01/2026",
+		},
+	}
+
+	mockStore := NewMockDataStoreBuilder().
+		WithPresentationsCIP7Map(map[int]entities.Presentation{4949729: presentation}).
+		WithPresentationsCIP13Map(map[int]entities.Presentation{3400949497294: presentation}).
+		Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/v1/presentations/{cip}", handler.ServePresentationsV1)
+
+	req := httptest.NewRequest("GET", "/v1/presentations/4949729", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var result entities.Presentation
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+	if result.Disponibilite == nil || result.Disponibilite.CodeStatut != 1 {
+		t.Errorf("Expected disponibilite with status 1, got %+v", result.Disponibilite)
+	}
+}
+
+// ============================================================================
+// MEDICAMENT ALERTS V1 TESTS
+// ============================================================================
+
+func TestServeMedicamentAlertsV1(t *testing.T) {
+	today := time.Now()
+	medicament := entities.Medicament{
+		Cis:          61266250,
+		Denomination: "DEPAKINE 500 mg",
+		InfosImportantes: []entities.InfoImportante{
+			{Cis: 61266250, DateDebut: today.AddDate(0, -1, 0).Format("2006-01-02"), DateFin: today.AddDate(0, 1, 0).Format("2006-01-02"), Texte: "Active"},
+			{Cis: 61266250, DateDebut: today.AddDate(-1, 0, 0).Format("2006-01-02"), DateFin: "", Texte: "Open-ended"},
+			{Cis: 61266250, DateDebut: today.AddDate(-1, 0, 0).Format("2006-01-02"), DateFin: today.AddDate(0, -1, 0).Format("2006-01-02"), Texte: "Expired"},
+			{Cis: 61266250, DateDebut: today.AddDate(0, 1, 0).Format("02/01/2006"), DateFin: "", Texte: "Upcoming"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		cis           string
+		expectedCode  int
+		expectedCount int
+		expectError   string
+	}{
+		{"active alerts only", "61266250", http.StatusOK, 2, ""},
+		{"unknown medicament", "60000000", http.StatusNotFound, 0, "Medicament not found"},
+		{"invalid cis", "123", http.StatusBadRequest, 0, "CIS should have 8 digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().WithMedicaments([]entities.Medicament{medicament}).Build()
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			router := chi.NewRouter()
+			router.Get("/v1/medicaments/{cis}/alerts", handler.ServeMedicamentAlertsV1)
+
+			req := httptest.NewRequest("GET", "/v1/medicaments/"+tt.cis+"/alerts", nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var results []entities.InfoImportante
+			if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+			if len(results) != tt.expectedCount {
+				t.Fatalf("Expected %d alerts, got %d", tt.expectedCount, len(results))
+			}
+			for _, alert := range results {
+				if alert.Texte == "Expired" || alert.Texte == "Upcoming" {
+					t.Errorf("Unexpected inactive alert in response: %s", alert.Texte)
+				}
+			}
+		})
+	}
+}
+
+func TestIsInfoImportanteActive(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		info     entities.InfoImportante
+		expected bool
+	}{
+		{"within period", entities.InfoImportante{DateDebut: "2026-01-01", DateFin: "2026-12-31"}, true},
+		{"last day inclusive", entities.InfoImportante{DateDebut: "2026-01-01", DateFin: "2026-03-15"}, true},
+		{"first day inclusive", entities.InfoImportante{DateDebut: "2026-03-15", DateFin: ""}, true},
+		{"expired", entities.InfoImportante{DateDebut: "2025-01-01", DateFin: "2026-03-14"}, false},
+		{"not started", entities.InfoImportante{DateDebut: "16/03/2026", DateFin: ""}, false},
+		{"french format", entities.InfoImportante{DateDebut: "01/01/2026", DateFin: "31/12/2026"}, true},
+		{"no dates", entities.InfoImportante{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isInfoImportanteActive(tt.info, now); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// MITM FILTER V1 TESTS
+// ============================================================================
+
+func TestServeMedicamentsV1_MitmFilter(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 60000001, Denomination: "AMOXICILLINE 500 mg", DenominationNormalized: "amoxicilline 500 mg", Mitm: true, CodeATC: "J01CA04"},
+		{Cis: 60000002, Denomination: "AMOXICILLINE 1 g", DenominationNormalized: "amoxicilline 1 g"},
+		{Cis: 60000003, Denomination: "INSULINE GLARGINE", DenominationNormalized: "insuline glargine", Mitm: true, CodeATC: "A10AE04"},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedCount int
+		paged         bool
+		expectError   string
+	}{
+		{"mitm only", "?mitm=true", http.StatusOK, 2, true, ""},
+		{"non mitm only", "?mitm=false", http.StatusOK, 1, true, ""},
+		{"search with mitm", "?search=amoxicilline&mitm=true", http.StatusOK, 1, true, ""},
+		{"page with mitm", "?page=1&mitm=true", http.StatusOK, 2, true, ""},
+		{"search with mitm no match", "?search=glargine&mitm=false", http.StatusNotFound, 0, false, "No medicaments found"},
+		{"invalid mitm value", "?mitm=maybe", http.StatusBadRequest, 0, false, "Invalid mitm value. Must be true or false"},
+		{"mitm with cip", "?cip=1234567&mitm=true", http.StatusBadRequest, 0, false, "mitm can only be combined with page or search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			req := httptest.NewRequest("GET", "/v1/medicaments"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var results []entities.Medicament
+			if tt.paged {
+				var response struct {
+					Data       []entities.Medicament `json:"data"`
+					TotalItems int                   `json:"totalItems"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response.TotalItems != tt.expectedCount {
+					t.Errorf("Expected totalItems %d, got %d", tt.expectedCount, response.TotalItems)
+				}
+				results = response.Data
+			} else if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			if len(results) != tt.expectedCount {
+				t.Fatalf("Expected %d medicaments, got %d", tt.expectedCount, len(results))
+			}
+			wantMitm := !strings.Contains(tt.query, "mitm=false")
+			for _, med := range results {
+				if med.Mitm != wantMitm {
+					t.Errorf("Expected mitm=%v for CIS %d", wantMitm, med.Cis)
+				}
+			}
+		})
+	}
+}
+
+func TestServeMedicamentsV1_StructuredFilters(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{
+			Cis: 60000001, Denomination: "IBUPROFENE BIOGARAN 400 mg, comprimé", DenominationNormalized: "ibuprofene biogaran 400 mg, comprime",
+			FormePharmaceutique: "comprimé pelliculé", VoiesAdministration: []string{"orale"}, Titulaire: " BIOGARAN",
+			EtatComercialisation: "Commercialisée", StatusAutorisation: "Autorisation active", TypeProcedure: "Procédure nationale",
+			SurveillanceRenforcee: "Oui", DateAMM: "12/03/2015",
+		},
+		{
+			Cis: 60000002, Denomination: "IBUPROFENE BIOGARAN 5 %, gel", DenominationNormalized: "ibuprofene biogaran 5 %, gel",
+			FormePharmaceutique: "gel", VoiesAdministration: []string{"cutanée"}, Titulaire: " BIOGARAN",
+			EtatComercialisation: "Commercialisée", StatusAutorisation: "Autorisation active", TypeProcedure: "Procédure nationale",
+			SurveillanceRenforcee: "Non", DateAMM: "01/06/2021",
+		},
+		{
+			Cis: 60000003, Denomination: "DOLIPRANE 1000 mg, comprimé", DenominationNormalized: "doliprane 1000 mg, comprime",
+			FormePharmaceutique: "comprimé", VoiesAdministration: []string{"orale", "rectale"}, Titulaire: " OPELLA HEALTHCARE FRANCE",
+			EtatComercialisation: "Non commercialisée", StatusAutorisation: "Autorisation abrogée", TypeProcedure: "Procédure de reconnaissance mutuelle",
+			SurveillanceRenforcee: "Non", DateAMM: "20/10/2008",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedCIS  []int
+		paged        bool
+		expectError  string
+	}{
+		{"titulaire", "?titulaire=biogaran", http.StatusOK, []int{60000001, 60000002}, true, ""},
+		{"administration route among several", "?voiesAdministration=rectale", http.StatusOK, []int{60000003}, true, ""},
+		{"combined filters", "?titulaire=BIOGARAN&voiesAdministration=orale&etatComercialisation=commercialisee&surveillanceRenforcee=true", http.StatusOK, []int{60000001}, true, ""},
+		{"exact value, not substring", "?etatComercialisation=Commercialis%C3%A9e", http.StatusOK, []int{60000001, 60000002}, true, ""},
+		{"forme pharmaceutique", "?formePharmaceutique=comprim%C3%A9", http.StatusOK, []int{60000003}, true, ""},
+		{"status and procedure", "?statusAutorisation=autorisation+abrogee&typeProcedure=Proc%C3%A9dure+de+reconnaissance+mutuelle", http.StatusOK, []int{60000003}, true, ""},
+		{"surveillance renforcee false", "?surveillanceRenforcee=false", http.StatusOK, []int{60000002, 60000003}, true, ""},
+		{"dateAMM range", "?dateAMMFrom=2010-01-01&dateAMMTo=31/12/2020", http.StatusOK, []int{60000001}, true, ""},
+		{"dateAMM lower bound only", "?dateAMMFrom=2015-03-12", http.StatusOK, []int{60000001, 60000002}, true, ""},
+		{"with page", "?page=1&pageSize=1&titulaire=biogaran", http.StatusOK, []int{60000001}, true, ""},
+		{"with pageSize only", "?pageSize=1&titulaire=biogaran", http.StatusOK, []int{60000001}, true, ""},
+		{"with search", "?search=ibuprofene&voiesAdministration=cutanee", http.StatusOK, []int{60000002}, true, ""},
+		{"no match", "?titulaire=sanofi", http.StatusNotFound, nil, false, "No medicaments found"},
+		{"invalid boolean", "?surveillanceRenforcee=oui", http.StatusBadRequest, nil, false, "Invalid surveillanceRenforcee value. Must be true or false"},
+		{"invalid date", "?dateAMMFrom=2020-13-01", http.StatusBadRequest, nil, false, "Invalid dateAMMFrom. Use YYYY-MM-DD or DD/MM/YYYY"},
+		{"reversed date range", "?dateAMMFrom=2020-01-01&dateAMMTo=2010-01-01", http.StatusBadRequest, nil, false, "dateAMMTo must not be before dateAMMFrom"},
+		{"filter with cip", "?cip=1234567&titulaire=biogaran", http.StatusBadRequest, nil, false, "titulaire can only be combined with page or search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			req := httptest.NewRequest("GET", "/v1/medicaments"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var results []entities.Medicament
+			if tt.paged {
+				results = decodeMedicamentsPage(t, rr.Body.Bytes())
+			} else if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			cis := make([]int, len(results))
+			for i, med := range results {
+				cis[i] = med.Cis
+			}
+			if fmt.Sprint(cis) != fmt.Sprint(tt.expectedCIS) {
+				t.Errorf("Expected CIS %v, got %v", tt.expectedCIS, cis)
+			}
+		})
+	}
+}
+
+func TestServeMedicamentsV1_Sort(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 60000003, Denomination: "DOLIPRANE 1000 mg", DenominationNormalized: "doliprane 1000 mg", Titulaire: " OPELLA HEALTHCARE FRANCE", DateAMM: "20/10/2008", EtatComercialisation: "Commercialisée"},
+		{Cis: 60000001, Denomination: "IBUPROFENE BIOGARAN 400 mg", DenominationNormalized: "ibuprofene biogaran 400 mg", Titulaire: " BIOGARAN", DateAMM: "12/03/2015", EtatComercialisation: "Commercialisée"},
+		{Cis: 60000002, Denomination: "ADVIL 200 mg", DenominationNormalized: "advil 200 mg", Titulaire: " PFIZER", DateAMM: "01/06/2021", EtatComercialisation: "Non commercialisée"},
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedCIS  []int
+		paged        bool
+		expectError  string
+	}{
+		{"denomination page", "?page=1&sort=denomination", http.StatusOK, []int{60000002, 60000003, 60000001}, true, ""},
+		{"denomination desc page", "?page=1&sort=-denomination", http.StatusOK, []int{60000001, 60000003, 60000002}, true, ""},
+		{"second page", "?page=2&pageSize=2&sort=cis", http.StatusOK, []int{60000003}, true, ""},
+		{"dateAMM desc", "?page=1&sort=-dateAMM", http.StatusOK, []int{60000002, 60000001, 60000003}, true, ""},
+		{"titulaire", "?page=1&sort=titulaire", http.StatusOK, []int{60000001, 60000003, 60000002}, true, ""},
+		{"with filter", "?etatComercialisation=commercialisee&sort=-dateAMM", http.StatusOK, []int{60000001, 60000003}, true, ""},
+		{"with filter and page", "?page=1&etatComercialisation=commercialisee&sort=denomination", http.StatusOK, []int{60000003, 60000001}, true, ""},
+		{"page out of range", "?page=3&pageSize=2&sort=cis", http.StatusNotFound, nil, false, "Page not found"},
+		{"invalid sort", "?page=1&sort=prix", http.StatusBadRequest, nil, false, "Invalid sort. Must be one of: cis, denomination, dateAMM, titulaire, prefixed with - for descending order"},
+		{"invalid descending sort", "?page=1&sort=--cis", http.StatusBadRequest, nil, false, "Invalid sort. Must be one of: cis, denomination, dateAMM, titulaire, prefixed with - for descending order"},
+		{"sort with search", "?search=doliprane&sort=cis", http.StatusBadRequest, nil, false, "sort can only be combined with page or filters"},
+		{"sort with cip", "?cip=1234567&sort=cis", http.StatusBadRequest, nil, false, "sort can only be combined with page or filters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			req := httptest.NewRequest("GET", "/v1/medicaments"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeMedicamentsV1(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectError != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectError {
+					t.Errorf("Expected error '%s', got '%v'", tt.expectError, response["message"])
+				}
+				return
+			}
+
+			var results []entities.Medicament
+			if tt.paged {
+				results = decodeMedicamentsPage(t, rr.Body.Bytes())
+			} else if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			cis := make([]int, len(results))
+			for i, med := range results {
+				cis[i] = med.Cis
+			}
+			if fmt.Sprint(cis) != fmt.Sprint(tt.expectedCIS) {
+				t.Errorf("Expected CIS %v, got %v", tt.expectedCIS, cis)
+			}
+		})
+	}
+}
+
+// cursorPage is the decoded body of a cursor page
+type cursorPage struct {
+	Data []entities.Medicament `json:"data"`
+	Next *string               `json:"next"`
+	Prev *string               `json:"prev"`
+}
+
+func getCursorPage(t *testing.T, handler interfaces.HTTPHandler, target string) (int, cursorPage) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", target, nil)
+	rr := httptest.NewRecorder()
+	handler.ServeMedicamentsV1(rr, req)
+
+	var page cursorPage
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+	}
+	return rr.Code, page
+}
+
+func pageCIS(page cursorPage) []int {
+	cis := make([]int, len(page.Data))
+	for i, med := range page.Data {
+		cis[i] = med.Cis
+	}
+	return cis
+}
+
+func TestServeMedicamentsV1_Cursor(t *testing.T) {
+	medicaments := []entities.Medicament{
+		{Cis: 5, EtatComercialisation: "Commercialisée"},
+		{Cis: 1, EtatComercialisation: "Commercialisée"},
+		{Cis: 4, EtatComercialisation: "Non commercialisée"},
+		{Cis: 2, EtatComercialisation: "Commercialisée"},
+		{Cis: 3, EtatComercialisation: "Commercialisée"},
+	}
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	mockStore.dataVersion = interfaces.DataVersion{Version: 1}
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	t.Run("crawl forward and back", func(t *testing.T) {
+		code, first := getCursorPage(t, handler, "/v1/medicaments?cursor=&pageSize=2")
+		if code != http.StatusOK || fmt.Sprint(pageCIS(first)) != "[1 2]" {
+			t.Fatalf("Expected first page [1 2], got %d %v", code, pageCIS(first))
+		}
+		if first.Prev != nil || first.Next == nil {
+			t.Fatalf("Expected only a next link on the first page, got prev=%v next=%v", first.Prev, first.Next)
+		}
+
+		_, second := getCursorPage(t, handler, *first.Next)
+		if fmt.Sprint(pageCIS(second)) != "[3 4]" || second.Prev == nil || second.Next == nil {
+			t.Fatalf("Expected second page [3 4] with both links, got %v", pageCIS(second))
+		}
+
+		_, last := getCursorPage(t, handler, *second.Next)
+		if fmt.Sprint(pageCIS(last)) != "[5]" || last.Next != nil {
+			t.Fatalf("Expected last page [5] without next link, got %v", pageCIS(last))
+		}
+
+		_, back := getCursorPage(t, handler, *last.Prev)
+		if fmt.Sprint(pageCIS(back)) != "[3 4]" {
+			t.Errorf("Expected prev page [3 4], got %v", pageCIS(back))
+		}
+	})
+
+	t.Run("links keep filters", func(t *testing.T) {
+		_, first := getCursorPage(t, handler, "/v1/medicaments?cursor=&pageSize=2&etatComercialisation=commercialisee")
+		if fmt.Sprint(pageCIS(first)) != "[1 2]" || first.Next == nil {
+			t.Fatalf("Expected first filtered page [1 2], got %v", pageCIS(first))
+		}
+
+		_, second := getCursorPage(t, handler, *first.Next)
+		if fmt.Sprint(pageCIS(second)) != "[3 5]" || second.Next != nil {
+			t.Errorf("Expected last filtered page [3 5], got %v", pageCIS(second))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			query        string
+			expectedCode int
+		}{
+			{"invalid cursor", "?cursor=not-a-cursor", http.StatusBadRequest},
+			{"cursor with page", "?cursor=&page=1", http.StatusBadRequest},
+			{"cursor with search", "?cursor=&search=doliprane", http.StatusBadRequest},
+			{"cursor with sort", "?cursor=&sort=cis", http.StatusBadRequest},
+			{"invalid pageSize", "?cursor=&pageSize=500", http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			if code, _ := getCursorPage(t, handler, "/v1/medicaments"+tt.query); code != tt.expectedCode {
+				t.Errorf("%s: expected status %d, got %d", tt.name, tt.expectedCode, code)
+			}
+		}
+	})
+
+	t.Run("cursor from a replaced dataset", func(t *testing.T) {
+		_, first := getCursorPage(t, handler, "/v1/medicaments?cursor=&pageSize=2")
+
+		mockStore.UpdateData(medicaments, nil, nil, nil, nil, nil, nil, interfaces.DataVersion{Version: mockStore.dataVersion.Version + 1})
+
+		req := httptest.NewRequest("GET", *first.Next, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeMedicamentsV1(rr, req)
+
+		if rr.Code != http.StatusGone {
+			t.Fatalf("Expected status %d, got %d", http.StatusGone, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "dataset that has been replaced") {
+			t.Errorf("Expected replaced dataset message, got %s", rr.Body.String())
+		}
+	})
+}
+
+func TestServeChangesV1(t *testing.T) {
+	updates := []time.Time{
+		time.Date(2026, 1, 14, 6, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 14, 18, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 15, 6, 0, 0, 0, time.UTC),
+	}
+	changeSets := []changes.ChangeSet{
+		{FromVersion: 1, ToVersion: 2, FromLoadedAt: updates[0], ToLoadedAt: updates[1], Medicaments: changes.Diff{Added: []int{1}}},
+		{FromVersion: 2, ToVersion: 3, FromLoadedAt: updates[1], ToLoadedAt: updates[2], Medicaments: changes.Diff{Removed: []int{2}}},
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedCode    int
+		expectedToVers  []uint64
+		expectedMessage string
+	}{
+		{"all retained", "", http.StatusOK, []uint64{2, 3}, ""},
+		{"since oldest version", "?since=1", http.StatusOK, []uint64{2, 3}, ""},
+		{"since middle version", "?since=2", http.StatusOK, []uint64{3}, ""},
+		{"since current version", "?since=3", http.StatusOK, []uint64{}, ""},
+		{"since timestamp", "?since=2026-01-14T20:00:00Z", http.StatusOK, []uint64{3}, ""},
+		{"since first load", "?since=2026-01-14T06:00:00Z", http.StatusOK, []uint64{2, 3}, ""},
+		{"version no longer retained", "?since=0", http.StatusGone, nil, "Changes since 0 are no longer available. Download the full export"},
+		{"unknown future version", "?since=4", http.StatusGone, nil, "Changes since 4 are no longer available. Download the full export"},
+		{"timestamp no longer retained", "?since=2026-01-01T00:00:00Z", http.StatusGone, nil, "Changes since 2026-01-01T00:00:00Z are no longer available. Download the full export"},
+		{"invalid since", "?since=yesterday", http.StatusBadRequest, nil, "Invalid since. Use a dataVersion or an RFC 3339 timestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockDataStoreBuilder().Build()
+			mockStore.changeSets = changeSets
+			mockStore.dataVersion = interfaces.DataVersion{Version: 3, LoadedAt: updates[2]}
+			handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+			req := httptest.NewRequest("GET", "/v1/changes"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeChangesV1(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectedCode, rr.Code)
+			}
+
+			if tt.expectedMessage != "" {
+				var response map[string]any
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal JSON: %v", err)
+				}
+				if response["message"] != tt.expectedMessage {
+					t.Errorf("Expected error %q, got %v", tt.expectedMessage, response["message"])
+				}
+				return
+			}
+
+			var response struct {
+				DataVersion uint64              `json:"dataVersion"`
+				Changes     []changes.ChangeSet `json:"changes"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal JSON: %v", err)
+			}
+
+			if response.DataVersion != 3 {
+				t.Errorf("Expected dataVersion 3, got %d", response.DataVersion)
+			}
+			toVersions := make([]uint64, len(response.Changes))
+			for i, changeSet := range response.Changes {
+				toVersions[i] = changeSet.ToVersion
+			}
+			if fmt.Sprint(toVersions) != fmt.Sprint(tt.expectedToVers) {
+				t.Errorf("Expected change sets to versions %v, got %v", tt.expectedToVers, toVersions)
+			}
+		})
+	}
+}
+
+func TestServeMedicamentsV1_Fields(t *testing.T) {
+	factory := NewTestDataFactory()
+	medicaments := []entities.Medicament{
+		factory.CreateMedicament(1, "Test Med 1"),
+		factory.CreateMedicament(2, "Test Med 2"),
+	}
+	mockStore := NewMockDataStoreBuilder().WithMedicaments(medicaments).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/v1/medicaments", handler.ServeMedicamentsV1)
+	router.Get("/v1/medicaments/{cis}", handler.FindMedicamentByCIS)
+
+	tests := []struct {
+		name         string
+		target       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "by CIS",
+			target:       "/v1/medicaments/00000001?fields=cis,elementPharmaceutique",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"cis":1,"elementPharmaceutique":"Test Med 1"}`,
+		},
+		{
+			name:         "nested field",
+			target:       "/v1/medicaments/00000001?fields=cis,presentation.cip7",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"cis":1,"presentation":[{"cip7":1234567}]}`,
+		},
+		{
+			name:         "page",
+			target:       "/v1/medicaments?page=1&pageSize=2&fields=cis",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"data":[{"cis":1},{"cis":2}],"maxPage":1,"page":1,"pageSize":2,"totalItems":2}`,
+		},
+		{
+			name:         "unknown field",
+			target:       "/v1/medicaments?page=1&fields=cis,nom",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":400,"error":"Bad Request","message":"Invalid fields: unknown field nom"}`,
+		},
+		{
+			name:         "empty fields",
+			target:       "/v1/medicaments/00000001?fields=",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"code":400,"error":"Bad Request","message":"Invalid fields: empty field name"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedCode, rr.Code, rr.Body.String())
+			}
+			if body := strings.TrimSpace(rr.Body.String()); body != tt.expectedBody {
+				t.Errorf("Expected %s, got %s", tt.expectedBody, body)
+			}
+		})
+	}
+
+	// A selection has its own ETag
+	etags := make(map[string]bool)
+	for _, target := range []string{"/v1/medicaments?page=1", "/v1/medicaments?page=1&fields=cis", "/v1/medicaments?page=1&fields=elementPharmaceutique"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		etags[rr.Header().Get("ETag")] = true
+	}
+	if len(etags) != 3 {
+		t.Errorf("Expected one ETag per selection, got %v", etags)
+	}
+}
+
+func TestFieldsRoutes(t *testing.T) {
+	factory := NewTestDataFactory()
+	med := factory.CreateMedicament(1, "Test Med 1")
+	med.Composition = []entities.Composition{{Cis: 1, CodeSubstance: 42, DenominationSubstance: "PARACETAMOL"}}
+
+	mockStore := NewMockDataStoreBuilder().WithMedicaments([]entities.Medicament{med}).Build()
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/v1/substances/{code}/medicaments", handler.ServeSubstanceMedicamentsV1)
+	router.Get("/v1/medicaments/{cis}/alerts", handler.ServeMedicamentAlertsV1)
+	router.Get("/v1/disponibilites", handler.ServeDisponibilitesV1)
+	router.Get("/v1/presentations/{cip}", handler.ServePresentationsV1)
+	router.Get("/v1/generiques", handler.ServeGeneriquesV1)
+
+	t.Run("substance medicaments", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/substances/42/medicaments?fields=cis", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+		if fmt.Sprint(body["data"]) != "[map[cis:1]]" {
+			t.Errorf("Expected only the CIS of each medicament, got %v", body["data"])
+		}
+	})
+
+	for _, target := range []string{
+		"/v1/medicaments/00000001/alerts?fields=cis",
+		"/v1/disponibilites?fields=cis",
+		"/v1/presentations/1234567?fields=cis",
+		"/v1/generiques?libelle=test&fields=cis",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+			if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "fields is only supported on") {
+				t.Errorf("Expected fields to be rejected, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestExpandRelations(t *testing.T) {
+	factory := NewTestDataFactory()
+	princeps := factory.CreateMedicament(1, "Test Med 1")
+	princeps.Generiques = []entities.Generique{{Cis: 1, Group: 10, Libelle: "TEST 500 mg", Type: "Princeps"}}
+	generique := factory.CreateMedicament(2, "Test Med 2")
+	generique.Generiques = []entities.Generique{{Cis: 2, Group: 10, Libelle: "TEST 500 mg", Type: "Générique"}}
+
+	group := factory.CreateGeneriqueList(10, "TEST 500 mg", []int{1, 2})
+	group.Medicaments[0].Type = "Princeps"
+	group.Medicaments[1].Type = "Générique"
+
+	mockStore := NewMockDataStoreBuilder().
+		WithMedicaments([]entities.Medicament{princeps, generique}).
+		WithGeneriques([]entities.GeneriqueList{group}).
+		Build()
+	mockStore.generiquesMap = map[int]entities.GeneriqueList{10: group}
+	handler := NewHTTPHandler(mockStore, NewMockDataValidatorBuilder().Build(), NewMockHealthCheckerBuilder().Build())
+
+	router := chi.NewRouter()
+	router.Get("/v1/medicaments/{cis}", handler.FindMedicamentByCIS)
+	router.Get("/v1/generiques/{groupID}", handler.FindGeneriquesByGroupID)
+
+	get := func(t *testing.T, target string) (int, map[string]any) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+
+		var body map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to unmarshal JSON: %v", err)
+		}
+		return rr.Code, body
+	}
+
+	t.Run("medicament without expand", func(t *testing.T) {
+		_, body := get(t, "/v1/medicaments/00000001")
+		presentation := body["presentation"].([]any)[0].(map[string]any)
+		if presentation["libelle"] != "Boîte de 20 comprimés" {
+			t.Errorf("Expected the embedded presentation, got %v", presentation)
+		}
+		if _, exists := body["generiqueGroups"]; exists {
+			t.Error("Expected no generiqueGroups without expand")
+		}
+	})
+
+	t.Run("medicament references", func(t *testing.T) {
+		code, body := get(t, "/v1/medicaments/00000001?expand=")
+		if code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", code)
+		}
+		if fmt.Sprint(body["presentation"]) != "[map[cip13:0 cip7:1.234567e+06 href:/v1/presentations/1234567]]" {
+			t.Errorf("Expected a presentation reference, got %v", body["presentation"])
+		}
+		if fmt.Sprint(body["generiques"]) != "[map[group:10 href:/v1/generiques/10 type:Princeps]]" {
+			t.Errorf("Expected a generique reference, got %v", body["generiques"])
+		}
+		if len(body["composition"].([]any)) != 1 {
+			t.Errorf("Expected the composition to stay embedded, got %v", body["composition"])
+		}
+	})
+
+	t.Run("medicament generique groups", func(t *testing.T) {
+		_, body := get(t, "/v1/medicaments/00000001?expand=presentation,generiqueGroups")
+		if _, ok := body["presentation"].([]any)[0].(map[string]any)["libelle"]; !ok {
+			t.Errorf("Expected the embedded presentation, got %v", body["presentation"])
+		}
+		groups := body["generiqueGroups"].([]any)
+		if len(groups) != 1 || groups[0].(map[string]any)["libelle"] != "TEST 500 mg" {
+			t.Errorf("Expected the generique group, got %v", groups)
+		}
+	})
+
+	t.Run("medicament expand with fields", func(t *testing.T) {
+		_, body := get(t, "/v1/medicaments/00000001?expand=&fields=cis,generiques")
+		if len(body) != 2 || fmt.Sprint(body["generiques"]) != "[map[group:10 href:/v1/generiques/10 type:Princeps]]" {
+			t.Errorf("Expected the CIS and the generique reference, got %v", body)
+		}
+	})
+
+	t.Run("medicament expand with fields of the expanded shape", func(t *testing.T) {
+		code, body := get(t, "/v1/medicaments/00000001?expand=generiqueGroups&fields=cis,presentation.href,generiqueGroups.libelle")
+		if code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %v", code, body)
+		}
+		if len(body) != 3 || fmt.Sprint(body["presentation"]) != "[map[href:/v1/presentations/1234567]]" ||
+			fmt.Sprint(body["generiqueGroups"]) != "[map[libelle:TEST 500 mg]]" {
+			t.Errorf("Expected the selected fields of the references and groups, got %v", body)
+		}
+	})
+
+	t.Run("medicament expand with fields of another shape", func(t *testing.T) {
+		for target, message := range map[string]string{
+			"/v1/medicaments/00000001?expand=&fields=presentation.prix":             "Invalid fields: unknown field presentation.prix",
+			"/v1/medicaments/00000001?expand=presentation&fields=presentation.href": "Invalid fields: unknown field presentation.href",
+			"/v1/medicaments/00000001?expand=&fields=generiqueGroups":               "Invalid fields: unknown field generiqueGroups",
+			"/v1/medicaments/00000001?fields=generiqueGroups":                       "Invalid fields: unknown field generiqueGroups",
+		} {
+			code, body := get(t, target)
+			if code != http.StatusBadRequest || body["message"] != message {
+				t.Errorf("%s: expected 400 %q, got %d %v", target, message, code, body["message"])
+			}
+		}
+	})
+
+	t.Run("generique group without expand", func(t *testing.T) {
+		_, body := get(t, "/v1/generiques/10")
+		member := body["medicaments"].([]any)[0].(map[string]any)
+		if member["elementPharmaceutique"] != "Médicament Générique 1" || member["titulaire"] != nil {
+			t.Errorf("Expected the light generique medicament, got %v", member)
+		}
+	})
+
+	t.Run("generique group references", func(t *testing.T) {
+		_, body := get(t, "/v1/generiques/10?expand=")
+		if fmt.Sprint(body["medicaments"]) != "[map[cis:1 href:/v1/medicaments/00000001 type:Princeps] map[cis:2 href:/v1/medicaments/00000002 type:Générique]]" {
+			t.Errorf("Expected medicament references, got %v", body["medicaments"])
+		}
+	})
+
+	t.Run("generique group medicaments", func(t *testing.T) {
+		_, body := get(t, "/v1/generiques/10?expand=medicaments")
+		members := body["medicaments"].([]any)
+		if len(members) != 2 {
+			t.Fatalf("Expected 2 medicaments, got %d", len(members))
+		}
+		member := members[1].(map[string]any)
+		if member["titulaire"] != "Laboratoire Test" || member["type"] != "Générique" {
+			t.Errorf("Expected the full medicament with its type, got %v", member)
+		}
+	})
+
+	t.Run("unknown relation", func(t *testing.T) {
+		code, body := get(t, "/v1/generiques/10?expand=presentation")
+		if code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400, got %d", code)
+		}
+		if body["message"] != `Invalid expand: unknown relation "presentation". Use one of: medicaments` {
+			t.Errorf("Unexpected message: %v", body["message"])
+		}
+	})
+}
@@ -28,9 +28,13 @@ func (h *Checker) HealthCheck() (status string, data map[string]any, httpStatus
 	medicaments := h.dataStore.GetMedicaments()
 	generiques := h.dataStore.GetGeneriques()
 	lastUpdate := h.dataStore.GetLastUpdated()
+	lastCheck := h.dataStore.GetLastChecked()
+	dataVersion := h.dataStore.GetDataVersion()
 	isUpdating := h.dataStore.IsUpdating()
 
 	dataAge := time.Since(lastUpdate)
+	// Updates that find the sources unchanged keep the data but still count as fresh
+	checkAge := time.Since(lastCheck)
 
 	// Determine health status and HTTP code using stricter thresholds
 	switch {
@@ -38,15 +42,15 @@ func (h *Checker) HealthCheck() (status string, data map[string]any, httpStatus
 		status = "unhealthy"
 		httpStatus = http.StatusServiceUnavailable
 
-	case dataAge > 48*time.Hour:
+	case checkAge > 48*time.Hour:
 		status = "unhealthy"
 		httpStatus = http.StatusServiceUnavailable
 
-	case dataAge > 24*time.Hour:
+	case checkAge > 24*time.Hour:
 		status = "degraded"
 		httpStatus = http.StatusServiceUnavailable
 
-	case isUpdating && dataAge > 6*time.Hour:
+	case isUpdating && checkAge > 6*time.Hour:
 		status = "degraded"
 		httpStatus = http.StatusServiceUnavailable
 
@@ -58,10 +62,13 @@ func (h *Checker) HealthCheck() (status string, data map[string]any, httpStatus
 	// Build response data (no system metrics, only data-related fields)
 	data = map[string]any{
 		"last_update":    lastUpdate.Format(time.RFC3339),
+		"last_check":     lastCheck.Format(time.RFC3339),
 		"data_age_hours": math.Round(dataAge.Hours()*10) / 10,
 		"medicaments":    len(medicaments),
 		"generiques":     len(generiques),
 		"is_updating":    isUpdating,
+		"data_version":   dataVersion.Version,
+		"data_hash":      dataVersion.Hash,
 	}
 
 	return status, data, httpStatus
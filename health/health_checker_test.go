@@ -6,8 +6,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // MockDataStore for testing
@@ -17,6 +21,8 @@ type MockHealthDataStore struct {
 	presentationsCIP7Map  map[int]entities.Presentation
 	presentationsCIP13Map map[int]entities.Presentation
 	lastUpdated           time.Time
+	dataVersion           interfaces.DataVersion
+	changeSets            []changes.ChangeSet
 	isUpdating            bool
 	shouldFail            bool
 }
@@ -51,15 +57,75 @@ func (m *MockHealthDataStore) GetPresentationsCIP13Map() map[int]entities.Presen
 	return m.presentationsCIP13Map
 }
 
+func (m *MockHealthDataStore) GetDisponibilites() []entities.Disponibilite {
+	return []entities.Disponibilite{}
+}
+
+func (m *MockHealthDataStore) GetSearchIndex() *search.Index {
+	return search.NewIndex(nil)
+}
+
+func (m *MockHealthDataStore) GetSubstanceIndex() *search.SubstanceIndex {
+	return search.NewSubstanceIndex(nil)
+}
+
+func (m *MockHealthDataStore) GetMedicamentOrders() search.Orders {
+	return search.NewOrders(nil)
+}
+
 func (m *MockHealthDataStore) GetLastUpdated() time.Time {
 	return m.lastUpdated
 }
 
+func (m *MockHealthDataStore) GetLastChecked() time.Time {
+	return m.lastUpdated
+}
+
+func (m *MockHealthDataStore) MarkChecked() {
+	// Not used in health tests
+}
+
+func (m *MockHealthDataStore) GetDataset() *interfaces.Dataset {
+	return &interfaces.Dataset{
+		Version:               m.GetDataVersion(),
+		Medicaments:           m.GetMedicaments(),
+		Generiques:            m.GetGeneriques(),
+		MedicamentsMap:        m.GetMedicamentsMap(),
+		GeneriquesMap:         m.GetGeneriquesMap(),
+		PresentationsCIP7Map:  m.GetPresentationsCIP7Map(),
+		PresentationsCIP13Map: m.GetPresentationsCIP13Map(),
+		Disponibilites:        m.GetDisponibilites(),
+		SearchIndex:           m.GetSearchIndex(),
+		SubstanceIndex:        m.GetSubstanceIndex(),
+		Orders:                m.GetMedicamentOrders(),
+	}
+}
+
+func (m *MockHealthDataStore) GetTabularExports() (*export.Exports, interfaces.DataVersion) {
+	return nil, m.GetDataVersion()
+}
+
+func (m *MockHealthDataStore) GetDataVersion() interfaces.DataVersion {
+	return m.dataVersion
+}
+
+func (m *MockHealthDataStore) GetChanges() []changes.ChangeSet {
+	return m.changeSets
+}
+
+func (m *MockHealthDataStore) GetExportArtifact() (*artifact.Artifact, interfaces.DataVersion) {
+	return nil, m.GetDataVersion()
+}
+
+func (m *MockHealthDataStore) RecordChanges(changeSet changes.ChangeSet) {
+	m.changeSets = append(m.changeSets, changeSet)
+}
+
 func (m *MockHealthDataStore) IsUpdating() bool {
 	return m.isUpdating
 }
 
-func (m *MockHealthDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentionsCIP7Map map[int]entities.Presentation, presentionsCIP13Map map[int]entities.Presentation, report *interfaces.DataQualityReport) {
+func (m *MockHealthDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentionsCIP7Map map[int]entities.Presentation, presentionsCIP13Map map[int]entities.Presentation, report *interfaces.DataQualityReport, version interfaces.DataVersion) {
 	// Not used in health tests
 }
 
@@ -102,6 +168,7 @@ func TestHealthCheck_Healthy(t *testing.T) {
 			{GroupID: 1, Libelle: "Gen1"},
 		},
 		lastUpdated: time.Now().Add(-1 * time.Hour),
+		dataVersion: interfaces.DataVersion{Version: 2, Hash: "abc"},
 		isUpdating:  false,
 	}
 
@@ -120,12 +187,16 @@ func TestHealthCheck_Healthy(t *testing.T) {
 		t.Error("Data should not be nil")
 	}
 
-	requiredFields := []string{"last_update", "data_age_hours", "medicaments", "generiques", "is_updating"}
+	requiredFields := []string{"last_update", "last_check", "data_age_hours", "medicaments", "generiques", "is_updating", "data_version", "data_hash"}
 	for _, field := range requiredFields {
 		if _, ok := data[field]; !ok {
 			t.Errorf("Data should contain '%s' field", field)
 		}
 	}
+
+	if data["data_version"] != uint64(2) || data["data_hash"] != "abc" {
+		t.Errorf("Expected data version 2 with hash abc, got %v %v", data["data_version"], data["data_hash"])
+	}
 }
 
 func TestHealthCheck_Unhealthy_NoMedicaments(t *testing.T) {
@@ -3,10 +3,15 @@
 package interfaces
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // DataQualityReport provides a summary of data quality issues
@@ -26,29 +31,80 @@ type DataQualityReport struct {
 	MedicamentsWithoutCompositionsCIS   []int
 	GeneriqueOnlyCISList                []int
 	PresentationsWithOrphanedCISCIPList []int
+	// Source files that failed to download, parsed from their last good copy
+	StaleSourceFiles []string
+	// Supplementary source files that could not be fetched or parsed, left out of the data
+	SkippedSourceFiles []string
 }
 
+// DataVersion identifies a loaded dataset. Version increases by one at every update and is
+// saved with the snapshot, so that it keeps increasing across restarts. Hash is a SHA-256 of
+// the content of the source files the dataset was parsed from.
+type DataVersion struct {
+	Version  uint64    `json:"version"`
+	Hash     string    `json:"hash"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// Dataset is one version of the data with everything derived from it. It is immutable and
+// replaced as a whole by an update, so that a response read from a single Dataset never mixes
+// two versions.
+type Dataset struct {
+	Version               DataVersion
+	Medicaments           []entities.Medicament
+	Generiques            []entities.GeneriqueList
+	MedicamentsMap        map[int]entities.Medicament
+	GeneriquesMap         map[int]entities.GeneriqueList
+	PresentationsCIP7Map  map[int]entities.Presentation
+	PresentationsCIP13Map map[int]entities.Presentation
+	Disponibilites        []entities.Disponibilite // Sorted by CIS then CIP13
+	SearchIndex           *search.Index
+	SubstanceIndex        *search.SubstanceIndex
+	Orders                search.Orders
+}
+
+// ErrSourcesUnchanged is returned by Parser.ParseAllMedicaments when none of the source
+// files changed since the last successful parse. The current dataset stays valid.
+var ErrSourcesUnchanged = errors.New("sources unchanged since the last update")
+
 // DataStore defines the contract for data storage operations.
 // It provides thread-safe access to medicaments and generiques data
 // with atomic operations for zero-downtime updates.
 type DataStore interface {
-	// Data retrieval methods
+	// GetDataset returns the dataset being served. Handlers reading several parts of the data
+	// or its version read them from one Dataset rather than from the getters below.
+	GetDataset() *Dataset
+
+	// Data retrieval methods, each reading the dataset being served
 	GetMedicaments() []entities.Medicament
 	GetGeneriques() []entities.GeneriqueList
 	GetMedicamentsMap() map[int]entities.Medicament
 	GetGeneriquesMap() map[int]entities.GeneriqueList
 	GetPresentationsCIP7Map() map[int]entities.Presentation
 	GetPresentationsCIP13Map() map[int]entities.Presentation
+	GetDisponibilites() []entities.Disponibilite
+	GetSearchIndex() *search.Index
+	GetSubstanceIndex() *search.SubstanceIndex
+	GetMedicamentOrders() search.Orders
 	GetLastUpdated() time.Time
+	GetLastChecked() time.Time
+	GetDataVersion() DataVersion
+	// GetTabularExports returns the CSV and Parquet exports with the version they were built from
+	GetTabularExports() (*export.Exports, DataVersion)
 	IsUpdating() bool
 	GetServerStartTime() time.Time
 	GetDataQualityReport() *DataQualityReport
+	GetChanges() []changes.ChangeSet
+	// GetExportArtifact returns the precompressed JSON export with the version it was built from
+	GetExportArtifact() (*artifact.Artifact, DataVersion)
 
 	// Data update methods
 	UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList,
 		medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList,
 		presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation,
-		report *DataQualityReport)
+		report *DataQualityReport, version DataVersion)
+	RecordChanges(changeSet changes.ChangeSet)
+	MarkChecked()
 	BeginUpdate() bool
 	EndUpdate()
 }
@@ -56,10 +112,28 @@ type DataStore interface {
 // Parser defines the contract for parsing medicament data from external sources.
 // It handles downloading, processing, and transforming raw data into structured entities.
 type Parser interface {
-	// ParseAllMedicaments downloads and parses all medicament data
+	// ParseAllMedicaments downloads and parses all medicament data.
+	// It returns ErrSourcesUnchanged when there is nothing new to parse.
 	ParseAllMedicaments() ([]entities.Medicament, map[int]entities.Presentation,
 		map[int]entities.Presentation, error)
 
+	// StaleSources returns the source files that the last ParseAllMedicaments could not
+	// fetch and read from their previous copy instead
+	StaleSources() []string
+
+	// SkippedSources returns the supplementary source files (HAS evaluations, shortages,
+	// safety information, MITM) that the last ParseAllMedicaments left out of the data
+	// because they could not be fetched or parsed
+	SkippedSources() []string
+
+	// SourceHash returns the SHA-256 of the content hashes of the source files behind the
+	// last successful ParseAllMedicaments, empty before the first one
+	SourceHash() string
+
+	// RestoreSourceHash sets the source hash of a dataset restored without parsing, such as
+	// a snapshot, so that the next ParseAllMedicaments skips sources that did not change
+	RestoreSourceHash(hash string)
+
 	// GeneriquesParser processes medicaments data to create generique groups
 	GeneriquesParser(medicaments *[]entities.Medicament, medicamentsMap *map[int]entities.Medicament) ([]entities.GeneriqueList, map[int]entities.GeneriqueList, error)
 }
@@ -91,12 +165,30 @@ type HTTPHandler interface {
 
 	// V1 handlers
 	ServeMedicamentsV1(w http.ResponseWriter, r *http.Request)
+	ServeMedicamentAlertsV1(w http.ResponseWriter, r *http.Request)
 	ServePresentationsV1(w http.ResponseWriter, r *http.Request)
 	ServePresentationsMissingCIP(w http.ResponseWriter, r *http.Request)
+	ServeDisponibilitesV1(w http.ResponseWriter, r *http.Request)
+	ServeSuggestV1(w http.ResponseWriter, r *http.Request)
+	ServeSubstancesV1(w http.ResponseWriter, r *http.Request)
+	ServeSubstanceMedicamentsV1(w http.ResponseWriter, r *http.Request)
+	ServeChangesV1(w http.ResponseWriter, r *http.Request)
 	ServeGeneriquesV1(w http.ResponseWriter, r *http.Request)
 	ServeDiagnosticsV1(w http.ResponseWriter, r *http.Request)
 }
 
+// ChangeNotifier is told about the changes between two successive data updates.
+// Implementations must not block the update.
+type ChangeNotifier interface {
+	NotifyChanges(changeSet changes.ChangeSet, presentationsCIP13Map map[int]entities.Presentation)
+}
+
+// EventPublisher broadcasts scheduler events such as the start and end of a data update.
+// Publish must not block.
+type EventPublisher interface {
+	Publish(eventType string, data any)
+}
+
 // HealthChecker defines the contract for health check functionality.
 // It provides system health monitoring and reporting.
 type HealthChecker interface {
@@ -8,7 +8,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // MockDataStore implements DataStore interface for testing
@@ -20,6 +24,9 @@ type MockDataStore struct {
 	presentationsCIP7Map  map[int]entities.Presentation
 	presentationsCIP13Map map[int]entities.Presentation
 	lastUpdated           time.Time
+	lastChecked           time.Time
+	dataVersion           DataVersion
+	changeSets            []changes.ChangeSet
 	updating              bool
 }
 
@@ -47,22 +54,82 @@ func (m *MockDataStore) GetPresentationsCIP13Map() map[int]entities.Presentation
 	return m.presentationsCIP13Map
 }
 
+func (m *MockDataStore) GetDisponibilites() []entities.Disponibilite {
+	return []entities.Disponibilite{}
+}
+
+func (m *MockDataStore) GetSearchIndex() *search.Index {
+	return search.NewIndex(nil)
+}
+
+func (m *MockDataStore) GetSubstanceIndex() *search.SubstanceIndex {
+	return search.NewSubstanceIndex(nil)
+}
+
+func (m *MockDataStore) GetMedicamentOrders() search.Orders {
+	return search.NewOrders(nil)
+}
+
 func (m *MockDataStore) GetLastUpdated() time.Time {
 	return m.lastUpdated
 }
 
+func (m *MockDataStore) GetLastChecked() time.Time {
+	return m.lastChecked
+}
+
+func (m *MockDataStore) MarkChecked() {
+	m.lastChecked = time.Now()
+}
+
+func (m *MockDataStore) GetDataset() *Dataset {
+	return &Dataset{
+		Version:               m.GetDataVersion(),
+		Medicaments:           m.GetMedicaments(),
+		Generiques:            m.GetGeneriques(),
+		MedicamentsMap:        m.GetMedicamentsMap(),
+		GeneriquesMap:         m.GetGeneriquesMap(),
+		PresentationsCIP7Map:  m.GetPresentationsCIP7Map(),
+		PresentationsCIP13Map: m.GetPresentationsCIP13Map(),
+		Disponibilites:        m.GetDisponibilites(),
+		SearchIndex:           m.GetSearchIndex(),
+		SubstanceIndex:        m.GetSubstanceIndex(),
+		Orders:                m.GetMedicamentOrders(),
+	}
+}
+
+func (m *MockDataStore) GetTabularExports() (*export.Exports, DataVersion) {
+	return nil, m.GetDataVersion()
+}
+
+func (m *MockDataStore) GetDataVersion() DataVersion {
+	return m.dataVersion
+}
+
+func (m *MockDataStore) GetChanges() []changes.ChangeSet {
+	return m.changeSets
+}
+
+func (m *MockDataStore) GetExportArtifact() (*artifact.Artifact, DataVersion) {
+	return nil, m.GetDataVersion()
+}
+
+func (m *MockDataStore) RecordChanges(changeSet changes.ChangeSet) {
+	m.changeSets = append(m.changeSets, changeSet)
+}
+
 func (m *MockDataStore) IsUpdating() bool {
 	return m.updating
 }
 
-func (m *MockDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation, report *DataQualityReport) {
+func (m *MockDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation, report *DataQualityReport, version DataVersion) {
 	m.medicaments = medicaments
 	m.generiques = generiques
 	m.medicamentsMap = medicamentsMap
 	m.generiquesMap = generiquesMap
 	m.presentationsCIP7Map = presentationsCIP7Map
 	m.presentationsCIP13Map = presentationsCIP13Map
-	m.lastUpdated = time.Now()
+	m.lastUpdated = version.LoadedAt
 }
 
 func (m *MockDataStore) GetDataQualityReport() *DataQualityReport {
@@ -118,6 +185,20 @@ func (m *MockParser) ParseAllMedicaments() ([]entities.Medicament, map[int]entit
 		}, nil
 }
 
+func (m *MockParser) StaleSources() []string {
+	return nil
+}
+
+func (m *MockParser) SkippedSources() []string {
+	return nil
+}
+
+func (m *MockParser) SourceHash() string {
+	return ""
+}
+
+func (m *MockParser) RestoreSourceHash(hash string) {}
+
 func (m *MockParser) GeneriquesParser(medicaments *[]entities.Medicament, medicamentsMap *map[int]entities.Medicament) ([]entities.GeneriqueList, map[int]entities.GeneriqueList, error) {
 	if m.shouldFail {
 		return nil, nil, &mockError{"generiques parse failed"}
@@ -227,6 +308,36 @@ func (m *MockHTTPHandler) ServeDiagnosticsV1(w http.ResponseWriter, r *http.Requ
 	_, _ = w.Write([]byte(m.responseBody))
 }
 
+func (m *MockHTTPHandler) ServeDisponibilitesV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
+func (m *MockHTTPHandler) ServeMedicamentAlertsV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
+func (m *MockHTTPHandler) ServeSuggestV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
+func (m *MockHTTPHandler) ServeSubstancesV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
+func (m *MockHTTPHandler) ServeSubstanceMedicamentsV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
+func (m *MockHTTPHandler) ServeChangesV1(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(m.responseCode)
+	_, _ = w.Write([]byte(m.responseBody))
+}
+
 // MockDataValidator implements DataValidator interface for testing
 type MockDataValidator struct {
 	shouldFail bool
@@ -100,3 +100,8 @@ func (w *responseWriterWrapper) Write(data []byte) (int, error) {
 	w.bytesWritten += n
 	return n, err
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer (flushing, write deadlines)
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
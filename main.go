@@ -15,10 +15,12 @@ import (
 
 	"github.com/giygas/medicaments-api/config"
 	"github.com/giygas/medicaments-api/data"
+	"github.com/giygas/medicaments-api/events"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser"
 	"github.com/giygas/medicaments-api/scheduler"
 	"github.com/giygas/medicaments-api/server"
+	"github.com/giygas/medicaments-api/webhooks"
 	"github.com/joho/godotenv"
 )
 
@@ -86,20 +88,69 @@ func main() {
 		"log_level", cfg.LogLevel,
 		"allow_direct_access", cfg.AllowDirectAccess,
 		"max_request_body", cfg.MaxRequestBody,
-		"max_header_size", cfg.MaxHeaderSize)
+		"max_header_size", cfg.MaxHeaderSize,
+		"changes_retention", cfg.ChangesRetention,
+		"download_retries", cfg.DownloadRetries,
+		"snapshot_path", cfg.SnapshotPath,
+		"disable_snapshot", cfg.DisableSnapshot)
 
 	// Initialize data container and parser
 	dataContainer := data.NewDataContainer()
+	dataContainer.SetChangesRetention(cfg.ChangesRetention)
 
-	httpClient, err := newCertignaHTTPClient()
-	if err != nil {
-		logging.Error("Failed to create HTTP client", "error", err)
-		os.Exit(1)
+	var parser *medicamentsparser.MedicamentsParser
+	if cfg.BDPMLocalPath != "" {
+		// Offline mode: the BDPM files are provided locally
+		source, err := medicamentsparser.NewLocalSource(cfg.BDPMLocalPath)
+		if err != nil {
+			logging.Error("Failed to open BDPM local source", "error", err)
+			os.Exit(1)
+		}
+		logging.Info("Reading BDPM files from local source", "path", cfg.BDPMLocalPath)
+		parser = medicamentsparser.NewMedicamentsParserFromSource(source)
+	} else {
+		httpClient, err := newCertignaHTTPClient()
+		if err != nil {
+			logging.Error("Failed to create HTTP client", "error", err)
+			os.Exit(1)
+		}
+		parser = medicamentsparser.NewMedicamentsParser(httpClient)
+	}
+	parser.SetRetryPolicy(medicamentsparser.RetryPolicy{
+		Retries:   cfg.DownloadRetries,
+		BaseDelay: time.Duration(cfg.DownloadRetryDelaySeconds) * time.Second,
+	})
+
+	// Webhook subscriptions are notified of the changes after each update
+	dispatcher := webhooks.NewDispatcher(webhooks.NewClient(10*time.Second, cfg.WebhooksAllowPrivate))
+	dispatcher.AllowPrivateTargets(cfg.WebhooksAllowPrivate)
+	if cfg.WebhooksFile != "" {
+		subscriptions, err := webhooks.LoadSubscriptions(cfg.WebhooksFile)
+		if err != nil {
+			logging.Error("Failed to load webhook subscriptions", "error", err)
+			os.Exit(1)
+		}
+		for _, subscription := range subscriptions {
+			if _, err := dispatcher.Add(subscription); err != nil {
+				logging.Error("Invalid webhook subscription", "subscription", subscription.ID, "error", err)
+				os.Exit(1)
+			}
+		}
+		logging.Info("Webhook subscriptions loaded", "count", len(subscriptions))
+		// Subscriptions managed with the admin API are saved to the same file
+		dispatcher.SetSubscriptionsFile(cfg.WebhooksFile)
 	}
-	parser := medicamentsparser.NewMedicamentsParser(httpClient)
 
 	// Initialize and start scheduler with dependency injection
 	sched := scheduler.NewScheduler(dataContainer, parser)
+	if !cfg.DisableSnapshot {
+		sched.SetSnapshotPath(cfg.SnapshotPath)
+	}
+	sched.AddChangeNotifier(dispatcher)
+
+	// Dashboards follow the updates on /v1/events
+	broker := events.NewBroker(cfg.EventsMaxClients)
+	sched.SetEventPublisher(broker)
 	if err := sched.Start(); err != nil {
 		logging.Error("Failed to start scheduler", "error", err)
 		os.Exit(1)
@@ -108,6 +159,8 @@ func main() {
 
 	// Initialize and start server
 	srv := server.NewServer(cfg, dataContainer)
+	srv.MountEvents(broker)
+	srv.MountWebhooksAdmin(dispatcher)
 
 	// Channel to listen for interrupt signals
 	quit := make(chan os.Signal, 1)
@@ -138,6 +191,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Stop webhook retries and wait for the deliveries in progress
+	dispatcher.Close()
+
 	logging.Info("Server shutdown complete")
 
 	// Ensure all logs are flushed before exit
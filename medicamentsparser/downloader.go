@@ -4,41 +4,143 @@ package medicamentsparser
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/giygas/medicaments-api/logging"
+	"github.com/giygas/medicaments-api/metrics"
 	"golang.org/x/text/encoding/charmap"
 )
 
-func downloadAndParseFile(path string, url string, client *http.Client) error {
+// sourcesStatePath keeps the state of the files in files/ between updates and restarts
+const sourcesStatePath = "files/sources.json"
 
-	path = "files/" + path + ".txt"
+// fileState describes a fetched file, to send conditional requests and detect changes
+type fileState struct {
+	Validators
+	Hash string `json:"hash"` // SHA-256 of the content as read from the source
+}
+
+// RetryPolicy sets how failed file fetches are retried
+type RetryPolicy struct {
+	Retries   int           // Attempts after the first one, 0 disables retries
+	BaseDelay time.Duration // Delay before the first retry, doubled at each retry
+}
+
+// DefaultRetryPolicy retries a failed file three times, after about 2, 4 and 8 seconds
+var DefaultRetryPolicy = RetryPolicy{Retries: 3, BaseDelay: 2 * time.Second}
+
+// delay returns the wait before a retry: the exponential delay with up to 50% jitter removed,
+// so that the files failing together are not requested again at the same time
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay - rand.N(delay/2+1)
+}
+
+// loadSourcesState reads the state saved by the previous fetch, keyed by BDPM file name.
+// A missing or unreadable state only means that every file is fetched again.
+func loadSourcesState() map[string]fileState {
+	state := make(map[string]fileState)
+
+	content, err := os.ReadFile(sourcesStatePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Warn("Failed to read the sources state", "path", sourcesStatePath, "error", err)
+		}
+		return state
+	}
+
+	if err := json.Unmarshal(content, &state); err != nil {
+		logging.Warn("Ignoring invalid sources state", "path", sourcesStatePath, "error", err)
+		return make(map[string]fileState)
+	}
+
+	return state
+}
+
+// saveSourcesState writes the state of the files in files/
+func saveSourcesState(state map[string]fileState) {
+	if len(state) == 0 {
+		// Nothing fetched, no conditional request to make next time
+		if err := os.Remove(sourcesStatePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logging.Warn("Failed to remove the sources state", "path", sourcesStatePath, "error", err)
+		}
+		return
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		logging.Warn("Failed to encode the sources state", "error", err)
+		return
+	}
+
+	if err := os.WriteFile(sourcesStatePath, content, 0600); err != nil {
+		logging.Warn("Failed to save the sources state", "path", sourcesStatePath, "error", err)
+	}
+}
+
+// fetchFile reads a BDPM file from the source and writes it to files/<name>.txt in UTF-8.
+// The previous state is used to skip files that did not change.
+func fetchFile(source Source, name string, fileName string, previous fileState) (fileState, error) {
+
+	path := "files/" + name + ".txt"
 	cleanPath := filepath.Clean(path)
 	if !strings.HasPrefix(cleanPath, "files/") {
-		return fmt.Errorf("invalid filepath: %s", path)
+		return fileState{}, fmt.Errorf("invalid filepath: %s", path)
 	}
 
-	response, err := client.Get(url)
+	// Without the local copy, the previous state describes nothing to keep
+	if _, err := os.Stat(cleanPath); err != nil {
+		previous = fileState{}
+	}
+
+	var body io.ReadCloser
+	var validators Validators
+	var err error
+	if conditional, ok := source.(ConditionalSource); ok {
+		body, validators, err = conditional.OpenIfModified(fileName, previous.Validators)
+		if errors.Is(err, ErrNotModified) {
+			logging.Debug(fmt.Sprintf("%s not modified, keeping %s", fileName, path))
+			return previous, nil
+		}
+	} else {
+		body, err = source.Open(fileName)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to download %s: %w", url, err)
+		return fileState{}, err
 	}
 	defer func() {
-		if err = response.Body.Close(); err != nil {
-			logging.Warn("Failed to close response body", "error", err)
+		if err = body.Close(); err != nil {
+			logging.Warn("Failed to close source file", "file", fileName, "error", err)
 		}
 	}()
 
 	// As there are some files in iso-8859-1 and some in utf8, read the content first
-	bodyBytes, err := io.ReadAll(response.Body)
+	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return fileState{}, &retryableError{fmt.Errorf("failed to read %s: %w", fileName, err)}
+	}
+
+	sum := sha256.Sum256(bodyBytes)
+	state := fileState{Validators: validators, Hash: hex.EncodeToString(sum[:])}
+	if state.Hash == previous.Hash {
+		logging.Debug(fmt.Sprintf("%s unchanged, keeping %s", fileName, path))
+		return state, nil
 	}
 
 	// Check if it's valid UTF-8
@@ -51,15 +153,12 @@ func downloadAndParseFile(path string, url string, client *http.Client) error {
 		reader = charmap.ISO8859_1.NewDecoder().Reader(bytes.NewReader(bodyBytes))
 	}
 
-	outFile, err := os.Create(cleanPath)
+	// Write next to the destination and rename, so that a failure keeps the last good copy
+	outFile, err := os.CreateTemp(filepath.Dir(cleanPath), name+".txt.tmp-*")
 	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", cleanPath, err)
+		return fileState{}, fmt.Errorf("failed to create file %s: %w", cleanPath, err)
 	}
-	defer func() {
-		if err = outFile.Close(); err != nil {
-			logging.Warn("Failed to close output file", "error", err)
-		}
-	}()
+	defer func() { _ = os.Remove(outFile.Name()) }() // No-op once renamed
 
 	scanner := bufio.NewScanner(reader)
 	scanner.Buffer(make([]byte, 0), 1*1024*1024)
@@ -68,61 +167,132 @@ func downloadAndParseFile(path string, url string, client *http.Client) error {
 		// #nosec G705 -- writing to file, not HTML output
 		_, err = io.WriteString(outFile, scanner.Text()+"\n")
 		if err != nil {
-			return fmt.Errorf("failed to write to file %s: %w", cleanPath, err)
+			_ = outFile.Close()
+			return fileState{}, fmt.Errorf("failed to write to file %s: %w", cleanPath, err)
 		}
 	}
 
 	// Check for scanner errors
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("scanner error in %s: %w", path, err)
+		_ = outFile.Close()
+		return fileState{}, fmt.Errorf("scanner error in %s: %w", path, err)
 	}
 
-	logging.Debug(fmt.Sprintf("%s downloaded and parsed without errors", path))
-	return nil
+	if err := outFile.Close(); err != nil {
+		return fileState{}, fmt.Errorf("failed to write to file %s: %w", cleanPath, err)
+	}
+	if err := os.Rename(outFile.Name(), cleanPath); err != nil {
+		return fileState{}, fmt.Errorf("failed to replace file %s: %w", cleanPath, err)
+	}
+
+	logging.Debug(fmt.Sprintf("%s fetched and parsed without errors", path))
+	return state, nil
 }
 
-// Download all files concurrently
-func downloadAndParseAll(client *http.Client) error {
+// fetchWithRetry fetches a file, retrying transient failures with exponential backoff and jitter
+func fetchWithRetry(source Source, name string, fileName string, previous fileState, policy RetryPolicy) (fileState, error) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		fetched, err := fetchFile(source, name, fileName, previous)
+		metrics.BDPMDownloadDuration.WithLabelValues(fileName).Observe(time.Since(start).Seconds())
 
-	//Files to download
-	var files = map[string]string{
-		"Specialites":   "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/CIS_bdpm.txt",
-		"Presentations": "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/CIS_CIP_bdpm.txt",
-		"Compositions":  "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/CIS_COMPO_bdpm.txt",
-		"Generiques":    "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/CIS_GENER_bdpm.txt",
-		"Conditions":    "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/CIS_CPD_bdpm.txt",
+		switch {
+		case err != nil:
+			metrics.BDPMDownloadAttempts.WithLabelValues(fileName, "error").Inc()
+		case previous.Hash != "" && fetched == previous:
+			metrics.BDPMDownloadAttempts.WithLabelValues(fileName, "not_modified").Inc()
+		default:
+			metrics.BDPMDownloadAttempts.WithLabelValues(fileName, "success").Inc()
+		}
+
+		if err == nil || attempt >= policy.Retries || !isRetryable(err) {
+			return fetched, err
+		}
+
+		delay := policy.delay(attempt)
+		logging.Warn("Failed to fetch BDPM file, retrying",
+			"file", fileName,
+			"attempt", attempt+1,
+			"retry_in", delay.String(),
+			"error", err,
+		)
+		time.Sleep(delay)
 	}
+}
+
+// Fetch all files concurrently, returning the content hash of each file by BDPM file name.
+// A file that cannot be fetched is served from its last good copy when there is one, and
+// reported in the stale list. The fetch fails if a file has no copy or if every file failed.
+func fetchAll(source Source, policy RetryPolicy) (map[string]string, []string, error) {
 
 	//Create the files directory if it doesn't exists
 	path := filepath.Join(".", "files")
 	err := os.MkdirAll(path, 0750)
 	if err != nil {
-		return fmt.Errorf("failed to create files directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to create files directory: %w", err)
 	}
 
+	state := loadSourcesState()
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
-	var errors []error
+	var fetchErrors []error
+	var stale []string
 
-	for fileName, url := range files {
+	for name, fileName := range bdpmFiles {
 		wg.Add(1)
 
-		go func(file string, url string) {
+		go func(name string, fileName string) {
 			defer wg.Done()
-			if err := downloadAndParseFile(file, url, client); err != nil {
-				mu.Lock()
-				errors = append(errors, err)
-				mu.Unlock()
+
+			mu.Lock()
+			previous := state[fileName]
+			mu.Unlock()
+
+			fetched, err := fetchWithRetry(source, name, fileName, previous, policy)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if _, statErr := os.Stat(filepath.Join("files", name+".txt")); previous.Hash != "" && statErr == nil {
+					logging.Warn("Using the last good copy of a BDPM file that failed to fetch", "file", fileName, "error", err)
+					stale = append(stale, fileName)
+					return
+				}
+				delete(state, fileName)
+				if supplementaryFiles[name] {
+					// Without a good copy, the file is left out of the data when parsed
+					logging.Warn("Skipping a supplementary BDPM file that failed to fetch", "file", fileName, "error", err)
+					if err := os.Remove(filepath.Join("files", name+".txt")); err != nil && !os.IsNotExist(err) {
+						logging.Warn("Failed to remove the copy of a skipped BDPM file", "file", fileName, "error", err)
+					}
+					return
+				}
+				fetchErrors = append(fetchErrors, err)
+				return
 			}
-		}(fileName, url)
+			state[fileName] = fetched
+		}(name, fileName)
 
 	}
 	wg.Wait()
 
-	if len(errors) > 0 {
-		logging.Error("Download errors occurred", "errors", errors)
-		return fmt.Errorf("download errors: %v", errors)
+	saveSourcesState(state)
+
+	if len(fetchErrors) > 0 {
+		logging.Error("Fetch errors occurred", "source", source.String(), "errors", fetchErrors)
+		return nil, nil, fmt.Errorf("fetch errors: %v", fetchErrors)
 	}
 
-	return nil
+	if len(stale) == len(bdpmFiles) {
+		logging.Error("No BDPM file could be fetched", "source", source.String())
+		return nil, nil, fmt.Errorf("no file could be fetched from %s", source.String())
+	}
+
+	slices.Sort(stale)
+	hashes := make(map[string]string, len(bdpmFiles))
+	for _, fileName := range bdpmFiles {
+		hashes[fileName] = state[fileName].Hash
+	}
+	return hashes, stale, nil
 }
@@ -0,0 +1,147 @@
+package medicamentsparser
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/giygas/medicaments-api/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// testRetryPolicy retries without slowing the tests down
+var testRetryPolicy = RetryPolicy{Retries: 3, BaseDelay: time.Millisecond}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Retries: 3, BaseDelay: 2 * time.Second}
+
+	for attempt, expected := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		for range 100 {
+			delay := policy.delay(attempt)
+			if delay < expected/2 || delay > expected {
+				t.Fatalf("Attempt %d: expected a delay between %v and %v, got %v", attempt, expected/2, expected, delay)
+			}
+		}
+	}
+
+	if delay := (RetryPolicy{}).delay(0); delay != 0 {
+		t.Errorf("Expected no delay without a base delay, got %v", delay)
+	}
+}
+
+func TestFetchAll_RetriesTransientFailures(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	server := newBDPMServer(t, testBDPMFiles(t))
+	server.fail("CIS_bdpm.txt", 2)
+
+	errorsBefore := testutil.ToFloat64(metrics.BDPMDownloadAttempts.WithLabelValues("CIS_bdpm.txt", "error"))
+	successBefore := testutil.ToFloat64(metrics.BDPMDownloadAttempts.WithLabelValues("CIS_bdpm.txt", "success"))
+
+	_, stale, err := fetchAll(server.source(), testRetryPolicy)
+	if err != nil {
+		t.Fatalf("Expected the retries to succeed, got %v", err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("Expected no stale file, got %v", stale)
+	}
+	if requests := server.requestCount("CIS_bdpm.txt"); requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	if delta := testutil.ToFloat64(metrics.BDPMDownloadAttempts.WithLabelValues("CIS_bdpm.txt", "error")) - errorsBefore; delta != 2 {
+		t.Errorf("Expected 2 failed attempts in the metrics, got %v", delta)
+	}
+	if delta := testutil.ToFloat64(metrics.BDPMDownloadAttempts.WithLabelValues("CIS_bdpm.txt", "success")) - successBefore; delta != 1 {
+		t.Errorf("Expected 1 successful attempt in the metrics, got %v", delta)
+	}
+}
+
+func TestFetchAll_PermanentFailureIsNotRetried(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	files := testBDPMFiles(t)
+	delete(files, "CIS_CPD_bdpm.txt")
+	server := newBDPMServer(t, files)
+
+	// No previous copy to fall back to
+	if _, _, err := fetchAll(server.source(), testRetryPolicy); err == nil {
+		t.Fatal("Expected an error for a missing file without previous copy")
+	}
+	if requests := server.requestCount("CIS_CPD_bdpm.txt"); requests != 1 {
+		t.Errorf("Expected a 404 not to be retried, got %d requests", requests)
+	}
+}
+
+func TestFetchAll_ReusesLastGoodCopy(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	server := newBDPMServer(t, testBDPMFiles(t))
+	if _, _, err := fetchAll(server.source(), testRetryPolicy); err != nil {
+		t.Fatalf("First fetch failed: %v", err)
+	}
+	previous, err := os.ReadFile("files/Presentations.txt")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	server.update("CIS_CIP_bdpm.txt", []byte("new content\n"))
+	server.fail("CIS_CIP_bdpm.txt", -1)
+
+	_, stale, err := fetchAll(server.source(), testRetryPolicy)
+	if err != nil {
+		t.Fatalf("Expected the last good copy to be used, got %v", err)
+	}
+	if !slices.Equal(stale, []string{"CIS_CIP_bdpm.txt"}) {
+		t.Errorf("Expected CIS_CIP_bdpm.txt to be stale, got %v", stale)
+	}
+	if requests := server.requestCount("CIS_CIP_bdpm.txt"); requests != 2+testRetryPolicy.Retries {
+		t.Errorf("Expected %d requests, got %d", 2+testRetryPolicy.Retries, requests)
+	}
+
+	content, err := os.ReadFile("files/Presentations.txt")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(content) != string(previous) {
+		t.Errorf("Expected the last good copy to be kept, got %q", content)
+	}
+
+	// Once every file fails there is nothing fresh to parse
+	for fileName := range server.files {
+		server.fail(fileName, -1)
+	}
+	if _, _, err := fetchAll(server.source(), RetryPolicy{}); err == nil {
+		t.Error("Expected an error when no file could be fetched")
+	}
+}
+
+func TestMedicamentsParser_StaleSources(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	server := newBDPMServer(t, testBDPMFiles(t))
+	parser := NewMedicamentsParserFromSource(server.source())
+	parser.SetRetryPolicy(testRetryPolicy)
+
+	if _, _, _, err := parser.ParseAllMedicaments(); err != nil {
+		t.Fatalf("First parse failed: %v", err)
+	}
+	if stale := parser.StaleSources(); len(stale) != 0 {
+		t.Errorf("Expected no stale source, got %v", stale)
+	}
+
+	// The sources did not change but one of them could not be checked: the data
+	// is parsed again so that the quality report flags the stale file
+	server.fail("CIS_GENER_bdpm.txt", -1)
+	medicaments, _, _, err := parser.ParseAllMedicaments()
+	if err != nil {
+		t.Fatalf("Expected a parse from the last good copy, got %v", err)
+	}
+	if len(medicaments) != 1 {
+		t.Errorf("Expected 1 medicament, got %d", len(medicaments))
+	}
+	if stale := parser.StaleSources(); !slices.Equal(stale, []string{"CIS_GENER_bdpm.txt"}) {
+		t.Errorf("Expected CIS_GENER_bdpm.txt to be stale, got %v", stale)
+	}
+}
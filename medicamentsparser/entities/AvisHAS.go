@@ -0,0 +1,19 @@
+package entities
+
+// AvisHAS represents an opinion of the HAS Commission de la transparence (SMR or ASMR)
+// attached to a medicament. DateAvis is kept as published by the BDPM (AAAAMMJJ).
+type AvisHAS struct {
+	Cis             int    `json:"cis"`
+	CodeDossierHAS  string `json:"codeDossierHAS"`
+	MotifEvaluation string `json:"motifEvaluation"`
+	DateAvis        string `json:"dateAvis"`
+	Valeur          string `json:"valeur"`
+	Libelle         string `json:"libelle"`
+	LienAvisCT      string `json:"lienAvisCT"`
+}
+
+// LienPageCT links a HAS dossier code to the Commission de la transparence opinion page
+type LienPageCT struct {
+	CodeDossierHAS string `json:"codeDossierHAS"`
+	Lien           string `json:"lien"`
+}
@@ -0,0 +1,22 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// dateLayouts are the formats of the BDPM dates: French in the files, ISO in some exports and API filters
+var dateLayouts = []string{"02/01/2006", "2006-01-02"}
+
+// ParseDate reads a BDPM date (DateAMM, DateDebut, DateFin) as midnight UTC, so that sorting,
+// filters and validity periods compare the same instants whatever the server time zone.
+// It returns false for missing or invalid dates.
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
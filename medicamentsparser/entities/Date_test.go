@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+		valid    bool
+	}{
+		{"20/10/2008", time.Date(2008, 10, 20, 0, 0, 0, 0, time.UTC), true},
+		{"2008-10-20", time.Date(2008, 10, 20, 0, 0, 0, 0, time.UTC), true},
+		{" 20/10/2008 ", time.Date(2008, 10, 20, 0, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"31/02/2008", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.value)
+		if ok != tt.valid || !got.Equal(tt.expected) || (ok && got.Location() != time.UTC) {
+			t.Errorf("ParseDate(%q) = %v, %v, expected %v, %v", tt.value, got, ok, tt.expected, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package entities
+
+// Disponibilite represents a stock shortage or supply tension reported by the ANSM.
+// Cip13 is 0 when the status applies to every presentation of the CIS.
+type Disponibilite struct {
+	Cis                   int    `json:"cis"`
+	Cip13                 int    `json:"cip13"`
+	CodeStatut            int    `json:"codeStatut"`
+	Statut                string `json:"statut"`
+	DateDebut             string `json:"dateDebut"`
+	DateMiseAJour         string `json:"dateMiseAJour"`
+	DateRemiseDisposition string `json:"dateRemiseDisposition"`
+	LienANSM              string `json:"lienANSM"`
+}
@@ -5,7 +5,7 @@ package entities
 type GeneriqueList struct {
 	GroupID           int                   `json:"groupID"`
 	Libelle           string                `json:"libelle"`
-	LibelleNormalized string                `json:"-"` // Pre-computed: ToLower() + ReplaceAll("+", " ")
+	LibelleNormalized string                `json:"-"` // Pre-computed: search.Normalize()
 	Medicaments       []GeneriqueMedicament `json:"medicaments"`
 	OrphanCIS         []int                 `json:"orphanCIS"`
 }
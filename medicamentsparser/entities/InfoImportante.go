@@ -0,0 +1,11 @@
+package entities
+
+// InfoImportante represents a safety communication published for a medicament.
+// DateFin is empty when the notice has no end date.
+type InfoImportante struct {
+	Cis       int    `json:"cis"`
+	DateDebut string `json:"dateDebut"`
+	DateFin   string `json:"dateFin"`
+	Texte     string `json:"texte"`
+	Lien      string `json:"lien"`
+}
@@ -0,0 +1,9 @@
+package entities
+
+// MITM represents a medicament of major therapeutic interest (médicament d'intérêt thérapeutique majeur)
+type MITM struct {
+	Cis          int    `json:"cis"`
+	CodeATC      string `json:"codeATC"`
+	Denomination string `json:"elementPharmaceutique"`
+	Lien         string `json:"lien"`
+}
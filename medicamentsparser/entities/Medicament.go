@@ -1,19 +1,24 @@
 package entities
 
 type Medicament struct {
-	Cis                    int            `json:"cis"`
-	Denomination           string         `json:"elementPharmaceutique"`
-	DenominationNormalized string         `json:"-"` // Pre-computed: ToLower() + ReplaceAll("+", " ")
-	FormePharmaceutique    string         `json:"formePharmaceutique"`
-	VoiesAdministration    []string       `json:"voiesAdministration"`
-	StatusAutorisation     string         `json:"statusAutorisation"`
-	TypeProcedure          string         `json:"typeProcedure"`
-	EtatComercialisation   string         `json:"etatComercialisation"`
-	DateAMM                string         `json:"dateAMM"`
-	Titulaire              string         `json:"titulaire"`
-	SurveillanceRenforcee  string         `json:"surveillanceRenforcee"`
-	Composition            []Composition  `json:"composition"`
-	Generiques             []Generique    `json:"generiques"`
-	Presentation           []Presentation `json:"presentation"`
-	Conditions             []string       `json:"conditions"`
+	Cis                    int              `json:"cis"`
+	Denomination           string           `json:"elementPharmaceutique"`
+	DenominationNormalized string           `json:"-"` // Pre-computed: search.Normalize()
+	FormePharmaceutique    string           `json:"formePharmaceutique"`
+	VoiesAdministration    []string         `json:"voiesAdministration"`
+	StatusAutorisation     string           `json:"statusAutorisation"`
+	TypeProcedure          string           `json:"typeProcedure"`
+	EtatComercialisation   string           `json:"etatComercialisation"`
+	DateAMM                string           `json:"dateAMM"`
+	Titulaire              string           `json:"titulaire"`
+	SurveillanceRenforcee  string           `json:"surveillanceRenforcee"`
+	Composition            []Composition    `json:"composition"`
+	Generiques             []Generique      `json:"generiques"`
+	Presentation           []Presentation   `json:"presentation"`
+	Conditions             []string         `json:"conditions"`
+	SMR                    []AvisHAS        `json:"smr"`
+	ASMR                   []AvisHAS        `json:"asmr"`
+	InfosImportantes       []InfoImportante `json:"infosImportantes"`
+	Mitm                   bool             `json:"mitm"`
+	CodeATC                string           `json:"codeATC"`
 }
@@ -0,0 +1,9 @@
+package entities
+
+// Substance is an active substance found in the medicament compositions.
+// The same code can be spelled differently across compositions, Denomination is the most common spelling.
+type Substance struct {
+	Code             int    `json:"codeSubstance"`
+	Denomination     string `json:"denominationSubstance"`
+	MedicamentsCount int    `json:"medicamentsCount"`
+}
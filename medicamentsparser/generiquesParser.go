@@ -9,6 +9,7 @@ import (
 
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // readGeneriquesFromTSV reads generiques data directly from TSV file
@@ -65,7 +66,7 @@ func readGeneriquesFromTSV() (map[string][]int, error) {
 func GeneriquesParser(medicaments *[]entities.Medicament, mMap *map[int]entities.Medicament) ([]entities.GeneriqueList, map[int]entities.GeneriqueList, error) {
 
 	// allGeneriques: []Generique
-	allGeneriques, err := makeGeneriques(nil)
+	allGeneriques, err := makeGeneriques()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to parse generiques: %w", err)
 	}
@@ -108,7 +109,7 @@ func GeneriquesParser(medicaments *[]entities.Medicament, mMap *map[int]entities
 		currentGenerique := entities.GeneriqueList{
 			GroupID:           groupInt,
 			Libelle:           libelle[groupInt],
-			LibelleNormalized: strings.ReplaceAll(strings.ToLower(libelle[groupInt]), "+", " "),
+			LibelleNormalized: search.Normalize(libelle[groupInt]),
 			Medicaments:       medicaments,
 			OrphanCIS:         orphaned,
 		}
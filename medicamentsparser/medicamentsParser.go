@@ -4,11 +4,11 @@ package medicamentsparser
 import (
 	"fmt"
 	"net/http"
-	"strings"
-	"sync"
+	"slices"
 
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 	"github.com/giygas/medicaments-api/validation"
 )
 
@@ -26,126 +26,71 @@ func validateMedicamentsIntegrity(m *entities.Medicament) error {
 	return nil
 }
 
+// ParseAllMedicaments downloads the BDPM files and parses them
 func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]entities.Presentation, map[int]entities.Presentation, error) {
+	return ParseAllMedicamentsFromSource(NewHTTPSource(client))
+}
 
-	// Download the neccesary files from https://base-donnees-publique.medicaments.gouv.fr/telechargement
-	if err := downloadAndParseAll(client); err != nil {
-		logging.Error("Failed to download and parse files", "error", err)
-		return nil, nil, nil, fmt.Errorf("failed to download files: %w", err)
-	}
-
-	//Make all the json files concurrently
-	var wg sync.WaitGroup
-	wg.Add(5)
-
-	conditionsChan := make(chan []entities.Condition)
-	presentationsChan := make(chan []entities.Presentation)
-	specialitesChan := make(chan []entities.Specialite)
-	generiquesChan := make(chan []entities.Generique)
-	compositionsChan := make(chan []entities.Composition)
-	errorChan := make(chan error, 5)
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logging.Error("Panic recovered in conditions goroutine", "panic", r)
-				errorChan <- fmt.Errorf("panic in conditions: %v", r)
-			}
-		}()
-		result, err := makeConditions(&wg)
-		if err != nil {
-			logging.Error("Failed to parse conditions", "error", err)
-			errorChan <- err
-			return
-		}
-		conditionsChan <- result
-	}()
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logging.Error("Panic recovered in presentations goroutine", "panic", r)
-				errorChan <- fmt.Errorf("panic in presentations: %v", r)
-			}
-		}()
-		result, err := makePresentations(&wg)
-		if err != nil {
-			logging.Error("Failed to parse presentations", "error", err)
-			errorChan <- err
-			return
-		}
-		presentationsChan <- result
-	}()
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logging.Error("Panic recovered in specialites goroutine", "panic", r)
-				errorChan <- fmt.Errorf("panic in specialites: %v", r)
-			}
-		}()
-		result, err := makeSpecialites(&wg)
-		if err != nil {
-			logging.Error("Failed to parse specialites", "error", err)
-			errorChan <- err
-			return
-		}
-		specialitesChan <- result
-	}()
+// ParseAllMedicamentsFromSource reads the BDPM files from a source and parses them
+func ParseAllMedicamentsFromSource(source Source) ([]entities.Medicament, map[int]entities.Presentation, map[int]entities.Presentation, error) {
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logging.Error("Panic recovered in generiques goroutine", "panic", r)
-				errorChan <- fmt.Errorf("panic in generiques: %v", r)
-			}
-		}()
-		result, err := makeGeneriques(&wg)
-		if err != nil {
-			logging.Error("Failed to parse generiques", "error", err)
-			errorChan <- err
-			return
-		}
-		generiquesChan <- result
-	}()
+	// Fetch the neccesary files, by default from https://base-donnees-publique.medicaments.gouv.fr/telechargement
+	if _, _, err := fetchAll(source, DefaultRetryPolicy); err != nil {
+		logging.Error("Failed to fetch and parse files", "source", source.String(), "error", err)
+		return nil, nil, nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logging.Error("Panic recovered in compositions goroutine", "panic", r)
-				errorChan <- fmt.Errorf("panic in compositions: %v", r)
-			}
-		}()
-		result, err := makeCompositions(&wg)
-		if err != nil {
-			logging.Error("Failed to parse compositions", "error", err)
-			errorChan <- err
-			return
-		}
-		compositionsChan <- result
-	}()
+	medicaments, presentationsCIP7Map, presentationsCIP13Map, _, err := parseFiles()
+	return medicaments, presentationsCIP7Map, presentationsCIP13Map, err
+}
 
-	wg.Wait()
+// parseFiles parses the files fetched to files/. It also returns the BDPM names of the
+// supplementary files that could not be read: their data is left out instead of failing the load.
+func parseFiles() ([]entities.Medicament, map[int]entities.Presentation, map[int]entities.Presentation, []string, error) {
 
-	// Check for any errors that occurred during concurrent processing
-	select {
-	case err := <-errorChan:
-		return nil, nil, nil, fmt.Errorf("error during data parsing: %w", err)
-	default:
-		// No errors, continue processing
+	//Make all the json files concurrently
+	conditionsChan := parseAsync("Conditions", makeConditions)
+	presentationsChan := parseAsync("Presentations", makePresentations)
+	specialitesChan := parseAsync("Specialites", makeSpecialites)
+	generiquesChan := parseAsync("Generiques", makeGeneriques)
+	compositionsChan := parseAsync("Compositions", makeCompositions)
+	avisSMRChan := parseAsync("AvisSMR", makeAvisSMR)
+	avisASMRChan := parseAsync("AvisASMR", makeAvisASMR)
+	liensCTChan := parseAsync("LiensCT", makeLiensCT)
+	disponibilitesChan := parseAsync("Disponibilites", makeDisponibilites)
+	infosImportantesChan := parseAsync("InfosImportantes", makeInfosImportantes)
+	mitmChan := parseAsync("MITM", makeMITM)
+
+	// The core files are required, the medicaments cannot be built without them
+	conditions, err := required(conditionsChan)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	presentations, err := required(presentationsChan)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	specialites, err := required(specialitesChan)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	generiques, err := required(generiquesChan)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	compositions, err := required(compositionsChan)
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
-	conditions := <-conditionsChan
-	presentations := <-presentationsChan
-	specialites := <-specialitesChan
-	generiques := <-generiquesChan
-	compositions := <-compositionsChan
-
-	conditionsChan = nil
-	presentationsChan = nil
-	specialitesChan = nil
-	generiquesChan = nil
-	compositionsChan = nil
+	var skipped []string
+	avisSMR := optional(avisSMRChan, &skipped)
+	avisASMR := optional(avisASMRChan, &skipped)
+	liensCT := optional(liensCTChan, &skipped)
+	disponibilites := optional(disponibilitesChan, &skipped)
+	infosImportantes := optional(infosImportantesChan, &skipped)
+	mitm := optional(mitmChan, &skipped)
+	slices.Sort(skipped)
 
 	// Make lookup maps (this is s O(n) task, but it makes possible searching as O(1))
 	compositionsMap := make(map[int][]entities.Composition)
@@ -164,6 +109,9 @@ func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]en
 		logging.Warn("Duplicate CIP values detected, last occurrence will be used", "error", err)
 	}
 
+	// Attach the availability status before the presentations are copied into the lookup maps
+	attachDisponibilites(presentations, disponibilites)
+
 	presentationsMap := make(map[int][]entities.Presentation)
 	presentationsCIP7Map := make(map[int]entities.Presentation)
 	presentationsCIP13Map := make(map[int]entities.Presentation)
@@ -178,6 +126,25 @@ func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]en
 		conditionsMap[cond.Cis] = append(conditionsMap[cond.Cis], cond.Condition)
 	}
 
+	mitmMap := make(map[int]entities.MITM, len(mitm))
+	for _, m := range mitm {
+		mitmMap[m.Cis] = m
+	}
+
+	infosImportantesMap := make(map[int][]entities.InfoImportante)
+	for _, info := range infosImportantes {
+		infosImportantesMap[info.Cis] = append(infosImportantesMap[info.Cis], info)
+	}
+
+	// The CT opinion link is published per HAS dossier, shared by the SMR and ASMR files
+	liensCTMap := make(map[string]string, len(liensCT))
+	for _, lien := range liensCT {
+		liensCTMap[lien.CodeDossierHAS] = lien.Lien
+	}
+
+	smrMap := makeAvisHASMap(avisSMR, liensCTMap)
+	asmrMap := makeAvisHASMap(avisASMR, liensCTMap)
+
 	medicamentsSlice := make([]entities.Medicament, 0, len(specialites))
 
 	for _, med := range specialites {
@@ -186,7 +153,7 @@ func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]en
 
 		medicament.Cis = med.Cis
 		medicament.Denomination = med.Denomination
-		medicament.DenominationNormalized = strings.ReplaceAll(strings.ToLower(med.Denomination), "+", " ")
+		medicament.DenominationNormalized = search.Normalize(med.Denomination)
 		medicament.FormePharmaceutique = med.FormePharmaceutique
 		medicament.VoiesAdministration = med.VoiesAdministration
 		medicament.StatusAutorisation = med.StatusAutorisation
@@ -217,6 +184,25 @@ func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]en
 			medicament.Conditions = cond
 		}
 
+		// Get the HAS evaluations of this medicament
+		if smr, exists := smrMap[med.Cis]; exists {
+			medicament.SMR = smr
+		}
+		if asmr, exists := asmrMap[med.Cis]; exists {
+			medicament.ASMR = asmr
+		}
+
+		// Get the safety information of this medicament
+		if infos, exists := infosImportantesMap[med.Cis]; exists {
+			medicament.InfosImportantes = infos
+		}
+
+		// Flag the medicaments of major therapeutic interest
+		if m, exists := mitmMap[med.Cis]; exists {
+			medicament.Mitm = true
+			medicament.CodeATC = m.CodeATC
+		}
+
 		// Validate the medicament structure
 		if err := validateMedicamentsIntegrity(medicament); err != nil {
 			logging.Warn("Skipping invalid medicament: ", "error", err, "cis", med.Cis)
@@ -230,5 +216,87 @@ func ParseAllMedicaments(client *http.Client) ([]entities.Medicament, map[int]en
 	logging.Info("All medicaments parsed successfully",
 		"medicaments_parsed", len(medicamentsSlice))
 
-	return medicamentsSlice, presentationsCIP7Map, presentationsCIP13Map, nil
+	return medicamentsSlice, presentationsCIP7Map, presentationsCIP13Map, skipped, nil
+}
+
+// parsedFile is the outcome of parsing one file of files/
+type parsedFile[T any] struct {
+	name    string
+	records []T
+	err     error
+}
+
+// parseAsync parses a file in its own goroutine. The outcome is sent once on the returned
+// channel, which is buffered so that the goroutine never blocks; a panic is returned as an error.
+func parseAsync[T any](name string, parse func() ([]T, error)) <-chan parsedFile[T] {
+	result := make(chan parsedFile[T], 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Error("Panic recovered while parsing a file", "file", name, "panic", r)
+				result <- parsedFile[T]{name: name, err: fmt.Errorf("panic in %s: %v", name, r)}
+			}
+		}()
+
+		records, err := parse()
+		result <- parsedFile[T]{name: name, records: records, err: err}
+	}()
+
+	return result
+}
+
+// required waits for a core file and fails the load if it could not be parsed
+func required[T any](result <-chan parsedFile[T]) ([]T, error) {
+	parsed := <-result
+	if parsed.err != nil {
+		logging.Error("Failed to parse a core file", "file", parsed.name, "error", parsed.err)
+		return nil, fmt.Errorf("error during data parsing: %w", parsed.err)
+	}
+	return parsed.records, nil
+}
+
+// optional waits for a supplementary file. One that could not be parsed is left out of the
+// data and its BDPM name added to skipped, for the quality report.
+func optional[T any](result <-chan parsedFile[T], skipped *[]string) []T {
+	parsed := <-result
+	if parsed.err != nil {
+		logging.Warn("Skipping a supplementary file that could not be parsed", "file", bdpmFiles[parsed.name], "error", parsed.err)
+		*skipped = append(*skipped, bdpmFiles[parsed.name])
+		return nil
+	}
+	return parsed.records
+}
+
+// makeAvisHASMap groups HAS evaluations by CIS and fills in the CT opinion link of each dossier
+func makeAvisHASMap(avis []entities.AvisHAS, liensCTMap map[string]string) map[int][]entities.AvisHAS {
+	avisMap := make(map[int][]entities.AvisHAS)
+	for _, a := range avis {
+		a.LienAvisCT = liensCTMap[a.CodeDossierHAS]
+		avisMap[a.Cis] = append(avisMap[a.Cis], a)
+	}
+	return avisMap
+}
+
+// attachDisponibilites links each presentation to its reported shortage, if any.
+// A status declared for a CIP13 takes precedence over one declared for the whole CIS.
+func attachDisponibilites(presentations []entities.Presentation, disponibilites []entities.Disponibilite) {
+	byCIP13 := make(map[int]*entities.Disponibilite)
+	byCIS := make(map[int]*entities.Disponibilite)
+	for i := range disponibilites {
+		dispo := &disponibilites[i]
+		if dispo.Cip13 != 0 {
+			byCIP13[dispo.Cip13] = dispo
+		} else {
+			byCIS[dispo.Cis] = dispo
+		}
+	}
+
+	for i := range presentations {
+		if dispo, exists := byCIP13[presentations[i].Cip13]; exists {
+			presentations[i].Disponibilite = dispo
+		} else if dispo, exists := byCIS[presentations[i].Cis]; exists {
+			presentations[i].Disponibilite = dispo
+		}
+	}
 }
@@ -532,7 +532,7 @@ func TestTSVPresentationsEdgeCases(t *testing.T) {
 			}
 
 			// Parse the file
-			result, err := makePresentations(nil)
+			result, err := makePresentations()
 			if err != nil {
 				t.Errorf("makePresentations failed: %v", err)
 				return
@@ -635,7 +635,7 @@ func TestTSVGeneriquesEdgeCases(t *testing.T) {
 			}
 
 			// Parse the file
-			result, err := makeGeneriques(nil)
+			result, err := makeGeneriques()
 			if err != nil {
 				t.Errorf("makeGeneriques failed: %v", err)
 				return
@@ -738,7 +738,7 @@ func TestTSVCompositionsEdgeCases(t *testing.T) {
 			}
 
 			// Parse the file
-			result, err := makeCompositions(nil)
+			result, err := makeCompositions()
 			if err != nil {
 				t.Errorf("makeCompositions failed: %v", err)
 				return
@@ -834,7 +834,7 @@ func TestTSVSpecialitesEdgeCases(t *testing.T) {
 			}
 
 			// Parse the file
-			result, err := makeSpecialites(nil)
+			result, err := makeSpecialites()
 			if err != nil {
 				t.Errorf("makeSpecialites failed: %v", err)
 				return
@@ -937,7 +937,7 @@ func TestTSVConditionsEdgeCases(t *testing.T) {
 			}
 
 			// Parse the file
-			result, err := makeConditions(nil)
+			result, err := makeConditions()
 			if err != nil {
 				t.Errorf("makeConditions failed: %v", err)
 				return
@@ -961,3 +961,368 @@ func TestTSVConditionsEdgeCases(t *testing.T) {
 
 	fmt.Println("TestTSVConditionsEdgeCases completed")
 }
+
+// TestTSVAvisHASEdgeCases tests edge cases for AvisSMR.txt, AvisASMR.txt and LiensCT.txt parsing
+func TestTSVAvisHASEdgeCases(t *testing.T) {
+	fmt.Println("Starting TestTSVAvisHASEdgeCases")
+
+	// Save original working directory
+	originalWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalWd) }()
+
+	// Create temp directory for test files
+	tempDir, err := os.MkdirTemp("", "avis-has-edge-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	_ = os.Chdir(tempDir)
+	_ = os.MkdirAll("files", 0755)
+
+	testCases := []struct {
+		name          string
+		content       string
+		expectRecords int
+		description   string
+	}{
+		{
+			name:          "Valid data",
+			content:       "61266250\tCT-10234\tInscription (CT)\t20110223\tImportant\tLe service médical rendu est important.\n",
+			expectRecords: 1,
+			description:   "Normal valid evaluation record",
+		},
+		{
+			name:          "Empty line in middle",
+			content:       "61266250\tCT-10234\tInscription (CT)\t20110223\tImportant\tSMR important\n\n60002283\tCT-5531\tRenouvellement d'inscription (CT)\t20160420\tModéré\tSMR modéré\n",
+			expectRecords: 2,
+			description:   "Empty line between valid records should be skipped",
+		},
+		{
+			name:          "Missing columns (5 instead of 6)",
+			content:       "61266250\tCT-10234\tInscription (CT)\t20110223\tImportant\n",
+			expectRecords: 0,
+			description:   "Line with only 5 columns should be skipped",
+		},
+		{
+			name:          "Invalid CIS (non-numeric)",
+			content:       "abc123\tCT-10234\tInscription (CT)\t20110223\tImportant\tSMR important\n",
+			expectRecords: 0,
+			description:   "Non-numeric CIS should cause format error skip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// SMR and ASMR files share the same layout
+			for _, file := range []string{"AvisSMR", "AvisASMR"} {
+				if err := os.WriteFile("files/"+file+".txt", []byte(tc.content), 0644); err != nil {
+					t.Fatalf("Failed to write test file: %v", err)
+				}
+			}
+
+			smr, err := makeAvisSMR()
+			if err != nil {
+				t.Fatalf("makeAvisSMR failed: %v", err)
+			}
+			asmr, err := makeAvisASMR()
+			if err != nil {
+				t.Fatalf("makeAvisASMR failed: %v", err)
+			}
+
+			if len(smr) != tc.expectRecords || len(asmr) != tc.expectRecords {
+				t.Errorf("Expected %d records, got %d SMR and %d ASMR. Description: %s",
+					tc.expectRecords, len(smr), len(asmr), tc.description)
+			}
+		})
+	}
+
+	t.Run("Liens CT", func(t *testing.T) {
+		content := "CT-10234\thttps://www.has-sante.fr/jcms/c_1036201\n\nCT-5531\n\thttps://www.has-sante.fr/orphan\n"
+		if err := os.WriteFile("files/LiensCT.txt", []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		liens, err := makeLiensCT()
+		if err != nil {
+			t.Fatalf("makeLiensCT failed: %v", err)
+		}
+
+		if len(liens) != 1 {
+			t.Fatalf("Expected 1 lien, got %d", len(liens))
+		}
+		if liens[0].CodeDossierHAS != "CT-10234" || liens[0].Lien != "https://www.has-sante.fr/jcms/c_1036201" {
+			t.Errorf("Unexpected lien: %+v", liens[0])
+		}
+	})
+
+	fmt.Println("TestTSVAvisHASEdgeCases completed")
+}
+
+// TestMakeAvisHASMap tests grouping of HAS evaluations by CIS with their CT links
+func TestMakeAvisHASMap(t *testing.T) {
+	avis := []entities.AvisHAS{
+		{Cis: 1, CodeDossierHAS: "CT-1", Valeur: "Important"},
+		{Cis: 1, CodeDossierHAS: "CT-2", Valeur: "Modéré"},
+		{Cis: 2, CodeDossierHAS: "CT-3", Valeur: "Insuffisant"},
+	}
+	liens := map[string]string{"CT-1": "https://www.has-sante.fr/ct1", "CT-3": "https://www.has-sante.fr/ct3"}
+
+	avisMap := makeAvisHASMap(avis, liens)
+
+	if len(avisMap[1]) != 2 {
+		t.Fatalf("Expected 2 evaluations for CIS 1, got %d", len(avisMap[1]))
+	}
+	if avisMap[1][0].LienAvisCT != "https://www.has-sante.fr/ct1" {
+		t.Errorf("Expected CT link for CT-1, got %q", avisMap[1][0].LienAvisCT)
+	}
+	if avisMap[1][1].LienAvisCT != "" {
+		t.Errorf("Expected empty CT link for CT-2, got %q", avisMap[1][1].LienAvisCT)
+	}
+	if len(avisMap[2]) != 1 || avisMap[2][0].LienAvisCT != "https://www.has-sante.fr/ct3" {
+		t.Errorf("Unexpected evaluations for CIS 2: %+v", avisMap[2])
+	}
+}
+
+// TestTSVDisponibilitesEdgeCases tests edge cases for Disponibilites.txt parsing
+func TestTSVDisponibilitesEdgeCases(t *testing.T) {
+	// Save original working directory
+	originalWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalWd) }()
+
+	// Create temp directory for test files
+	tempDir, err := os.MkdirTemp("", "disponibilites-edge-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	_ = os.Chdir(tempDir)
+	_ = os.MkdirAll("files", 0755)
+
+	testCases := []struct {
+		name          string
+		content       string
+		expectRecords int
+		description   string
+	}{
+		{
+			name:          "Valid CIP13 data",
+			content:       "60002283\t3400949497294\t1\tRupture de stock\t02/01/2026\t05/01/2026\t\thttps://ansm.sante.fr/disponibilites\n",
+			expectRecords: 1,
+			description:   "Normal valid shortage record",
+		},
+		{
+			name:          "CIS-level status (empty CIP13)",
+			content:       "61266250\t\t2\tTension d'approvisionnement\t02/01/2026\t05/01/2026\t\thttps://ansm.sante.fr/disponibilites\n",
+			expectRecords: 1,
+			description:   "Empty CIP13 applies to the whole CIS",
+		},
+		{
+			name:          "Missing columns (7 instead of 8)",
+			content:       "60002283\t3400949497294\t1\tRupture de stock\t02/01/2026\t05/01/2026\t\n",
+			expectRecords: 0,
+			description:   "Line with only 7 columns should be skipped",
+		},
+		{
+			name:          "Invalid CIP13 (non-numeric)",
+			content:       "60002283\tabc\t1\tRupture de stock\t02/01/2026\t05/01/2026\t\thttps://ansm.sante.fr\n",
+			expectRecords: 0,
+			description:   "Non-numeric CIP13 should cause format error skip",
+		},
+		{
+			name:          "Invalid status code",
+			content:       "60002283\t3400949497294\tx\tRupture de stock\t02/01/2026\t05/01/2026\t\thttps://ansm.sante.fr\n",
+			expectRecords: 0,
+			description:   "Non-numeric status code should cause format error skip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile("files/Disponibilites.txt", []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			result, err := makeDisponibilites()
+			if err != nil {
+				t.Fatalf("makeDisponibilites failed: %v", err)
+			}
+
+			if len(result) != tc.expectRecords {
+				t.Errorf("Expected %d records, got %d. Description: %s", tc.expectRecords, len(result), tc.description)
+			}
+		})
+	}
+}
+
+// TestTSVInfosImportantesEdgeCases tests edge cases in the safety information TSV parsing
+func TestTSVInfosImportantesEdgeCases(t *testing.T) {
+	// Save original working directory
+	originalWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalWd) }()
+
+	// Create temp directory for test files
+	tempDir, err := os.MkdirTemp("", "infos-importantes-edge-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	_ = os.Chdir(tempDir)
+	_ = os.MkdirAll("files", 0755)
+
+	testCases := []struct {
+		name          string
+		content       string
+		expectRecords int
+		expectTexte   string
+		expectLien    string
+		description   string
+	}{
+		{
+			name:          "HTML anchor",
+			content:       "61266250\t2024-01-15\t2027-01-15\t<a target='_blank' href='https://ansm.sante.fr/informations-de-securite/valproate'>Valproate et grossesse &amp; contraception</a>\n",
+			expectRecords: 1,
+			expectTexte:   "Valproate et grossesse & contraception",
+			expectLien:    "https://ansm.sante.fr/informations-de-securite/valproate",
+			description:   "Anchor is split into text and link",
+		},
+		{
+			name:          "Plain text without end date",
+			content:       "61266250\t2024-01-15\t\tInformation de sécurité\n",
+			expectRecords: 1,
+			expectTexte:   "Information de sécurité",
+			expectLien:    "",
+			description:   "Plain text keeps an empty link",
+		},
+		{
+			name:          "Missing columns (3 instead of 4)",
+			content:       "61266250\t2024-01-15\t2027-01-15\n",
+			expectRecords: 0,
+			description:   "Line with only 3 columns should be skipped",
+		},
+		{
+			name:          "Invalid CIS",
+			content:       "abc\t2024-01-15\t2027-01-15\tTexte\n",
+			expectRecords: 0,
+			description:   "Non-numeric CIS should cause format error skip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile("files/InfosImportantes.txt", []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			result, err := makeInfosImportantes()
+			if err != nil {
+				t.Fatalf("makeInfosImportantes failed: %v", err)
+			}
+
+			if len(result) != tc.expectRecords {
+				t.Fatalf("Expected %d records, got %d. Description: %s", tc.expectRecords, len(result), tc.description)
+			}
+			if tc.expectRecords == 0 {
+				return
+			}
+			if result[0].Texte != tc.expectTexte {
+				t.Errorf("Expected texte %q, got %q", tc.expectTexte, result[0].Texte)
+			}
+			if result[0].Lien != tc.expectLien {
+				t.Errorf("Expected lien %q, got %q", tc.expectLien, result[0].Lien)
+			}
+		})
+	}
+}
+
+// TestTSVMITMEdgeCases tests edge cases in the MITM TSV parsing
+func TestTSVMITMEdgeCases(t *testing.T) {
+	// Save original working directory
+	originalWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalWd) }()
+
+	// Create temp directory for test files
+	tempDir, err := os.MkdirTemp("", "mitm-edge-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	_ = os.Chdir(tempDir)
+	_ = os.MkdirAll("files", 0755)
+
+	testCases := []struct {
+		name          string
+		content       string
+		expectRecords int
+		expectATC     string
+		description   string
+	}{
+		{
+			name:          "Valid data",
+			content:       "60002283\tJ01CA04\tAMOXICILLINE 500 mg, gélule\thttps://base-donnees-publique.medicaments.gouv.fr/extrait.php?specid=60002283\n",
+			expectRecords: 1,
+			expectATC:     "J01CA04",
+			description:   "Normal valid MITM record",
+		},
+		{
+			name:          "Missing columns (3 instead of 4)",
+			content:       "60002283\tJ01CA04\tAMOXICILLINE 500 mg, gélule\n",
+			expectRecords: 0,
+			description:   "Line with only 3 columns should be skipped",
+		},
+		{
+			name:          "Invalid CIS",
+			content:       "abc\tJ01CA04\tAMOXICILLINE 500 mg, gélule\thttps://example.com\n",
+			expectRecords: 0,
+			description:   "Non-numeric CIS should cause format error skip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile("files/MITM.txt", []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			result, err := makeMITM()
+			if err != nil {
+				t.Fatalf("makeMITM failed: %v", err)
+			}
+
+			if len(result) != tc.expectRecords {
+				t.Fatalf("Expected %d records, got %d. Description: %s", tc.expectRecords, len(result), tc.description)
+			}
+			if tc.expectRecords > 0 && result[0].CodeATC != tc.expectATC {
+				t.Errorf("Expected ATC code %q, got %q", tc.expectATC, result[0].CodeATC)
+			}
+		})
+	}
+}
+
+// TestAttachDisponibilites tests that CIP13 statuses take precedence over CIS-level ones
+func TestAttachDisponibilites(t *testing.T) {
+	presentations := []entities.Presentation{
+		{Cis: 1, Cip13: 3400900000001},
+		{Cis: 1, Cip13: 3400900000002},
+		{Cis: 2, Cip13: 3400900000003},
+	}
+	disponibilites := []entities.Disponibilite{
+		{Cis: 1, CodeStatut: 2},
+		{Cis: 1, Cip13: 3400900000002, CodeStatut: 1},
+	}
+
+	attachDisponibilites(presentations, disponibilites)
+
+	if presentations[0].Disponibilite == nil || presentations[0].Disponibilite.CodeStatut != 2 {
+		t.Errorf("Expected CIS-level status for first presentation, got %+v", presentations[0].Disponibilite)
+	}
+	if presentations[1].Disponibilite == nil || presentations[1].Disponibilite.CodeStatut != 1 {
+		t.Errorf("Expected CIP13 status for second presentation, got %+v", presentations[1].Disponibilite)
+	}
+	if presentations[2].Disponibilite != nil {
+		t.Errorf("Expected no status for third presentation, got %+v", presentations[2].Disponibilite)
+	}
+}
@@ -1,9 +1,16 @@
 package medicamentsparser
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"sync"
 
 	"github.com/giygas/medicaments-api/interfaces"
+	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
 )
 
@@ -11,19 +18,113 @@ import (
 var _ interfaces.Parser = (*MedicamentsParser)(nil)
 
 // MedicamentsParser implements the Parser interface
-type MedicamentsParser struct{ client *http.Client }
+type MedicamentsParser struct {
+	source      Source
+	retryPolicy RetryPolicy
 
-// NewMedicamentsParser creates a new MedicamentsParser instance
+	// Hash of the files behind the last successful parse or the restored dataset, empty before either
+	mu             sync.Mutex
+	parsedHash     string
+	staleSources   []string
+	skippedSources []string
+}
+
+// NewMedicamentsParser creates a new MedicamentsParser instance downloading from the BDPM website
 func NewMedicamentsParser(client *http.Client) *MedicamentsParser {
-	return &MedicamentsParser{client: client}
+	return &MedicamentsParser{source: NewHTTPSource(client), retryPolicy: DefaultRetryPolicy}
+}
+
+// NewMedicamentsParserFromSource creates a new MedicamentsParser instance reading from a source,
+// such as a local directory or archive
+func NewMedicamentsParserFromSource(source Source) *MedicamentsParser {
+	return &MedicamentsParser{source: source, retryPolicy: DefaultRetryPolicy}
+}
+
+// SetRetryPolicy sets how failed file fetches are retried
+func (p *MedicamentsParser) SetRetryPolicy(policy RetryPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retryPolicy = policy
 }
 
-// ParseAllMedicaments implements the Parser interface
+// ParseAllMedicaments implements the Parser interface.
+// It returns interfaces.ErrSourcesUnchanged when every file matches the last successful parse.
 func (p *MedicamentsParser) ParseAllMedicaments() ([]entities.Medicament, map[int]entities.Presentation, map[int]entities.Presentation, error) {
-	return ParseAllMedicaments(p.client)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hashes, stale, err := fetchAll(p.source, p.retryPolicy)
+	if err != nil {
+		logging.Error("Failed to fetch and parse files", "source", p.source.String(), "error", err)
+		return nil, nil, nil, fmt.Errorf("failed to fetch files: %w", err)
+	}
+	p.staleSources = stale
+
+	// Stale files were not checked, parse again to flag them in the quality report
+	hash := sourceHash(hashes)
+	if len(stale) == 0 && p.parsedHash != "" && hash == p.parsedHash {
+		return nil, nil, nil, interfaces.ErrSourcesUnchanged
+	}
+
+	medicaments, presentationsCIP7Map, presentationsCIP13Map, skipped, err := parseFiles()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	p.parsedHash = hash
+	p.skippedSources = skipped
+	return medicaments, presentationsCIP7Map, presentationsCIP13Map, nil
+}
+
+// StaleSources implements the Parser interface
+func (p *MedicamentsParser) StaleSources() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.staleSources)
+}
+
+// SkippedSources implements the Parser interface
+func (p *MedicamentsParser) SkippedSources() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.skippedSources)
+}
+
+// SourceHash implements the Parser interface
+func (p *MedicamentsParser) SourceHash() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parsedHash
+}
+
+// RestoreSourceHash implements the Parser interface
+func (p *MedicamentsParser) RestoreSourceHash(hash string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.parsedHash = hash
+}
+
+// sourceHash combines the content hashes of the files, in file name order
+func sourceHash(hashes map[string]string) string {
+	if hashes == nil {
+		return ""
+	}
+
+	hasher := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(hashes)) {
+		fmt.Fprintf(hasher, "%s %s\n", name, hashes[name])
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
 }
 
 // GeneriquesParser implements the Parser interface
 func (p *MedicamentsParser) GeneriquesParser(medicaments *[]entities.Medicament, medicamentsMap *map[int]entities.Medicament) ([]entities.GeneriqueList, map[int]entities.GeneriqueList, error) {
-	return GeneriquesParser(medicaments, medicamentsMap)
+	generiques, generiquesMap, err := GeneriquesParser(medicaments, medicamentsMap)
+	if err != nil {
+		// The update is dropped, the same files must be parsed again next time
+		p.mu.Lock()
+		p.parsedHash = ""
+		p.mu.Unlock()
+	}
+	return generiques, generiquesMap, err
 }
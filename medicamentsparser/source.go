@@ -0,0 +1,282 @@
+package medicamentsparser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// bdpmBaseURL is where the BDPM files are published
+const bdpmBaseURL = "https://base-donnees-publique.medicaments.gouv.fr/index.php/download/file/"
+
+// bdpmFiles maps the local name of each file in files/ to its BDPM file name
+var bdpmFiles = map[string]string{
+	"Specialites":      "CIS_bdpm.txt",
+	"Presentations":    "CIS_CIP_bdpm.txt",
+	"Compositions":     "CIS_COMPO_bdpm.txt",
+	"Generiques":       "CIS_GENER_bdpm.txt",
+	"Conditions":       "CIS_CPD_bdpm.txt",
+	"AvisSMR":          "CIS_HAS_SMR_bdpm.txt",
+	"AvisASMR":         "CIS_HAS_ASMR_bdpm.txt",
+	"LiensCT":          "HAS_LiensPageCT_bdpm.txt",
+	"Disponibilites":   "CIS_CIP_Dispo_Spec.txt",
+	"InfosImportantes": "CIS_InfoImportantes.txt",
+	"MITM":             "CIS_MITM.txt",
+}
+
+// supplementaryFiles enrich the medicaments but are not needed to build them. One that cannot
+// be fetched or parsed is left out of the data instead of failing the whole load.
+var supplementaryFiles = map[string]bool{
+	"AvisSMR":          true,
+	"AvisASMR":         true,
+	"LiensCT":          true,
+	"Disponibilites":   true,
+	"InfosImportantes": true,
+	"MITM":             true,
+}
+
+// Source provides the raw BDPM files by their BDPM file name (e.g. CIS_bdpm.txt).
+// Files may be encoded in UTF-8 or ISO-8859-1, they are converted when fetched.
+type Source interface {
+	Open(fileName string) (io.ReadCloser, error)
+	String() string
+}
+
+// ErrNotModified is returned by ConditionalSource.OpenIfModified when the file did not change
+var ErrNotModified = errors.New("not modified")
+
+// Validators identify the version of a file on the server, as sent in its ETag and Last-Modified headers
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// ConditionalSource is a Source that can skip the transfer of files that did not change
+type ConditionalSource interface {
+	Source
+	// OpenIfModified returns ErrNotModified if the file still matches the validators,
+	// otherwise the file and its new validators. Empty validators always return the file.
+	OpenIfModified(fileName string, validators Validators) (io.ReadCloser, Validators, error)
+}
+
+// retryableError marks a transient failure, such as a network error or a 5xx response
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable reports whether fetching the file again may succeed
+func isRetryable(err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable)
+}
+
+// HTTPSource downloads the files from the BDPM website
+type HTTPSource struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewHTTPSource creates a source downloading from the BDPM website
+func NewHTTPSource(client *http.Client) *HTTPSource {
+	return &HTTPSource{client: client, baseURL: bdpmBaseURL}
+}
+
+// Open downloads a file
+func (s *HTTPSource) Open(fileName string) (io.ReadCloser, error) {
+	body, _, err := s.OpenIfModified(fileName, Validators{})
+	return body, err
+}
+
+// OpenIfModified downloads a file unless the server answers 304 Not Modified to a conditional request
+func (s *HTTPSource) OpenIfModified(fileName string, validators Validators) (io.ReadCloser, Validators, error) {
+	url := s.baseURL + fileName
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	if validators.ETag != "" {
+		request.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		request.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, Validators{}, &retryableError{fmt.Errorf("failed to download %s: %w", url, err)}
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response.Body, Validators{
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+		}, nil
+	case http.StatusNotModified:
+		_ = response.Body.Close()
+		return nil, validators, ErrNotModified
+	default:
+		_ = response.Body.Close()
+		err := fmt.Errorf("failed to download %s: unexpected status %d", url, response.StatusCode)
+		if response.StatusCode >= 500 || response.StatusCode == http.StatusRequestTimeout || response.StatusCode == http.StatusTooManyRequests {
+			return nil, Validators{}, &retryableError{err}
+		}
+		return nil, Validators{}, err
+	}
+}
+
+func (s *HTTPSource) String() string {
+	return s.baseURL
+}
+
+// DirSource reads the files from a local directory, for air-gapped deployments and tests
+type DirSource struct {
+	dir string
+}
+
+// Open reads a file from the directory
+func (s *DirSource) Open(fileName string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.Base(fileName))) // #nosec G304 -- directory comes from the configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", fileName, err)
+	}
+	return f, nil
+}
+
+func (s *DirSource) String() string {
+	return s.dir
+}
+
+// ArchiveSource reads the files from a .zip, .tar, .tar.gz or .tgz archive.
+// Files are matched by name in any directory of the archive.
+type ArchiveSource struct {
+	path string
+}
+
+// Open extracts a file from the archive
+func (s *ArchiveSource) Open(fileName string) (io.ReadCloser, error) {
+	if strings.HasSuffix(strings.ToLower(s.path), ".zip") {
+		return s.openZip(fileName)
+	}
+	return s.openTar(fileName)
+}
+
+func (s *ArchiveSource) String() string {
+	return s.path
+}
+
+func (s *ArchiveSource) openZip(fileName string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", s.path, err)
+	}
+
+	for _, f := range archive.File {
+		if path.Base(f.Name) != fileName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			_ = archive.Close()
+			return nil, fmt.Errorf("failed to open %s in %s: %w", fileName, s.path, err)
+		}
+		return &archiveEntry{Reader: rc, closers: []io.Closer{rc, archive}}, nil
+	}
+
+	_ = archive.Close()
+	return nil, fmt.Errorf("%s not found in %s", fileName, s.path)
+}
+
+func (s *ArchiveSource) openTar(fileName string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", s.path, err)
+	}
+	closers := []io.Closer{f}
+
+	var r io.Reader = f
+	lower := strings.ToLower(s.path)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to decompress archive %s: %w", s.path, err)
+		}
+		r = zr
+		closers = append([]io.Closer{zr}, closers...)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			closeAll(closers)
+			return nil, fmt.Errorf("failed to read archive %s: %w", s.path, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == fileName {
+			return &archiveEntry{Reader: tr, closers: closers}, nil
+		}
+	}
+
+	closeAll(closers)
+	return nil, fmt.Errorf("%s not found in %s", fileName, s.path)
+}
+
+// archiveEntry reads one file of an archive and closes the archive with it
+type archiveEntry struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (e *archiveEntry) Close() error {
+	return closeAll(e.closers)
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, c := range closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// NewLocalSource reads the files from a directory or an archive
+func NewLocalSource(localPath string) (Source, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BDPM local path: %w", err)
+	}
+
+	if info.IsDir() {
+		return &DirSource{dir: localPath}, nil
+	}
+
+	lower := strings.ToLower(localPath)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return &ArchiveSource{path: localPath}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported BDPM archive %s: must be a directory, .zip, .tar, .tar.gz or .tgz", localPath)
+}
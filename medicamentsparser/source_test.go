@@ -0,0 +1,434 @@
+package medicamentsparser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/giygas/medicaments-api/interfaces"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// testBDPMFiles returns minimal BDPM files: one medicament with one presentation, the other files empty.
+// Specialites is encoded in ISO-8859-1 like some of the published files.
+func testBDPMFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	specialites, err := charmap.ISO8859_1.NewEncoder().String(
+		"61266250\tDOLIPRANE 1000 mg, comprimé\tcomprimé\torale\tAutorisation active\tProcédure nationale\tCommercialisée\t09/07/2002\t\t\t OPELLA HEALTHCARE FRANCE\tNon\n")
+	if err != nil {
+		t.Fatalf("Failed to encode specialites: %v", err)
+	}
+
+	files := make(map[string][]byte, len(bdpmFiles))
+	for _, fileName := range bdpmFiles {
+		files[fileName] = nil
+	}
+	files["CIS_bdpm.txt"] = []byte(specialites)
+	files["CIS_CIP_bdpm.txt"] = []byte("61266250\t3400935\tplaquette(s) de 8 comprimé(s)\tPrésentation active\tDéclaration de commercialisation\t16/03/2011\t3400930000001\toui\t65%\t2,18\t\t\t\n")
+	return files
+}
+
+func writeTestDir(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for fileName, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, fileName), content, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", fileName, err)
+		}
+	}
+	return dir
+}
+
+func writeTestZip(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "bdpm.zip")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for fileName, content := range files {
+		w, err := zw.Create("bdpm/" + fileName)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", fileName, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatalf("Failed to write %s: %v", fileName, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	_ = f.Close()
+	return archivePath
+}
+
+func writeTestTarGz(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "bdpm.tar.gz")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+	for fileName, content := range files {
+		header := &tar.Header{Name: "bdpm/" + fileName, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add %s: %v", fileName, err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatalf("Failed to write %s: %v", fileName, err)
+		}
+	}
+	_ = tw.Close()
+	_ = zw.Close()
+	_ = f.Close()
+	return archivePath
+}
+
+// cleanupFetchedFiles removes the files written to files/ by fetchAll
+func cleanupFetchedFiles(t *testing.T) {
+	t.Cleanup(func() {
+		for name := range bdpmFiles {
+			_ = os.Remove(filepath.Join("files", name+".txt"))
+		}
+		_ = os.Remove(sourcesStatePath)
+	})
+}
+
+// bdpmServer serves files like the BDPM website, with an ETag per file version
+type bdpmServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	files     map[string][]byte
+	versions  map[string]int
+	failures  map[string]int // Remaining 503 responses by file, -1 to always fail
+	requests  map[string]int
+	downloads int
+}
+
+func newBDPMServer(t *testing.T, files map[string][]byte) *bdpmServer {
+	t.Helper()
+
+	s := &bdpmServer{files: files, versions: make(map[string]int), failures: make(map[string]int), requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		fileName := path.Base(r.URL.Path)
+		s.requests[fileName]++
+		if s.failures[fileName] != 0 {
+			if s.failures[fileName] > 0 {
+				s.failures[fileName]--
+			}
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		content, ok := s.files[fileName]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		etag := fmt.Sprintf(`"%s-%d"`, fileName, s.versions[fileName])
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.downloads++
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *bdpmServer) source() *HTTPSource {
+	return &HTTPSource{client: s.Client(), baseURL: s.URL + "/download/file/"}
+}
+
+// update publishes a new version of a file
+func (s *bdpmServer) update(fileName string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[fileName] = content
+	s.versions[fileName]++
+}
+
+// fail makes the next n requests of a file fail, -1 for all of them
+func (s *bdpmServer) fail(fileName string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[fileName] = n
+}
+
+func (s *bdpmServer) requestCount(fileName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[fileName]
+}
+
+func (s *bdpmServer) resetDownloads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	downloads := s.downloads
+	s.downloads = 0
+	return downloads
+}
+
+func TestLocalSources(t *testing.T) {
+	files := testBDPMFiles(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"directory", writeTestDir(t, files)},
+		{"zip archive", writeTestZip(t, files)},
+		{"tar.gz archive", writeTestTarGz(t, files)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewLocalSource(tt.path)
+			if err != nil {
+				t.Fatalf("NewLocalSource failed: %v", err)
+			}
+
+			rc, err := source.Open("CIS_CIP_bdpm.txt")
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			content, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if string(content) != string(files["CIS_CIP_bdpm.txt"]) {
+				t.Errorf("Unexpected content: %q", content)
+			}
+
+			if _, err := source.Open("CIS_UNKNOWN.txt"); err == nil {
+				t.Error("Expected an error for a missing file")
+			}
+		})
+	}
+}
+
+func TestNewLocalSource_Errors(t *testing.T) {
+	if _, err := NewLocalSource(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+
+	unsupported := filepath.Join(t.TempDir(), "bdpm.rar")
+	if err := os.WriteFile(unsupported, nil, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := NewLocalSource(unsupported); err == nil {
+		t.Error("Expected an error for an unsupported archive")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/CIS_bdpm.txt") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer srv.Close()
+
+	source := &HTTPSource{client: srv.Client(), baseURL: srv.URL + "/download/file/"}
+
+	rc, err := source.Open("CIS_bdpm.txt")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	content, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(content) != "content" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	// An error page must not be parsed as data
+	if _, err := source.Open("CIS_MITM.txt"); err == nil {
+		t.Error("Expected an error for a 404 response")
+	}
+}
+
+func TestParseAllMedicamentsFromSource(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	source, err := NewLocalSource(writeTestZip(t, testBDPMFiles(t)))
+	if err != nil {
+		t.Fatalf("NewLocalSource failed: %v", err)
+	}
+
+	medicaments, cip7Map, cip13Map, err := NewMedicamentsParserFromSource(source).ParseAllMedicaments()
+	if err != nil {
+		t.Fatalf("ParseAllMedicaments failed: %v", err)
+	}
+
+	if len(medicaments) != 1 {
+		t.Fatalf("Expected 1 medicament, got %d", len(medicaments))
+	}
+	// The ISO-8859-1 file is converted to UTF-8
+	if medicaments[0].Denomination != "DOLIPRANE 1000 mg, comprimé" || medicaments[0].DenominationNormalized != "doliprane 1000 mg, comprime" {
+		t.Errorf("Unexpected denomination: %q (%q)", medicaments[0].Denomination, medicaments[0].DenominationNormalized)
+	}
+	if medicaments[0].Titulaire != "OPELLA HEALTHCARE FRANCE" {
+		t.Errorf("Unexpected titulaire: %q", medicaments[0].Titulaire)
+	}
+	if len(medicaments[0].Presentation) != 1 || cip7Map[3400935].Cip13 != 3400930000001 || cip13Map[3400930000001].Prix != 2.18 {
+		t.Errorf("Unexpected presentations: %+v", medicaments[0].Presentation)
+	}
+}
+
+func TestParseAllMedicamentsFromSource_MissingFile(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	files := testBDPMFiles(t)
+	delete(files, "CIS_GENER_bdpm.txt")
+
+	source, err := NewLocalSource(writeTestDir(t, files))
+	if err != nil {
+		t.Fatalf("NewLocalSource failed: %v", err)
+	}
+
+	if _, _, _, err := ParseAllMedicamentsFromSource(source); err == nil || !strings.Contains(err.Error(), "CIS_GENER_bdpm.txt") {
+		t.Errorf("Expected an error naming the missing file, got %v", err)
+	}
+}
+
+func TestMedicamentsParser_SkipsSupplementaryFiles(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	// A missing supplementary file and another one that cannot be read do not block the load
+	files := testBDPMFiles(t)
+	delete(files, "CIS_MITM.txt")
+	files["CIS_InfoImportantes.txt"] = []byte(strings.Repeat("x", 2*1024*1024))
+
+	source, err := NewLocalSource(writeTestDir(t, files))
+	if err != nil {
+		t.Fatalf("NewLocalSource failed: %v", err)
+	}
+
+	parser := NewMedicamentsParserFromSource(source)
+	medicaments, _, _, err := parser.ParseAllMedicaments()
+	if err != nil {
+		t.Fatalf("Expected the core files to be loaded, got %v", err)
+	}
+	if len(medicaments) != 1 || len(medicaments[0].Presentation) != 1 {
+		t.Errorf("Unexpected medicaments: %+v", medicaments)
+	}
+	if skipped := parser.SkippedSources(); !slices.Equal(skipped, []string{"CIS_InfoImportantes.txt", "CIS_MITM.txt"}) {
+		t.Errorf("Expected the unreadable files to be skipped, got %v", skipped)
+	}
+}
+
+func TestFetchAll_ConditionalRequests(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	server := newBDPMServer(t, testBDPMFiles(t))
+	source := server.source()
+
+	first, _, err := fetchAll(source, DefaultRetryPolicy)
+	if err != nil {
+		t.Fatalf("First fetch failed: %v", err)
+	}
+	if downloads := server.resetDownloads(); downloads != len(bdpmFiles) {
+		t.Errorf("Expected %d downloads, got %d", len(bdpmFiles), downloads)
+	}
+
+	// Every file is answered with 304 Not Modified
+	second, _, err := fetchAll(source, DefaultRetryPolicy)
+	if err != nil {
+		t.Fatalf("Second fetch failed: %v", err)
+	}
+	if downloads := server.resetDownloads(); downloads != 0 {
+		t.Errorf("Expected no download for unchanged files, got %d", downloads)
+	}
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Errorf("Expected the same hashes, got %v then %v", first, second)
+	}
+
+	// A missing local copy is downloaded again, even if the server has not changed it
+	if err := os.Remove("files/Specialites.txt"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, _, err := fetchAll(source, DefaultRetryPolicy); err != nil {
+		t.Fatalf("Third fetch failed: %v", err)
+	}
+	if downloads := server.resetDownloads(); downloads != 1 {
+		t.Errorf("Expected the missing file to be downloaded, got %d downloads", downloads)
+	}
+	if _, err := os.Stat("files/Specialites.txt"); err != nil {
+		t.Errorf("Expected files/Specialites.txt to be written again: %v", err)
+	}
+}
+
+func TestMedicamentsParser_SkipsUnchangedSources(t *testing.T) {
+	cleanupFetchedFiles(t)
+
+	server := newBDPMServer(t, testBDPMFiles(t))
+	parser := NewMedicamentsParserFromSource(server.source())
+
+	if hash := parser.SourceHash(); hash != "" {
+		t.Errorf("Expected no source hash before the first parse, got %q", hash)
+	}
+	if _, _, _, err := parser.ParseAllMedicaments(); err != nil {
+		t.Fatalf("First parse failed: %v", err)
+	}
+	firstHash := parser.SourceHash()
+	if len(firstHash) != 64 {
+		t.Errorf("Expected a SHA-256 source hash, got %q", firstHash)
+	}
+
+	if _, _, _, err := parser.ParseAllMedicaments(); !errors.Is(err, interfaces.ErrSourcesUnchanged) {
+		t.Fatalf("Expected ErrSourcesUnchanged, got %v", err)
+	}
+
+	// A new version with the same content is still unchanged
+	server.update("CIS_MITM.txt", nil)
+	if _, _, _, err := parser.ParseAllMedicaments(); !errors.Is(err, interfaces.ErrSourcesUnchanged) {
+		t.Fatalf("Expected ErrSourcesUnchanged for identical content, got %v", err)
+	}
+
+	server.update("CIS_CIP_bdpm.txt", []byte("61266250\t3400935\tplaquette(s) de 8 comprimé(s)\tPrésentation active\tDéclaration de commercialisation\t16/03/2011\t3400930000001\toui\t65%\t2,50\t\t\t\n"))
+	_, _, cip13Map, err := parser.ParseAllMedicaments()
+	if err != nil {
+		t.Fatalf("Expected the changed file to be parsed, got %v", err)
+	}
+	if cip13Map[3400930000001].Prix != 2.5 {
+		t.Errorf("Expected the new price, got %+v", cip13Map[3400930000001])
+	}
+	if hash := parser.SourceHash(); hash == firstHash {
+		t.Error("Expected the source hash to change with the file content")
+	}
+
+	// A parser restored from a snapshot of the current files skips them without parsing first
+	restored := NewMedicamentsParserFromSource(server.source())
+	restored.RestoreSourceHash(parser.SourceHash())
+	if _, _, _, err := restored.ParseAllMedicaments(); !errors.Is(err, interfaces.ErrSourcesUnchanged) {
+		t.Fatalf("Expected ErrSourcesUnchanged after restoring the source hash, got %v", err)
+	}
+}
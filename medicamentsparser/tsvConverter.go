@@ -2,22 +2,26 @@ package medicamentsparser
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"html"
 	"math"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
 )
 
-func makePresentations(wg *sync.WaitGroup) ([]entities.Presentation, error) {
-	if wg != nil {
-		defer wg.Done()
-	}
+// InfoImportantes texts are published as HTML anchors pointing to the ANSM notice
+var (
+	infoLinkRegex = regexp.MustCompile(`href=['"]([^'"]+)['"]`)
+	htmlTagRegex  = regexp.MustCompile(`<[^>]*>`)
+)
 
+func makePresentations() ([]entities.Presentation, error) {
 	tsvFile, err := os.Open("files/Presentations.txt")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Presentations.txt: %w", err)
@@ -136,11 +140,7 @@ func makePresentations(wg *sync.WaitGroup) ([]entities.Presentation, error) {
 	return jsonRecords, nil
 }
 
-func makeGeneriques(wg *sync.WaitGroup) ([]entities.Generique, error) {
-
-	if wg != nil {
-		defer wg.Done()
-	}
+func makeGeneriques() ([]entities.Generique, error) {
 
 	tsvFile, err := os.Open("files/Generiques.txt")
 	if err != nil {
@@ -244,11 +244,7 @@ func makeGeneriques(wg *sync.WaitGroup) ([]entities.Generique, error) {
 	return jsonRecords, nil
 }
 
-func makeCompositions(wg *sync.WaitGroup) ([]entities.Composition, error) {
-	if wg != nil {
-		defer wg.Done()
-	}
-
+func makeCompositions() ([]entities.Composition, error) {
 	tsvFile, err := os.Open("files/Compositions.txt")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open compositions file: %w", err)
@@ -330,11 +326,7 @@ func makeCompositions(wg *sync.WaitGroup) ([]entities.Composition, error) {
 	return jsonRecords, nil
 }
 
-func makeSpecialites(wg *sync.WaitGroup) ([]entities.Specialite, error) {
-	if wg != nil {
-		defer wg.Done()
-	}
-
+func makeSpecialites() ([]entities.Specialite, error) {
 	tsvFile, err := os.Open("files/Specialites.txt")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Specialites.txt: %w", err)
@@ -413,11 +405,7 @@ func makeSpecialites(wg *sync.WaitGroup) ([]entities.Specialite, error) {
 	return jsonRecords, nil
 }
 
-func makeConditions(wg *sync.WaitGroup) ([]entities.Condition, error) {
-	if wg != nil {
-		defer wg.Done()
-	}
-
+func makeConditions() ([]entities.Condition, error) {
 	tsvFile, err := os.Open("files/Conditions.txt")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open Conditions.txt: %w", err)
@@ -488,6 +476,186 @@ func makeConditions(wg *sync.WaitGroup) ([]entities.Condition, error) {
 	return jsonRecords, nil
 }
 
+func makeAvisSMR() ([]entities.AvisHAS, error) {
+	return readTSV("AvisSMR", 6, parseAvisHAS)
+}
+
+func makeAvisASMR() ([]entities.AvisHAS, error) {
+	return readTSV("AvisASMR", 6, parseAvisHAS)
+}
+
+// parseAvisHAS parses a line of a HAS evaluation file (SMR or ASMR). Both files share the same layout:
+// CIS, code dossier HAS, motif d'évaluation, date de l'avis, valeur, libellé
+func parseAvisHAS(fields []string) (entities.AvisHAS, error) {
+	cis, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return entities.AvisHAS{}, err
+	}
+
+	return entities.AvisHAS{
+		Cis:             cis,
+		CodeDossierHAS:  fields[1],
+		MotifEvaluation: fields[2],
+		DateAvis:        fields[3],
+		Valeur:          fields[4],
+		Libelle:         fields[5],
+	}, nil
+}
+
+func makeLiensCT() ([]entities.LienPageCT, error) {
+	return readTSV("LiensCT", 2, func(fields []string) (entities.LienPageCT, error) {
+		if fields[0] == "" {
+			return entities.LienPageCT{}, errors.New("missing code dossier HAS")
+		}
+
+		return entities.LienPageCT{
+			CodeDossierHAS: fields[0],
+			Lien:           fields[1],
+		}, nil
+	})
+}
+
+func makeDisponibilites() ([]entities.Disponibilite, error) {
+	return readTSV("Disponibilites", 8, func(fields []string) (entities.Disponibilite, error) {
+		cis, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return entities.Disponibilite{}, err
+		}
+
+		// An empty CIP13 means the status applies to the whole CIS
+		cip13 := 0
+		if fields[1] != "" {
+			cip13, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return entities.Disponibilite{}, err
+			}
+		}
+
+		codeStatut, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return entities.Disponibilite{}, err
+		}
+
+		return entities.Disponibilite{
+			Cis:                   cis,
+			Cip13:                 cip13,
+			CodeStatut:            codeStatut,
+			Statut:                fields[3],
+			DateDebut:             fields[4],
+			DateMiseAJour:         fields[5],
+			DateRemiseDisposition: fields[6],
+			LienANSM:              fields[7],
+		}, nil
+	})
+}
+
+func makeInfosImportantes() ([]entities.InfoImportante, error) {
+	return readTSV("InfosImportantes", 4, func(fields []string) (entities.InfoImportante, error) {
+		cis, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return entities.InfoImportante{}, err
+		}
+
+		// Split the HTML anchor into plain text and link
+		var lien string
+		if match := infoLinkRegex.FindStringSubmatch(fields[3]); match != nil {
+			lien = match[1]
+		}
+		texte := strings.TrimSpace(html.UnescapeString(htmlTagRegex.ReplaceAllString(fields[3], "")))
+
+		return entities.InfoImportante{
+			Cis:       cis,
+			DateDebut: fields[1],
+			DateFin:   fields[2],
+			Texte:     texte,
+			Lien:      lien,
+		}, nil
+	})
+}
+
+func makeMITM() ([]entities.MITM, error) {
+	return readTSV("MITM", 4, func(fields []string) (entities.MITM, error) {
+		cis, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return entities.MITM{}, err
+		}
+
+		return entities.MITM{
+			Cis:          cis,
+			CodeATC:      strings.TrimSpace(fields[1]),
+			Denomination: fields[2],
+			Lien:         strings.TrimSpace(fields[3]),
+		}, nil
+	})
+}
+
+// readTSV converts files/<name>.txt into records, one per line. Empty lines, lines with fewer
+// than minColumns columns and lines that parse rejects are skipped and counted in the skip statistics.
+func readTSV[T any](name string, minColumns int, parse func(fields []string) (T, error)) ([]T, error) {
+	tsvFile, err := os.Open("files/" + name + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s.txt: %w", name, err)
+	}
+	defer func() {
+		if err := tsvFile.Close(); err != nil {
+			logging.Warn("Failed to close TSV file", "file", name, "error", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(tsvFile)
+	scanner.Buffer(make([]byte, 0), 1*1024*1024)
+
+	var jsonRecords []T
+	lineCount := 0
+	skippedEmptyLines := 0
+	skippedMissingColumns := 0
+	skippedFormatErrors := 0
+
+	for scanner.Scan() {
+		lineCount++
+		line := scanner.Text()
+
+		// Skip empty lines silently
+		if len(line) == 0 {
+			skippedEmptyLines++
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+
+		if len(fields) < minColumns {
+			skippedMissingColumns++
+			continue
+		}
+
+		record, err := parse(fields)
+		if err != nil {
+			skippedFormatErrors++
+			continue
+		}
+
+		jsonRecords = append(jsonRecords, record)
+	}
+
+	// Check for scanner errors
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner error in %s.txt: %w", name, err)
+	}
+
+	// Log skip statistics if any lines were skipped
+	if skippedEmptyLines > 0 || skippedMissingColumns > 0 || skippedFormatErrors > 0 {
+		logging.Info(name+".txt skip statistics",
+			"empty_lines", skippedEmptyLines,
+			"missing_columns", skippedMissingColumns,
+			"format_errors", skippedFormatErrors,
+			"total_lines", lineCount,
+			"records_parsed", len(jsonRecords))
+	}
+
+	logging.Debug(name+" file conversion completed", "records_count", len(jsonRecords))
+	return jsonRecords, nil
+}
+
 // Creates a mapping where the key is the medicament cis and the value is the type of generique of the medicament
 //
 // Returns a map where key:cis and value:typeOfGenerique
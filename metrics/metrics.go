@@ -4,6 +4,10 @@
 //   - http_request_duration_seconds: Histogram with method and path labels
 //   - http_request_in_flight: Gauge for concurrent requests
 //
+// And two metrics for the BDPM file downloads:
+//   - bdpm_download_attempts_total: Counter with file and result labels
+//   - bdpm_download_duration_seconds: Histogram with file label
+//
 // All metrics are automatically registered with the Prometheus default registry
 // during package initialization.
 package metrics
@@ -41,6 +45,23 @@ var (
 			Help: "Total number of rate limiter buckets (IPs seen in last ~5 minutes)",
 		},
 	)
+
+	BDPMDownloadAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bdpm_download_attempts_total",
+			Help: "BDPM file download attempts by result (success, not_modified, error)",
+		},
+		[]string{"file", "result"},
+	)
+
+	BDPMDownloadDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "bdpm_download_duration_seconds",
+			Help:    "BDPM file download attempt duration",
+			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+		[]string{"file"},
+	)
 )
 
 func init() {
@@ -48,4 +69,6 @@ func init() {
 	prometheus.MustRegister(HTTPRequestDuration)
 	prometheus.MustRegister(HTTPRequestInFlight)
 	prometheus.MustRegister(RateLimiterBucketsTotal)
+	prometheus.MustRegister(BDPMDownloadAttempts)
+	prometheus.MustRegister(BDPMDownloadDuration)
 }
@@ -4,12 +4,16 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/events"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/snapshot"
 	"github.com/giygas/medicaments-api/validation"
 	"github.com/go-co-op/gocron"
 )
@@ -22,6 +26,10 @@ type Scheduler struct {
 	dataStore interfaces.DataStore
 	parser    interfaces.Parser
 	scheduler *gocron.Scheduler
+	notifiers []interfaces.ChangeNotifier
+	events    interfaces.EventPublisher
+
+	snapshotPath string // Empty when snapshots are disabled
 }
 
 // NewScheduler creates a new scheduler instance with injected dependencies
@@ -33,10 +41,40 @@ func NewScheduler(dataStore interfaces.DataStore, parser interfaces.Parser) *Sch
 	}
 }
 
+// AddChangeNotifier registers a notifier called after each update that has a previous dataset.
+// Notifiers must be added before Start.
+func (s *Scheduler) AddChangeNotifier(notifier interfaces.ChangeNotifier) {
+	s.notifiers = append(s.notifiers, notifier)
+}
+
+// SetEventPublisher sets where the update_started, update_completed and update_failed events are published
+func (s *Scheduler) SetEventPublisher(publisher interfaces.EventPublisher) {
+	s.events = publisher
+}
+
+// publish sends an event when a publisher is set
+func (s *Scheduler) publish(eventType string, data any) {
+	if s.events != nil {
+		s.events.Publish(eventType, data)
+	}
+}
+
+// SetSnapshotPath enables saving the dataset after each update and serving it at startup
+func (s *Scheduler) SetSnapshotPath(path string) {
+	s.snapshotPath = path
+}
+
 // Start initializes the scheduler with data updates and health monitoring
 func (s *Scheduler) Start() error {
-	// Initial load
-	if err := s.updateData(); err != nil {
+	// Initial load: serve the last snapshot right away and refresh in the background,
+	// or block until the first download and parse complete when there is none
+	if s.loadSnapshot() {
+		go func() {
+			if err := s.updateData(); err != nil {
+				logging.Error("Failed to refresh data, still serving the snapshot", "error", err)
+			}
+		}()
+	} else if err := s.updateData(); err != nil {
 		logging.Error("Failed to perform initial data load", "error", err)
 		return fmt.Errorf("initial data load failed: %w", err)
 	}
@@ -77,12 +115,28 @@ func (s *Scheduler) updateData() error {
 
 	logging.Info(fmt.Sprintf("Starting database update at: %s", time.Now().Format(time.RFC3339)))
 	start := time.Now()
+	s.publish(events.UpdateStarted, events.UpdateStartedData{StartedAt: start.UTC()})
 
 	// Parse data using injected parser
 	newMedicaments, newPresentationsCIP7Map, newPresentationsCIP13Map, err := s.parser.ParseAllMedicaments()
+	if errors.Is(err, interfaces.ErrSourcesUnchanged) {
+		s.keepUnchangedData(start)
+		return nil
+	}
 	if err != nil {
 		logging.Error("Failed to parse medicaments", "error", err)
-		return fmt.Errorf("failed to parse medicaments: %w", err)
+		err = fmt.Errorf("failed to parse medicaments: %w", err)
+		s.publish(events.UpdateFailed, events.UpdateFailedData{Error: err.Error(), StartedAt: start.UTC()})
+		return err
+	}
+
+	// Sources parsed again with the content of the current version do not make a new version:
+	// pins, cursors and the change feed would see an empty update. Stale files are still
+	// published, to flag them in the quality report.
+	previousVersion := s.dataStore.GetDataVersion()
+	if hash := s.parser.SourceHash(); hash != "" && hash == previousVersion.Hash && len(s.parser.StaleSources()) == 0 {
+		s.keepUnchangedData(start)
+		return nil
 	}
 
 	// Create new maps
@@ -94,11 +148,31 @@ func (s *Scheduler) updateData() error {
 	newGeneriques, newGeneriquesMap, err := s.parser.GeneriquesParser(&newMedicaments, &newMedicamentsMap)
 	if err != nil {
 		logging.Error("Failed to parse generiques", "error", err)
-		return fmt.Errorf("failed to parse generiques: %w", err)
+		err = fmt.Errorf("failed to parse generiques: %w", err)
+		s.publish(events.UpdateFailed, events.UpdateFailedData{Error: err.Error(), StartedAt: start.UTC()})
+		return err
 	}
 
 	validator := validation.NewDataValidator()
 	report := validator.ReportDataQuality(newMedicaments, newGeneriques, newPresentationsCIP7Map, newPresentationsCIP13Map)
+	report.StaleSourceFiles = s.parser.StaleSources()
+	report.SkippedSourceFiles = s.parser.SkippedSources()
+
+	// Log source files parsed from their previous copy
+	if len(report.StaleSourceFiles) > 0 {
+		logging.Warn("Source files served from their last good copy",
+			"total", len(report.StaleSourceFiles),
+			"files", report.StaleSourceFiles,
+		)
+	}
+
+	// Log supplementary source files left out of the data
+	if len(report.SkippedSourceFiles) > 0 {
+		logging.Warn("Supplementary source files left out of the data",
+			"total", len(report.SkippedSourceFiles),
+			"files", report.SkippedSourceFiles,
+		)
+	}
 
 	// Log duplicate CIS
 	if len(report.DuplicateCIS) > 0 {
@@ -132,15 +206,145 @@ func (s *Scheduler) updateData() error {
 		)
 	}
 
+	// Keep the previous dataset to compute the changes once the new one is served
+	previousMedicamentsMap := s.dataStore.GetMedicamentsMap()
+	previousPresentationsMap := s.dataStore.GetPresentationsCIP13Map()
+	previousGeneriquesMap := s.dataStore.GetGeneriquesMap()
+
+	// The version follows the previous one, restored from the snapshot after a restart,
+	// and is identified by the content of the source files
+	currentVersion := interfaces.DataVersion{
+		Version:  previousVersion.Version + 1,
+		Hash:     s.parser.SourceHash(),
+		LoadedAt: time.Now(),
+	}
+
 	// Atomic update using injected data store (including report)
-	s.dataStore.UpdateData(newMedicaments, newGeneriques, newMedicamentsMap, newGeneriquesMap, newPresentationsCIP7Map, newPresentationsCIP13Map, report)
+	s.dataStore.UpdateData(newMedicaments, newGeneriques, newMedicamentsMap, newGeneriquesMap, newPresentationsCIP7Map, newPresentationsCIP13Map, report, currentVersion)
+
+	if s.snapshotPath != "" {
+		s.saveSnapshot(&snapshot.Snapshot{
+			Medicaments:           newMedicaments,
+			Generiques:            newGeneriques,
+			PresentationsCIP7Map:  newPresentationsCIP7Map,
+			PresentationsCIP13Map: newPresentationsCIP13Map,
+			Report:                report,
+			DataVersion:           currentVersion,
+		})
+	}
+
+	// The first load has no previous dataset to compare to
+	if previousVersion.Version > 0 {
+		changeSet := changes.ChangeSet{
+			FromVersion:   previousVersion.Version,
+			ToVersion:     currentVersion.Version,
+			FromLoadedAt:  previousVersion.LoadedAt,
+			ToLoadedAt:    currentVersion.LoadedAt,
+			Medicaments:   changes.Compare(previousMedicamentsMap, newMedicamentsMap),
+			Presentations: changes.Compare(previousPresentationsMap, newPresentationsCIP13Map),
+			Generiques:    changes.Compare(previousGeneriquesMap, newGeneriquesMap),
+		}
+		s.dataStore.RecordChanges(changeSet)
+
+		logging.Info("Changes since previous update",
+			"from_version", changeSet.FromVersion,
+			"to_version", changeSet.ToVersion,
+			"medicaments", changeSet.Medicaments.Len(),
+			"presentations", changeSet.Presentations.Len(),
+			"generiques", changeSet.Generiques.Len(),
+		)
+
+		for _, notifier := range s.notifiers {
+			notifier.NotifyChanges(changeSet, newPresentationsCIP13Map)
+		}
+	}
 
 	elapsed := time.Since(start)
 	logging.Info("Database update completed", "duration", elapsed.String(), "medicament_count", len(newMedicaments))
 
+	s.publish(events.UpdateCompleted, events.UpdateCompletedData{
+		DataVersion:   currentVersion.Version,
+		DataHash:      currentVersion.Hash,
+		Medicaments:   len(newMedicaments),
+		Generiques:    len(newGeneriques),
+		Presentations: len(newPresentationsCIP13Map),
+		StartedAt:     start.UTC(),
+		DurationMs:    elapsed.Milliseconds(),
+	})
+
 	return nil
 }
 
+// keepUnchangedData ends an update whose sources did not change: the current dataset,
+// its version and the change feed are kept, only the last check time moves forward
+func (s *Scheduler) keepUnchangedData(start time.Time) {
+	s.dataStore.MarkChecked()
+
+	elapsed := time.Since(start)
+	version := s.dataStore.GetDataVersion()
+	logging.Info("Sources unchanged, keeping the current data", "duration", elapsed.String(), "data_version", version.Version)
+
+	s.publish(events.UpdateCompleted, events.UpdateCompletedData{
+		DataVersion:   version.Version,
+		DataHash:      version.Hash,
+		Medicaments:   len(s.dataStore.GetMedicaments()),
+		Generiques:    len(s.dataStore.GetGeneriques()),
+		Presentations: len(s.dataStore.GetPresentationsCIP13Map()),
+		StartedAt:     start.UTC(),
+		DurationMs:    elapsed.Milliseconds(),
+		Unchanged:     true,
+	})
+}
+
+// loadSnapshot serves the saved dataset, reporting whether one was loaded
+func (s *Scheduler) loadSnapshot() bool {
+	if s.snapshotPath == "" {
+		return false
+	}
+
+	snap, err := snapshot.Load(s.snapshotPath)
+	if err != nil {
+		if errors.Is(err, snapshot.ErrNotFound) {
+			logging.Info("No snapshot found, waiting for the initial data load", "path", s.snapshotPath)
+		} else {
+			logging.Warn("Ignoring unreadable snapshot", "path", s.snapshotPath, "error", err)
+		}
+		return false
+	}
+
+	if !s.dataStore.BeginUpdate() {
+		return false
+	}
+	defer s.dataStore.EndUpdate()
+
+	// The snapshot keeps its version and load time: versions keep increasing across restarts,
+	// and /health and Last-Modified report the age of the data rather than the restart
+	s.dataStore.UpdateData(snap.Medicaments, snap.Generiques, snap.MedicamentsMap(), snap.GeneriquesMap(),
+		snap.PresentationsCIP7Map, snap.PresentationsCIP13Map, snap.Report, snap.DataVersion)
+
+	// The refresh that follows the restart is skipped when the sources did not change since the snapshot
+	s.parser.RestoreSourceHash(snap.DataVersion.Hash)
+
+	logging.Info("Serving data from snapshot",
+		"path", s.snapshotPath,
+		"saved_at", snap.SavedAt.Format(time.RFC3339),
+		"data_version", snap.DataVersion.Version,
+		"loaded_at", snap.DataVersion.LoadedAt.Format(time.RFC3339),
+		"medicament_count", len(snap.Medicaments),
+	)
+	return true
+}
+
+// saveSnapshot writes the dataset to disk. A failure is logged and does not fail the update.
+func (s *Scheduler) saveSnapshot(snap *snapshot.Snapshot) {
+	start := time.Now()
+	if err := snapshot.Save(s.snapshotPath, snap); err != nil {
+		logging.Warn("Failed to save snapshot", "path", s.snapshotPath, "error", err)
+		return
+	}
+	logging.Info("Snapshot saved", "path", s.snapshotPath, "duration", time.Since(start).String())
+}
+
 // startHealthMonitoring monitors the health of the data updates
 func (s *Scheduler) startHealthMonitoring() {
 	go func() {
@@ -148,9 +352,9 @@ func (s *Scheduler) startHealthMonitoring() {
 		defer ticker.Stop()
 
 		for range ticker.C {
-			lastUpdate := s.dataStore.GetLastUpdated()
-			if time.Since(lastUpdate) > 25*time.Hour {
-				logging.Warn("Data hasn't been updated in over 25 hours")
+			lastCheck := s.dataStore.GetLastChecked()
+			if time.Since(lastCheck) > 25*time.Hour {
+				logging.Warn("Data hasn't been checked for updates in over 25 hours")
 			}
 		}
 	}()
@@ -1,11 +1,21 @@
 package scheduler
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/giygas/medicaments-api/artifact"
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/events"
+	"github.com/giygas/medicaments-api/export"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
+	"github.com/giygas/medicaments-api/snapshot"
 )
 
 // MockDataStore for testing scheduler
@@ -17,8 +27,12 @@ type mockSchedulerDataStore struct {
 	presentationsCIP7Map  map[int]entities.Presentation
 	presentationsCIP13Map map[int]entities.Presentation
 	lastUpdated           time.Time
+	lastChecked           time.Time
+	dataVersion           interfaces.DataVersion
+	changeSets            []changes.ChangeSet
 	updating              bool
 	updateCount           int
+	report                *interfaces.DataQualityReport
 }
 
 func (m *mockSchedulerDataStore) GetMedicaments() []entities.Medicament {
@@ -45,22 +59,84 @@ func (m *mockSchedulerDataStore) GetPresentationsCIP13Map() map[int]entities.Pre
 	return m.presentationsCIP13Map
 }
 
+func (m *mockSchedulerDataStore) GetDisponibilites() []entities.Disponibilite {
+	return []entities.Disponibilite{}
+}
+
+func (m *mockSchedulerDataStore) GetSearchIndex() *search.Index {
+	return search.NewIndex(nil)
+}
+
+func (m *mockSchedulerDataStore) GetSubstanceIndex() *search.SubstanceIndex {
+	return search.NewSubstanceIndex(nil)
+}
+
+func (m *mockSchedulerDataStore) GetMedicamentOrders() search.Orders {
+	return search.NewOrders(nil)
+}
+
 func (m *mockSchedulerDataStore) GetLastUpdated() time.Time {
 	return m.lastUpdated
 }
 
+func (m *mockSchedulerDataStore) GetLastChecked() time.Time {
+	return m.lastChecked
+}
+
+func (m *mockSchedulerDataStore) MarkChecked() {
+	m.lastChecked = time.Now()
+}
+
+func (m *mockSchedulerDataStore) GetDataset() *interfaces.Dataset {
+	return &interfaces.Dataset{
+		Version:               m.GetDataVersion(),
+		Medicaments:           m.GetMedicaments(),
+		Generiques:            m.GetGeneriques(),
+		MedicamentsMap:        m.GetMedicamentsMap(),
+		GeneriquesMap:         m.GetGeneriquesMap(),
+		PresentationsCIP7Map:  m.GetPresentationsCIP7Map(),
+		PresentationsCIP13Map: m.GetPresentationsCIP13Map(),
+		Disponibilites:        m.GetDisponibilites(),
+		SearchIndex:           m.GetSearchIndex(),
+		SubstanceIndex:        m.GetSubstanceIndex(),
+		Orders:                m.GetMedicamentOrders(),
+	}
+}
+
+func (m *mockSchedulerDataStore) GetTabularExports() (*export.Exports, interfaces.DataVersion) {
+	return nil, m.dataVersion
+}
+
+func (m *mockSchedulerDataStore) GetDataVersion() interfaces.DataVersion {
+	return m.dataVersion
+}
+
+func (m *mockSchedulerDataStore) GetChanges() []changes.ChangeSet {
+	return m.changeSets
+}
+
+func (m *mockSchedulerDataStore) GetExportArtifact() (*artifact.Artifact, interfaces.DataVersion) {
+	return nil, m.GetDataVersion()
+}
+
+func (m *mockSchedulerDataStore) RecordChanges(changeSet changes.ChangeSet) {
+	m.changeSets = append(m.changeSets, changeSet)
+}
+
 func (m *mockSchedulerDataStore) IsUpdating() bool {
 	return m.updating
 }
 
-func (m *mockSchedulerDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation, report *interfaces.DataQualityReport) {
+func (m *mockSchedulerDataStore) UpdateData(medicaments []entities.Medicament, generiques []entities.GeneriqueList, medicamentsMap map[int]entities.Medicament, generiquesMap map[int]entities.GeneriqueList, presentationsCIP7Map map[int]entities.Presentation, presentationsCIP13Map map[int]entities.Presentation, report *interfaces.DataQualityReport, version interfaces.DataVersion) {
 	m.medicaments = medicaments
 	m.generiques = generiques
 	m.medicamentsMap = medicamentsMap
 	m.generiquesMap = generiquesMap
 	m.presentationsCIP7Map = presentationsCIP7Map
 	m.presentationsCIP13Map = presentationsCIP13Map
-	m.lastUpdated = time.Now()
+	m.report = report
+	m.lastUpdated = version.LoadedAt
+	m.dataVersion = version
 	m.updateCount++
 }
 
@@ -103,6 +179,12 @@ func (m *mockSchedulerDataStore) GetDataQualityReport() *interfaces.DataQualityR
 type mockSchedulerParser struct {
 	parseCount int
 	shouldFail bool
+	unchanged  bool
+	stale      []string
+	skipped    []string
+	// Fixed source hash, the hash changes with every parse when empty
+	hash         string
+	restoredHash string
 	// Configurable presentation maps for testing
 	cip7Map  map[int]entities.Presentation
 	cip13Map map[int]entities.Presentation
@@ -113,6 +195,9 @@ func (m *mockSchedulerParser) ParseAllMedicaments() ([]entities.Medicament, map[
 	if m.shouldFail {
 		return nil, nil, nil, &mockSchedulerError{"parse failed"}
 	}
+	if m.unchanged {
+		return nil, nil, nil, interfaces.ErrSourcesUnchanged
+	}
 
 	// Use configured presentation maps if available, otherwise use default
 	cip7Map := m.cip7Map
@@ -136,6 +221,26 @@ func (m *mockSchedulerParser) ParseAllMedicaments() ([]entities.Medicament, map[
 	}, cip7Map, cip13Map, nil
 }
 
+func (m *mockSchedulerParser) StaleSources() []string {
+	return m.stale
+}
+
+func (m *mockSchedulerParser) SkippedSources() []string {
+	return m.skipped
+}
+
+// SourceHash changes with every parse, like sources that changed at each update, unless fixed
+func (m *mockSchedulerParser) SourceHash() string {
+	if m.hash != "" {
+		return m.hash
+	}
+	return fmt.Sprintf("hash-%d", m.parseCount)
+}
+
+func (m *mockSchedulerParser) RestoreSourceHash(hash string) {
+	m.restoredHash = hash
+}
+
 func (m *mockSchedulerParser) GeneriquesParser(medicaments *[]entities.Medicament, medicamentsMap *map[int]entities.Medicament) ([]entities.GeneriqueList, map[int]entities.GeneriqueList, error) {
 	if m.shouldFail {
 		return nil, nil, &mockSchedulerError{"generiques parse failed"}
@@ -385,6 +490,367 @@ func TestScheduler_UpdateOverridesMaps(t *testing.T) {
 	scheduler.Stop()
 }
 
+func TestScheduler_RecordsChanges(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{
+		cip13Map: map[int]entities.Presentation{
+			3400900000001: {Cis: 1, Cip13: 3400900000001, Prix: 2.5},
+		},
+	}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+
+	// The first load has nothing to compare to
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("First update failed: %v", err)
+	}
+	if len(mockDataStore.changeSets) != 0 {
+		t.Fatalf("Expected no change set after the first load, got %d", len(mockDataStore.changeSets))
+	}
+
+	mockParser.cip13Map = map[int]entities.Presentation{
+		3400900000001: {Cis: 1, Cip13: 3400900000001, Prix: 3},
+		3400900000002: {Cis: 2, Cip13: 3400900000002},
+	}
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Second update failed: %v", err)
+	}
+
+	if len(mockDataStore.changeSets) != 1 {
+		t.Fatalf("Expected 1 change set, got %d", len(mockDataStore.changeSets))
+	}
+	changeSet := mockDataStore.changeSets[0]
+	if changeSet.FromVersion != 1 || changeSet.ToVersion != 2 {
+		t.Errorf("Expected change set from version 1 to 2, got %d to %d", changeSet.FromVersion, changeSet.ToVersion)
+	}
+	if changeSet.Medicaments.Len() != 0 {
+		t.Errorf("Expected no medicament change, got %+v", changeSet.Medicaments)
+	}
+	if len(changeSet.Presentations.Added) != 1 || changeSet.Presentations.Added[0] != 3400900000002 {
+		t.Errorf("Expected presentation 3400900000002 added, got %v", changeSet.Presentations.Added)
+	}
+	if len(changeSet.Presentations.Modified) != 1 || changeSet.Presentations.Modified[0].Fields[0].Field != "prix" {
+		t.Errorf("Expected prix change on 3400900000001, got %+v", changeSet.Presentations.Modified)
+	}
+}
+
+type recordingNotifier struct {
+	changeSets []changes.ChangeSet
+	cip13Maps  []map[int]entities.Presentation
+}
+
+func (n *recordingNotifier) NotifyChanges(changeSet changes.ChangeSet, presentationsCIP13Map map[int]entities.Presentation) {
+	n.changeSets = append(n.changeSets, changeSet)
+	n.cip13Maps = append(n.cip13Maps, presentationsCIP13Map)
+}
+
+func TestScheduler_NotifiesChanges(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{}
+	notifier := &recordingNotifier{}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	scheduler.AddChangeNotifier(notifier)
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("First update failed: %v", err)
+	}
+	if len(notifier.changeSets) != 0 {
+		t.Fatalf("Expected no notification after the first load, got %d", len(notifier.changeSets))
+	}
+
+	mockParser.cip13Map = map[int]entities.Presentation{
+		3400900000001: {Cis: 1, Cip13: 3400900000001, Prix: 3},
+	}
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Second update failed: %v", err)
+	}
+
+	if len(notifier.changeSets) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(notifier.changeSets))
+	}
+	if notifier.changeSets[0].ToVersion != 2 {
+		t.Errorf("Expected notification for version 2, got %d", notifier.changeSets[0].ToVersion)
+	}
+	if notifier.cip13Maps[0][3400900000001].Cis != 1 {
+		t.Errorf("Expected the new CIP13 map to be passed, got %v", notifier.cip13Maps[0])
+	}
+}
+
+type recordingPublisher struct {
+	types []string
+	data  []any
+}
+
+func (p *recordingPublisher) Publish(eventType string, data any) {
+	p.types = append(p.types, eventType)
+	p.data = append(p.data, data)
+}
+
+func TestScheduler_PublishesUpdateEvents(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{}
+	publisher := &recordingPublisher{}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	scheduler.SetEventPublisher(publisher)
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if len(publisher.types) != 2 || publisher.types[0] != events.UpdateStarted || publisher.types[1] != events.UpdateCompleted {
+		t.Fatalf("Expected update_started then update_completed, got %v", publisher.types)
+	}
+	completed, ok := publisher.data[1].(events.UpdateCompletedData)
+	if !ok {
+		t.Fatalf("Expected UpdateCompletedData, got %T", publisher.data[1])
+	}
+	if completed.DataVersion != 1 || completed.Medicaments != 2 || completed.Generiques != 1 || completed.Presentations != 1 {
+		t.Errorf("Unexpected update_completed payload: %+v", completed)
+	}
+
+	// A failed update is reported instead of completed
+	publisher.types, publisher.data = nil, nil
+	mockParser.shouldFail = true
+	if err := scheduler.updateData(); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+
+	if len(publisher.types) != 2 || publisher.types[1] != events.UpdateFailed {
+		t.Fatalf("Expected update_started then update_failed, got %v", publisher.types)
+	}
+	failed := publisher.data[1].(events.UpdateFailedData)
+	if !strings.Contains(failed.Error, "parse failed") {
+		t.Errorf("Expected the parse error in update_failed, got %q", failed.Error)
+	}
+}
+
+func TestScheduler_KeepsDataWhenSourcesUnchanged(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{}
+	notifier := &recordingNotifier{}
+	publisher := &recordingPublisher{}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	scheduler.AddChangeNotifier(notifier)
+	scheduler.SetEventPublisher(publisher)
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("First update failed: %v", err)
+	}
+	lastUpdated := mockDataStore.lastUpdated
+
+	publisher.types, publisher.data = nil, nil
+	mockParser.unchanged = true
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Expected unchanged sources not to fail the update, got %v", err)
+	}
+
+	if mockDataStore.updateCount != 1 || mockDataStore.dataVersion.Version != 1 || len(mockDataStore.medicaments) != 2 {
+		t.Errorf("Expected the dataset to be kept, got %d updates and version %d", mockDataStore.updateCount, mockDataStore.dataVersion.Version)
+	}
+	if !mockDataStore.lastUpdated.Equal(lastUpdated) || mockDataStore.lastChecked.IsZero() {
+		t.Errorf("Expected only the last check to be recorded, got updated %v and checked %v", mockDataStore.lastUpdated, mockDataStore.lastChecked)
+	}
+	if len(mockDataStore.changeSets) != 0 || len(notifier.changeSets) != 0 {
+		t.Errorf("Expected no change set, got %d recorded and %d notified", len(mockDataStore.changeSets), len(notifier.changeSets))
+	}
+
+	if len(publisher.types) != 2 || publisher.types[1] != events.UpdateCompleted {
+		t.Fatalf("Expected update_started then update_completed, got %v", publisher.types)
+	}
+	completed := publisher.data[1].(events.UpdateCompletedData)
+	if !completed.Unchanged || completed.DataVersion != 1 || completed.Medicaments != 2 {
+		t.Errorf("Unexpected update_completed payload: %+v", completed)
+	}
+}
+
+func TestScheduler_ReportsStaleSources(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{stale: []string{"CIS_CIP_Dispo_Spec.txt"}}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if mockDataStore.report == nil || len(mockDataStore.report.StaleSourceFiles) != 1 || mockDataStore.report.StaleSourceFiles[0] != "CIS_CIP_Dispo_Spec.txt" {
+		t.Errorf("Expected the stale file in the quality report, got %+v", mockDataStore.report)
+	}
+}
+
+func TestScheduler_ReportsSkippedSources(t *testing.T) {
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{skipped: []string{"CIS_MITM.txt"}}
+
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if mockDataStore.report == nil || len(mockDataStore.report.SkippedSourceFiles) != 1 || mockDataStore.report.SkippedSourceFiles[0] != "CIS_MITM.txt" {
+		t.Errorf("Expected the skipped file in the quality report, got %+v", mockDataStore.report)
+	}
+}
+
+func TestScheduler_SavesSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.gob.gz")
+	scheduler := NewScheduler(&mockSchedulerDataStore{}, &mockSchedulerParser{})
+	scheduler.SetSnapshotPath(path)
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		t.Fatalf("Expected a snapshot after the update: %v", err)
+	}
+	if len(snap.Medicaments) != 2 || len(snap.Generiques) != 1 || len(snap.PresentationsCIP13Map) != 1 || snap.Report == nil {
+		t.Errorf("Unexpected snapshot content: %+v", snap)
+	}
+	if snap.DataVersion.Version != 1 || snap.DataVersion.Hash != "hash-1" {
+		t.Errorf("Expected the snapshot to keep data version 1, got %+v", snap.DataVersion)
+	}
+}
+
+func TestScheduler_VersionContinuesFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.gob.gz")
+	err := snapshot.Save(path, &snapshot.Snapshot{
+		Medicaments: []entities.Medicament{{Cis: 61266250, Denomination: "DOLIPRANE 1000 mg"}},
+		Report:      &interfaces.DataQualityReport{},
+		DataVersion: interfaces.DataVersion{Version: 41, Hash: "before-restart"},
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	mockDataStore := &mockSchedulerDataStore{}
+	scheduler := NewScheduler(mockDataStore, &mockSchedulerParser{})
+	scheduler.SetSnapshotPath(path)
+
+	if !scheduler.loadSnapshot() {
+		t.Fatal("Expected the snapshot to be loaded")
+	}
+	if version := mockDataStore.GetDataVersion(); version.Version != 41 || version.Hash != "before-restart" {
+		t.Errorf("Expected the snapshot version 41, got %+v", version)
+	}
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if version := mockDataStore.GetDataVersion(); version.Version != 42 || version.Hash != "hash-1" {
+		t.Errorf("Expected version 42 with the source hash, got %+v", version)
+	}
+	if len(mockDataStore.changeSets) != 1 || mockDataStore.changeSets[0].FromVersion != 41 {
+		t.Errorf("Expected the changes since the snapshot version, got %+v", mockDataStore.changeSets)
+	}
+}
+
+func TestScheduler_UnchangedRefreshAfterSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.gob.gz")
+	err := snapshot.Save(path, &snapshot.Snapshot{
+		Medicaments: []entities.Medicament{{Cis: 61266250, Denomination: "DOLIPRANE 1000 mg"}},
+		Report:      &interfaces.DataQualityReport{},
+		DataVersion: interfaces.DataVersion{Version: 41, Hash: "before-restart"},
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// The sources still have the content of the snapshot
+	mockDataStore := &mockSchedulerDataStore{}
+	mockParser := &mockSchedulerParser{hash: "before-restart"}
+	notifier := &recordingNotifier{}
+	scheduler := NewScheduler(mockDataStore, mockParser)
+	scheduler.SetSnapshotPath(path)
+	scheduler.AddChangeNotifier(notifier)
+
+	if !scheduler.loadSnapshot() {
+		t.Fatal("Expected the snapshot to be loaded")
+	}
+	if mockParser.restoredHash != "before-restart" {
+		t.Errorf("Expected the parser to be given the snapshot hash, got %q", mockParser.restoredHash)
+	}
+
+	if err := scheduler.updateData(); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if mockDataStore.updateCount != 1 || mockDataStore.dataVersion.Version != 41 {
+		t.Errorf("Expected the snapshot to be kept as version 41, got %d updates and %+v", mockDataStore.updateCount, mockDataStore.dataVersion)
+	}
+	if len(mockDataStore.changeSets) != 0 || len(notifier.changeSets) != 0 {
+		t.Errorf("Expected no change set, got %d recorded and %d notified", len(mockDataStore.changeSets), len(notifier.changeSets))
+	}
+	if mockDataStore.lastChecked.IsZero() {
+		t.Error("Expected the check to be recorded")
+	}
+}
+
+func TestScheduler_StartFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.gob.gz")
+	loadedAt := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	err := snapshot.Save(path, &snapshot.Snapshot{
+		Medicaments: []entities.Medicament{{Cis: 61266250, Denomination: "DOLIPRANE 1000 mg"}},
+		Generiques:  []entities.GeneriqueList{{GroupID: 7, Libelle: "PARACETAMOL 1000 mg"}},
+		Report:      &interfaces.DataQualityReport{},
+		DataVersion: interfaces.DataVersion{Version: 3, LoadedAt: loadedAt},
+	})
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// The source is unavailable, the snapshot is served anyway
+	mockDataStore := &mockSchedulerDataStore{}
+	scheduler := NewScheduler(mockDataStore, &mockSchedulerParser{shouldFail: true})
+	scheduler.SetSnapshotPath(path)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Expected start to succeed from the snapshot, got %v", err)
+	}
+	defer scheduler.Stop()
+
+	if mockDataStore.updateCount != 1 {
+		t.Errorf("Expected 1 update from the snapshot, got %d", mockDataStore.updateCount)
+	}
+	if mockDataStore.medicamentsMap[61266250].Denomination != "DOLIPRANE 1000 mg" {
+		t.Errorf("Expected the snapshot medicaments to be served, got %v", mockDataStore.medicamentsMap)
+	}
+	if mockDataStore.generiquesMap[7].Libelle != "PARACETAMOL 1000 mg" {
+		t.Errorf("Expected the snapshot generiques to be served, got %v", mockDataStore.generiquesMap)
+	}
+
+	// The data is as old as when the snapshot was loaded, not as the restart
+	if !mockDataStore.GetLastUpdated().Equal(loadedAt) || mockDataStore.GetDataVersion().Version != 3 {
+		t.Errorf("Expected version 3 loaded at %v, got %+v", loadedAt, mockDataStore.GetDataVersion())
+	}
+}
+
+func TestScheduler_StartWithUnreadableSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.gob.gz")
+	if err := os.WriteFile(path, []byte("corrupted"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	// Without a usable snapshot, the initial load blocks as before
+	mockDataStore := &mockSchedulerDataStore{}
+	scheduler := NewScheduler(mockDataStore, &mockSchedulerParser{})
+	scheduler.SetSnapshotPath(path)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Unexpected error during start: %v", err)
+	}
+	defer scheduler.Stop()
+
+	if len(mockDataStore.medicaments) != 2 {
+		t.Errorf("Expected the parsed medicaments, got %d", len(mockDataStore.medicaments))
+	}
+	if _, err := snapshot.Load(path); err != nil {
+		t.Errorf("Expected the snapshot to be replaced after the update, got %v", err)
+	}
+}
+
 func TestScheduler_PresentationMapsStored(t *testing.T) {
 	// Test that CIP7 and CIP13 maps are properly stored
 	mockDataStore := &mockSchedulerDataStore{}
@@ -0,0 +1,107 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"unicode"
+)
+
+// minFuzzySimilarity is the trigram similarity below which tokens are not considered a match
+const minFuzzySimilarity = 0.3
+
+// FuzzySearch returns the medicaments matching every query term with tolerance for typos,
+// most similar first. Letter tokens are compared with trigram similarity against the
+// denomination and substance tokens; numbers still have to match by prefix.
+func (idx *Index) FuzzySearch(query string) []Result {
+	results := idx.search(query, idx.fuzzyMatches)
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(
+			cmp.Compare(b.Similarity, a.Similarity),
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(a.Cis, b.Cis),
+		)
+	})
+
+	return results
+}
+
+// fuzzyMatches returns the tokens similar to term, weighted by their trigram similarity.
+// Prefix matches are always kept so that partially typed words still match.
+func (idx *Index) fuzzyMatches(term string) []termMatch {
+	weights := make(map[string]float64)
+	for _, match := range idx.prefixMatches(term) {
+		weights[match.token] = match.weight
+	}
+
+	if isFuzzySearchable(term) {
+		termTrigrams := trigramsOf(term)
+
+		shared := make(map[int32]int)
+		for _, trigram := range termTrigrams {
+			for _, pos := range idx.trigrams[trigram] {
+				shared[pos]++
+			}
+		}
+
+		for pos, count := range shared {
+			// Jaccard similarity of the trigram sets
+			similarity := float64(count) / float64(len(termTrigrams)+idx.trigramCounts[pos]-count)
+			if similarity < minFuzzySimilarity {
+				continue
+			}
+
+			token := idx.terms[pos]
+			weights[token] = max(weights[token], similarity)
+		}
+	}
+
+	matches := make([]termMatch, 0, len(weights))
+	for token, weight := range weights {
+		matches = append(matches, termMatch{token: token, weight: weight})
+	}
+
+	return matches
+}
+
+// buildTrigrams indexes the trigrams of the given tokens for fuzzy lookups
+func (idx *Index) buildTrigrams(tokens map[string]bool) {
+	idx.trigrams = make(map[string][]int32)
+	idx.trigramCounts = make([]int, len(idx.terms))
+
+	for pos, term := range idx.terms {
+		if !tokens[term] || !isFuzzySearchable(term) {
+			continue
+		}
+
+		termTrigrams := trigramsOf(term)
+		for _, trigram := range termTrigrams {
+			idx.trigrams[trigram] = append(idx.trigrams[trigram], int32(pos))
+		}
+		idx.trigramCounts[pos] = len(termTrigrams)
+	}
+}
+
+// isFuzzySearchable reports whether a token is long enough and made of letters
+func isFuzzySearchable(token string) bool {
+	runes := []rune(token)
+	return len(runes) >= 3 && unicode.IsLetter(runes[0])
+}
+
+// trigramsOf returns the distinct trigrams of a token, padded so that the
+// beginning of the word weighs more than the end
+func trigramsOf(token string) []string {
+	runes := []rune("  " + token + " ")
+
+	seen := make(map[string]bool, len(runes))
+	trigrams := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		trigram := string(runes[i : i+3])
+		if !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+
+	return trigrams
+}
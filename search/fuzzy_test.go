@@ -0,0 +1,77 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func TestTrigramsOf(t *testing.T) {
+	got := trigramsOf("abc")
+	expected := []string{"  a", " ab", "abc", "bc "}
+	if !slices.Equal(got, expected) {
+		t.Errorf("trigramsOf(\"abc\") = %q, expected %q", got, expected)
+	}
+
+	// Repeated trigrams are only counted once
+	if got := trigramsOf("aaaa"); len(got) != 4 {
+		t.Errorf("Expected 4 distinct trigrams for \"aaaa\", got %q", got)
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []int
+	}{
+		{"missing letter", "ibuprofne", []int{4}},
+		{"missing suffix", "ibuprofen", []int{4}},
+		{"swapped letters", "paracetmaol", []int{1, 2, 3}},
+		{"typo with number", "paracetamlo 500", []int{2, 3}},
+		{"glued dosage", "dafalgan 500mg", []int{3}},
+		{"substance typo", "codiene", []int{2}},
+		{"titulaire is not fuzzy", "upsq", []int{}},
+		{"numbers are not fuzzy", "paracetamol 600", []int{}},
+		{"unrelated", "xylocaine", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resultCIS(idx.FuzzySearch(tt.query))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("FuzzySearch(%q) = %v, expected %v", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFuzzySearchSimilarity(t *testing.T) {
+	idx := NewIndex([]entities.Medicament{
+		{Cis: 1, DenominationNormalized: "doliprane 1000 mg"},
+		{Cis: 2, DenominationNormalized: "dolipraneorodoz 500 mg"},
+	})
+
+	results := idx.FuzzySearch("doliprane")
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	// The exact match comes first with a perfect similarity
+	if results[0].Cis != 1 || results[0].Similarity != 1 {
+		t.Errorf("Expected exact match first with similarity 1, got %+v", results[0])
+	}
+	if results[1].Similarity >= 1 || results[1].Similarity <= 0 {
+		t.Errorf("Expected partial similarity for the second result, got %f", results[1].Similarity)
+	}
+
+	// A typo lowers the similarity but still matches
+	results = idx.FuzzySearch("dolipane")
+	if len(results) == 0 || results[0].Cis != 1 || results[0].Similarity >= 1 {
+		t.Errorf("Expected CIS 1 with partial similarity, got %+v", results)
+	}
+}
@@ -0,0 +1,276 @@
+// Package search provides the full-text index used to rank medicaments by relevance.
+// The index is immutable once built and is rebuilt on every data update, so it can be
+// shared between goroutines without locking.
+package search
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// Matches on a token that only starts with the query term count less than exact matches
+	prefixMatchWeight = 0.5
+)
+
+// Field weights applied to term frequencies
+const (
+	denominationWeight = 3.0
+	substanceWeight    = 2.0
+	titulaireWeight    = 1.0
+)
+
+// Result is a ranked search hit
+type Result struct {
+	Cis   int
+	Score float64
+	// Similarity is the mean, over the query terms, of how closely the best matching token
+	// of the medicament matches the term: 1 for an exact match, lower for prefix or fuzzy matches
+	Similarity float64
+}
+
+// termMatch is a vocabulary token matched by a query term, weighted by how closely it matches
+type termMatch struct {
+	token  string
+	weight float64
+}
+
+type posting struct {
+	doc int32
+	tf  float32 // Weighted term frequency
+}
+
+type document struct {
+	cis    int
+	length int
+}
+
+// Index is an inverted index over the medicament denomination, substances and titulaire
+type Index struct {
+	postings  map[string][]posting
+	terms     []string // Sorted vocabulary for prefix lookups
+	docs      []document
+	avgDocLen float64
+
+	// Fuzzy matching over denomination and substance tokens
+	trigrams      map[string][]int32 // Trigram -> positions in terms
+	trigramCounts []int              // Number of distinct trigrams of each term, 0 if not fuzzy-searchable
+
+	// Autocomplete over the whole denomination
+	suggestions     []Suggestion     // Sorted by normalized denomination
+	suggestionKeys  []string         // Normalized denomination of each suggestion
+	suggestionWords []suggestionWord // Denomination and substance tails starting at a word, sorted
+}
+
+// NewIndex builds an index over the given medicaments
+func NewIndex(medicaments []entities.Medicament) *Index {
+	idx := &Index{
+		postings: make(map[string][]posting),
+		docs:     make([]document, 0, len(medicaments)),
+	}
+
+	fuzzyTokens := make(map[string]bool)
+	totalLen := 0
+	for i, med := range medicaments {
+		frequencies := make(map[string]float32)
+		length := 0
+
+		add := func(text string, weight float32, fuzzy bool) {
+			for _, token := range Tokenize(text) {
+				frequencies[token] += weight
+				length++
+				if fuzzy {
+					fuzzyTokens[token] = true
+				}
+			}
+		}
+
+		add(med.DenominationNormalized, denominationWeight, true)
+		for _, comp := range med.Composition {
+			add(comp.DenominationSubstance, substanceWeight, true)
+		}
+		add(med.Titulaire, titulaireWeight, false)
+
+		for token, tf := range frequencies {
+			idx.postings[token] = append(idx.postings[token], posting{doc: int32(i), tf: tf})
+		}
+
+		idx.docs = append(idx.docs, document{cis: med.Cis, length: length})
+		totalLen += length
+
+		idx.suggestions = append(idx.suggestions, Suggestion{Cis: med.Cis, Denomination: med.Denomination})
+	}
+
+	if len(idx.docs) > 0 {
+		idx.avgDocLen = float64(totalLen) / float64(len(idx.docs))
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.terms = append(idx.terms, token)
+	}
+	slices.Sort(idx.terms)
+
+	idx.buildTrigrams(fuzzyTokens)
+	idx.buildSuggestions(medicaments)
+
+	return idx
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// Search returns the medicaments matching every query term, best match first.
+// A query term matches any indexed token it is a prefix of.
+func (idx *Index) Search(query string) []Result {
+	results := idx.search(query, idx.prefixMatches)
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Cis, b.Cis))
+	})
+
+	return results
+}
+
+// search scores the documents matching every query term, using expand to find
+// the vocabulary tokens each term matches
+func (idx *Index) search(query string, expand func(string) []termMatch) []Result {
+	queryTerms := Tokenize(query)
+	if len(queryTerms) == 0 || len(idx.docs) == 0 {
+		return nil
+	}
+
+	type docScore struct {
+		score      float64
+		similarity float64
+	}
+
+	var scores map[int32]docScore
+	for _, term := range queryTerms {
+		termScores, termSimilarities := idx.scoreTerm(expand(term))
+
+		if scores == nil {
+			scores = make(map[int32]docScore, len(termScores))
+			for doc, score := range termScores {
+				scores[doc] = docScore{score: score, similarity: termSimilarities[doc]}
+			}
+		} else {
+			// AND semantics: keep only documents matching every term
+			for doc, ds := range scores {
+				if termScore, ok := termScores[doc]; ok {
+					scores[doc] = docScore{score: ds.score + termScore, similarity: ds.similarity + termSimilarities[doc]}
+				} else {
+					delete(scores, doc)
+				}
+			}
+		}
+
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for doc, ds := range scores {
+		results = append(results, Result{
+			Cis:        idx.docs[doc].cis,
+			Score:      ds.score,
+			Similarity: ds.similarity / float64(len(queryTerms)),
+		})
+	}
+
+	return results
+}
+
+// prefixMatches returns the tokens starting with term
+func (idx *Index) prefixMatches(term string) []termMatch {
+	var matches []termMatch
+
+	start := sort.SearchStrings(idx.terms, term)
+	for _, token := range idx.terms[start:] {
+		if !strings.HasPrefix(token, term) {
+			break
+		}
+
+		weight := 1.0
+		if token != term {
+			weight = prefixMatchWeight
+		}
+		matches = append(matches, termMatch{token: token, weight: weight})
+	}
+
+	return matches
+}
+
+// scoreTerm computes the BM25 contribution of a single query term for every matching document,
+// along with the weight of the best token each document matched
+func (idx *Index) scoreTerm(matches []termMatch) (map[int32]float64, map[int32]float64) {
+	frequencies := make(map[int32]float64)
+	similarities := make(map[int32]float64)
+
+	for _, match := range matches {
+		for _, p := range idx.postings[match.token] {
+			frequencies[p.doc] += float64(p.tf) * match.weight
+			similarities[p.doc] = max(similarities[p.doc], match.weight)
+		}
+	}
+
+	n := float64(len(idx.docs))
+	df := float64(len(frequencies))
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+	scores := make(map[int32]float64, len(frequencies))
+	for doc, tf := range frequencies {
+		norm := k1 * (1 - b + b*float64(idx.docs[doc].length)/idx.avgDocLen)
+		scores[doc] = idf * tf * (k1 + 1) / (tf + norm)
+	}
+
+	return scores, similarities
+}
+
+// Tokenize folds accents, lowercases text and splits it on anything that is not a letter or a digit.
+// Digits and letters are also split apart so that "1000mg" matches "1000 mg".
+func Tokenize(text string) []string {
+	text = strings.ToLower(FoldAccents(text))
+
+	var tokens []string
+	start := -1
+	prevDigit := false
+	for i, r := range text {
+		isDigit := unicode.IsDigit(r)
+		if !isDigit && !unicode.IsLetter(r) {
+			if start >= 0 {
+				tokens = append(tokens, text[start:i])
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && isDigit != prevDigit {
+			tokens = append(tokens, text[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start = i
+		}
+		prevDigit = isDigit
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+
+	return tokens
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func testMedicaments() []entities.Medicament {
+	return []entities.Medicament{
+		{
+			Cis:                    1,
+			DenominationNormalized: "paracetamol biogaran 1000 mg, comprime",
+			Composition:            []entities.Composition{{DenominationSubstance: "PARACETAMOL"}},
+			Titulaire:              "BIOGARAN",
+		},
+		{
+			Cis:                    2,
+			DenominationNormalized: "paracetamol codeine arrow 500 mg/30 mg, comprime effervescent secable",
+			Composition: []entities.Composition{
+				{DenominationSubstance: "PARACETAMOL"},
+				{DenominationSubstance: "PHOSPHATE DE CODEINE HEMIHYDRATE"},
+			},
+			Titulaire: "ARROW GENERIQUES",
+		},
+		{
+			Cis:                    3,
+			DenominationNormalized: "dafalgan 500 mg, gelule",
+			Composition:            []entities.Composition{{DenominationSubstance: "PARACETAMOL"}},
+			Titulaire:              "UPSA",
+		},
+		{
+			Cis:                    4,
+			DenominationNormalized: "ibuprofene biogaran 400 mg, comprime",
+			Composition:            []entities.Composition{{DenominationSubstance: "IBUPROFENE"}},
+			Titulaire:              "BIOGARAN",
+		},
+	}
+}
+
+func resultCIS(results []Result) []int {
+	cis := make([]int, len(results))
+	for i, r := range results {
+		cis[i] = r.Cis
+	}
+	return cis
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"PARACETAMOL 500 mg, comprimé", []string{"paracetamol", "500", "mg", "comprime"}},
+		{"paracetamol+codeine", []string{"paracetamol", "codeine"}},
+		{"500 mg/30 mg", []string{"500", "mg", "30", "mg"}},
+		{"doliprane 1000mg", []string{"doliprane", "1000", "mg"}},
+		{"vitamine b12", []string{"vitamine", "b", "12"}},
+		{"  ", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Tokenize(tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("Tokenize(%q) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []int
+	}{
+		{"denomination and substance", "paracetamol", []int{1, 2, 3}},
+		{"substance only", "codeine", []int{2}},
+		{"titulaire", "upsa", []int{3}},
+		{"all terms must match", "paracetamol biogaran", []int{1}},
+		{"prefix match", "ibupro", []int{4}},
+		{"no match", "aspirine", []int{}},
+		{"empty query", "", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resultCIS(idx.Search(tt.query))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Search(%q) = %v, expected %v", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	// Denomination matches rank above substance-only matches,
+	// and shorter documents rank above longer ones
+	got := resultCIS(idx.Search("paracetamol"))
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected ranking [1 2 3], got %v", got)
+	}
+
+	// Exact token matches rank above prefix matches
+	idx = NewIndex([]entities.Medicament{
+		{Cis: 1, DenominationNormalized: "codeinex 10 mg"},
+		{Cis: 2, DenominationNormalized: "codeine 10 mg"},
+	})
+	got = resultCIS(idx.Search("codeine"))
+	if !slices.Equal(got, []int{2, 1}) {
+		t.Errorf("Expected exact match first, got %v", got)
+	}
+
+	// Scores are strictly positive and sorted
+	results := idx.Search("codeine")
+	for i, r := range results {
+		if r.Score <= 0 {
+			t.Errorf("Expected positive score, got %f", r.Score)
+		}
+		if i > 0 && results[i-1].Score < r.Score {
+			t.Errorf("Results not sorted by score: %v", results)
+		}
+	}
+}
+
+func TestEmptyIndex(t *testing.T) {
+	idx := NewIndex(nil)
+
+	if idx.Len() != 0 {
+		t.Errorf("Expected empty index, got %d documents", idx.Len())
+	}
+	if results := idx.Search("paracetamol"); len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Ligatures are not decomposed by NFD and have to be expanded explicitly
+var ligatureReplacer = strings.NewReplacer("œ", "oe", "Œ", "OE", "æ", "ae", "Æ", "AE")
+
+// FoldAccents removes diacritics so that "ibuprofène" and "IBUPROFENE" compare equal
+// once lowercased. The text is decomposed (NFD), combining marks are dropped and the
+// result is recomposed (NFC).
+func FoldAccents(text string) string {
+	if isASCII(text) {
+		return text
+	}
+
+	// transform.Chain keeps state and cannot be shared between goroutines
+	folder := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(folder, ligatureReplacer.Replace(text))
+	if err != nil {
+		return text
+	}
+
+	return folded
+}
+
+// Normalize prepares text for substring matching: accents are folded, the text is
+// lowercased and + is replaced with a space. It is applied to both the precomputed
+// normalized fields and user queries so that they always match identically.
+func Normalize(text string) string {
+	return strings.ReplaceAll(strings.ToLower(FoldAccents(text)), "+", " ")
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package search
+
+import "testing"
+
+func TestFoldAccents(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ibuprofène", "ibuprofene"},
+		{"PARACÉTAMOL", "PARACETAMOL"},
+		{"codéïne gélule", "codeine gelule"},
+		{"àâäéèêëïîôöùûüÿç", "aaaeeeeiioouuuyc"},
+		{"cœur", "coeur"},
+		{"ASCII 500 mg/ml", "ASCII 500 mg/ml"},
+	}
+
+	for _, tt := range tests {
+		if got := FoldAccents(tt.input); got != tt.expected {
+			t.Errorf("FoldAccents(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("IBUPROFÈNE+CAFÉINE 400 mg"); got != "ibuprofene cafeine 400 mg" {
+		t.Errorf("Normalize() = %q, expected %q", got, "ibuprofene cafeine 400 mg")
+	}
+}
+
+func TestSearchAccentInsensitive(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	accented := resultCIS(idx.Search("ibuprofène"))
+	plain := resultCIS(idx.Search("ibuprofene"))
+	if len(plain) == 0 || len(accented) != len(plain) {
+		t.Errorf("Search(\"ibuprofène\") = %v, expected %v", accented, plain)
+	}
+}
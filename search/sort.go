@@ -0,0 +1,101 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// Fields the medicaments can be sorted on
+const (
+	SortCIS          = "cis"
+	SortDenomination = "denomination"
+	SortDateAMM      = "dateAMM"
+	SortTitulaire    = "titulaire"
+)
+
+// SortFields lists the accepted sort fields
+var SortFields = []string{SortCIS, SortDenomination, SortDateAMM, SortTitulaire}
+
+// Order is a permutation of a medicaments snapshot sorted on one field, ascending.
+// It keeps the snapshot it was built from so positions always refer to the right slice.
+type Order struct {
+	medicaments []entities.Medicament
+	positions   []int32
+}
+
+// Orders holds one Order per sort field. Like Index it is immutable once built.
+type Orders map[string]*Order
+
+// NewOrders sorts the medicaments on every field of SortFields. Ties are broken by CIS,
+// and medicaments without a valid AMM date come last when sorting on dateAMM.
+func NewOrders(medicaments []entities.Medicament) Orders {
+	denominations := make([]string, len(medicaments))
+	titulaires := make([]string, len(medicaments))
+	datesAMM := make([]time.Time, len(medicaments))
+	for i, med := range medicaments {
+		denominations[i] = med.DenominationNormalized
+		titulaires[i] = strings.TrimSpace(Normalize(med.Titulaire))
+		datesAMM[i], _ = entities.ParseDate(med.DateAMM) // Zero time when missing
+	}
+
+	byCIS := func(a, b int32) int {
+		return cmp.Compare(medicaments[a].Cis, medicaments[b].Cis)
+	}
+
+	comparators := map[string]func(a, b int32) int{
+		SortCIS: byCIS,
+		SortDenomination: func(a, b int32) int {
+			return cmp.Or(cmp.Compare(denominations[a], denominations[b]), byCIS(a, b))
+		},
+		SortTitulaire: func(a, b int32) int {
+			return cmp.Or(cmp.Compare(titulaires[a], titulaires[b]), byCIS(a, b))
+		},
+		SortDateAMM: func(a, b int32) int {
+			if datesAMM[a].IsZero() != datesAMM[b].IsZero() {
+				if datesAMM[a].IsZero() {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Or(datesAMM[a].Compare(datesAMM[b]), byCIS(a, b))
+		},
+	}
+
+	orders := make(Orders, len(comparators))
+	for field, compare := range comparators {
+		positions := make([]int32, len(medicaments))
+		for i := range positions {
+			positions[i] = int32(i)
+		}
+		slices.SortFunc(positions, compare)
+		orders[field] = &Order{medicaments: medicaments, positions: positions}
+	}
+
+	return orders
+}
+
+// Len returns the number of sorted medicaments
+func (o *Order) Len() int {
+	return len(o.positions)
+}
+
+// At returns the i-th medicament, counting from the end when desc is set
+func (o *Order) At(i int, desc bool) entities.Medicament {
+	if desc {
+		i = len(o.positions) - 1 - i
+	}
+	return o.medicaments[o.positions[i]]
+}
+
+// Find returns the smallest ascending position i for which f(At(i, false)) is true, or Len()
+// if there is none. f must be false and then true along the order, as in sort.Search.
+func (o *Order) Find(f func(entities.Medicament) bool) int {
+	return sort.Search(len(o.positions), func(i int) bool {
+		return f(o.medicaments[o.positions[i]])
+	})
+}
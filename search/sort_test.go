@@ -0,0 +1,91 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func testSortMedicaments() []entities.Medicament {
+	return []entities.Medicament{
+		{Cis: 3, DenominationNormalized: "doliprane 1000 mg", Titulaire: " SANOFI", DateAMM: "20/10/2008"},
+		{Cis: 1, DenominationNormalized: "advil 200 mg", Titulaire: " Pfizer", DateAMM: ""},
+		{Cis: 4, DenominationNormalized: "doliprane 1000 mg", Titulaire: " ÉLÉA", DateAMM: "01/06/2021"},
+		{Cis: 2, DenominationNormalized: "zyrtec 10 mg", Titulaire: " BIOGARAN", DateAMM: "12/03/2015"},
+	}
+}
+
+func orderCIS(order *Order, desc bool) []int {
+	cis := make([]int, order.Len())
+	for i := range cis {
+		cis[i] = order.At(i, desc).Cis
+	}
+	return cis
+}
+
+func TestNewOrders(t *testing.T) {
+	orders := NewOrders(testSortMedicaments())
+
+	tests := []struct {
+		field    string
+		desc     bool
+		expected []int
+	}{
+		{SortCIS, false, []int{1, 2, 3, 4}},
+		{SortCIS, true, []int{4, 3, 2, 1}},
+		// Equal denominations are ordered by CIS
+		{SortDenomination, false, []int{1, 3, 4, 2}},
+		{SortDenomination, true, []int{2, 4, 3, 1}},
+		// Accents are folded and the leading space of the BDPM value is ignored
+		{SortTitulaire, false, []int{2, 4, 1, 3}},
+		// Missing dates come last in ascending order
+		{SortDateAMM, false, []int{3, 2, 4, 1}},
+		{SortDateAMM, true, []int{1, 4, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s desc=%v", tt.field, tt.desc), func(t *testing.T) {
+			order, ok := orders[tt.field]
+			if !ok {
+				t.Fatalf("Expected an order for %s", tt.field)
+			}
+			if got := orderCIS(order, tt.desc); fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewOrders_AllFields(t *testing.T) {
+	orders := NewOrders(nil)
+
+	for _, field := range SortFields {
+		order, ok := orders[field]
+		if !ok {
+			t.Fatalf("Expected an order for %s", field)
+		}
+		if order.Len() != 0 {
+			t.Errorf("Expected empty order for %s, got %d", field, order.Len())
+		}
+	}
+}
+
+func TestOrderFind(t *testing.T) {
+	order := NewOrders(testSortMedicaments())[SortCIS]
+
+	tests := []struct {
+		after    int
+		expected int
+	}{
+		{0, 0},
+		{2, 2},
+		{4, 4},
+	}
+
+	for _, tt := range tests {
+		if got := order.Find(func(med entities.Medicament) bool { return med.Cis > tt.after }); got != tt.expected {
+			t.Errorf("Find(cis > %d) = %d, expected %d", tt.after, got, tt.expected)
+		}
+	}
+}
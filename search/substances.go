@@ -0,0 +1,154 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// SubstanceIndex maps the active substances to the medicaments containing them.
+// Like Index it is immutable once built and rebuilt on every data update.
+type SubstanceIndex struct {
+	substances  []entities.Substance // Sorted by denomination
+	positions   map[int]int          // Substance code -> position in substances
+	medicaments map[int][]int        // Substance code -> sorted CIS
+	names       []string             // Normalized denomination of each substance, sorted
+	words       []substanceWord      // Words of the normalized denominations, sorted for prefix lookups
+}
+
+// substanceWord is a word of a substance denomination and the position of the substance
+type substanceWord struct {
+	word string
+	pos  int
+}
+
+// NewSubstanceIndex builds the substance index from the medicament compositions
+func NewSubstanceIndex(medicaments []entities.Medicament) *SubstanceIndex {
+	idx := &SubstanceIndex{
+		positions:   make(map[int]int),
+		medicaments: make(map[int][]int),
+	}
+
+	// Count the spellings of each code to pick the most common one
+	spellings := make(map[int]map[string]int)
+	for _, med := range medicaments {
+		seen := make(map[int]bool)
+		for _, comp := range med.Composition {
+			if comp.CodeSubstance == 0 || comp.DenominationSubstance == "" {
+				continue
+			}
+
+			if spellings[comp.CodeSubstance] == nil {
+				spellings[comp.CodeSubstance] = make(map[string]int)
+			}
+			spellings[comp.CodeSubstance][comp.DenominationSubstance]++
+
+			// A substance can appear in several elements of the same medicament
+			if !seen[comp.CodeSubstance] {
+				seen[comp.CodeSubstance] = true
+				idx.medicaments[comp.CodeSubstance] = append(idx.medicaments[comp.CodeSubstance], med.Cis)
+			}
+		}
+	}
+
+	idx.substances = make([]entities.Substance, 0, len(spellings))
+	for code, names := range spellings {
+		denomination := ""
+		for name, count := range names {
+			if count > names[denomination] || (count == names[denomination] && name < denomination) {
+				denomination = name
+			}
+		}
+
+		slices.Sort(idx.medicaments[code])
+		idx.substances = append(idx.substances, entities.Substance{
+			Code:             code,
+			Denomination:     denomination,
+			MedicamentsCount: len(idx.medicaments[code]),
+		})
+	}
+
+	slices.SortFunc(idx.substances, func(a, b entities.Substance) int {
+		return cmp.Or(cmp.Compare(Normalize(a.Denomination), Normalize(b.Denomination)), cmp.Compare(a.Code, b.Code))
+	})
+
+	idx.names = make([]string, len(idx.substances))
+	for i, substance := range idx.substances {
+		idx.positions[substance.Code] = i
+		idx.names[i] = Normalize(substance.Denomination)
+		for _, word := range Tokenize(idx.names[i]) {
+			idx.words = append(idx.words, substanceWord{word: word, pos: i})
+		}
+	}
+
+	slices.SortFunc(idx.words, func(a, b substanceWord) int {
+		return cmp.Or(cmp.Compare(a.word, b.word), cmp.Compare(a.pos, b.pos))
+	})
+
+	return idx
+}
+
+// Substances returns every substance sorted by denomination
+func (idx *SubstanceIndex) Substances() []entities.Substance {
+	return idx.substances
+}
+
+// Get returns the substance with the given code
+func (idx *SubstanceIndex) Get(code int) (entities.Substance, bool) {
+	pos, ok := idx.positions[code]
+	if !ok {
+		return entities.Substance{}, false
+	}
+	return idx.substances[pos], true
+}
+
+// Medicaments returns the CIS of the medicaments containing the substance, in ascending order
+func (idx *SubstanceIndex) Medicaments(code int) []int {
+	return idx.medicaments[code]
+}
+
+// Suggest returns the substances whose denomination, or one of its words, starts with prefix.
+// Denominations starting with prefix come first, then the most used substances. Both lookups
+// are binary searches in the names and words sorted when the index is built.
+func (idx *SubstanceIndex) Suggest(prefix string, limit int) []entities.Substance {
+	prefix = strings.TrimSpace(Normalize(prefix))
+	if prefix == "" || limit <= 0 {
+		return nil
+	}
+
+	var leading, others []int
+	seen := make(map[int]bool)
+
+	for i := sort.SearchStrings(idx.names, prefix); i < len(idx.names) && strings.HasPrefix(idx.names[i], prefix); i++ {
+		leading = append(leading, i)
+		seen[i] = true
+	}
+
+	start := sort.Search(len(idx.words), func(i int) bool { return idx.words[i].word >= prefix })
+	for i := start; i < len(idx.words) && strings.HasPrefix(idx.words[i].word, prefix); i++ {
+		if pos := idx.words[i].pos; !seen[pos] {
+			others = append(others, pos)
+			seen[pos] = true
+		}
+	}
+
+	// Most used substances first, in alphabetical order on ties
+	byUsage := func(a, b int) int {
+		return cmp.Or(cmp.Compare(idx.substances[b].MedicamentsCount, idx.substances[a].MedicamentsCount), cmp.Compare(a, b))
+	}
+	slices.SortFunc(leading, byUsage)
+	slices.SortFunc(others, byUsage)
+
+	results := make([]entities.Substance, 0, min(limit, len(leading)+len(others)))
+	for _, pos := range append(leading, others...) {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, idx.substances[pos])
+	}
+
+	return results
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func testSubstanceMedicaments() []entities.Medicament {
+	return []entities.Medicament{
+		{
+			Cis: 1,
+			Composition: []entities.Composition{
+				{CodeSubstance: 2202, DenominationSubstance: "PARACÉTAMOL"},
+			},
+		},
+		{
+			Cis: 2,
+			Composition: []entities.Composition{
+				{CodeSubstance: 2202, DenominationSubstance: "PARACETAMOL"},
+				{CodeSubstance: 1240, DenominationSubstance: "PHOSPHATE DE CODÉINE HÉMIHYDRATÉ"},
+			},
+		},
+		{
+			Cis: 3,
+			Composition: []entities.Composition{
+				// Same substance in two elements of the medicament
+				{ElementPharmaceutique: "comprimé", CodeSubstance: 2202, DenominationSubstance: "PARACETAMOL"},
+				{ElementPharmaceutique: "gélule", CodeSubstance: 2202, DenominationSubstance: "PARACETAMOL"},
+			},
+		},
+		{
+			Cis: 4,
+			Composition: []entities.Composition{
+				{CodeSubstance: 1240, DenominationSubstance: "CODEINE"},
+				{CodeSubstance: 0, DenominationSubstance: "SANS CODE"},
+			},
+		},
+	}
+}
+
+func TestSubstanceIndex(t *testing.T) {
+	idx := NewSubstanceIndex(testSubstanceMedicaments())
+
+	substances := idx.Substances()
+	if len(substances) != 2 {
+		t.Fatalf("Expected 2 substances, got %+v", substances)
+	}
+
+	// The most common spelling is kept and substances are sorted by denomination
+	// Ties between spellings are broken alphabetically
+	if substances[0].Code != 1240 || substances[0].Denomination != "CODEINE" || substances[0].MedicamentsCount != 2 {
+		t.Errorf("Unexpected first substance: %+v", substances[0])
+	}
+	if substances[1].Code != 2202 || substances[1].Denomination != "PARACETAMOL" || substances[1].MedicamentsCount != 3 {
+		t.Errorf("Unexpected second substance: %+v", substances[1])
+	}
+
+	if got := idx.Medicaments(2202); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Medicaments(2202) = %v, expected [1 2 3]", got)
+	}
+
+	if _, ok := idx.Get(9999); ok {
+		t.Error("Expected unknown substance code not to be found")
+	}
+	if got := idx.Medicaments(9999); len(got) != 0 {
+		t.Errorf("Expected no medicaments for unknown substance, got %v", got)
+	}
+}
+
+func TestSubstanceIndexSuggest(t *testing.T) {
+	idx := NewSubstanceIndex(testSubstanceMedicaments())
+
+	tests := []struct {
+		name     string
+		prefix   string
+		expected []int
+	}{
+		{"denomination prefix", "parac", []int{2202}},
+		{"accented prefix", "PARACÉ", []int{2202}},
+		{"word prefix", "cod", []int{1240}},
+		{"no match", "amox", []int{}},
+		{"empty", "  ", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int{}
+			for _, s := range idx.Suggest(tt.prefix, 10) {
+				got = append(got, s.Code)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Suggest(%q) = %v, expected %v", tt.prefix, got, tt.expected)
+			}
+		})
+	}
+
+	if got := idx.Suggest("p", 1); len(got) != 1 {
+		t.Errorf("Expected suggestions to be limited to 1, got %d", len(got))
+	}
+}
+
+func TestSubstanceIndexSuggestOrder(t *testing.T) {
+	composition := func(code int, name string) []entities.Composition {
+		return []entities.Composition{{CodeSubstance: code, DenominationSubstance: name}}
+	}
+	idx := NewSubstanceIndex([]entities.Medicament{
+		{Cis: 1, Composition: composition(1, "CODEINE")},
+		{Cis: 2, Composition: composition(2, "CODEINE BASE")},
+		{Cis: 3, Composition: composition(2, "CODEINE BASE")},
+		{Cis: 4, Composition: composition(3, "PHOSPHATE DE CODEINE")},
+		{Cis: 5, Composition: composition(3, "PHOSPHATE DE CODEINE")},
+		{Cis: 6, Composition: composition(3, "PHOSPHATE DE CODEINE")},
+	})
+
+	// Denominations starting with the prefix come first, each group by number of medicaments
+	got := []int{}
+	for _, s := range idx.Suggest("cod", 10) {
+		got = append(got, s.Code)
+	}
+	if !slices.Equal(got, []int{2, 1, 3}) {
+		t.Errorf("Suggest(\"cod\") = %v, expected [2 1 3]", got)
+	}
+}
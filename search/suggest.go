@@ -0,0 +1,125 @@
+package search
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// Suggestion is an autocomplete entry for a medicament denomination
+type Suggestion struct {
+	Cis          int    `json:"cis"`
+	Denomination string `json:"elementPharmaceutique"`
+}
+
+// suggestionWord is the tail of a suggestion key or substance starting at one of its words,
+// and the position of the medicament in suggestions
+type suggestionWord struct {
+	tail string
+	pos  int
+}
+
+// suggestionKey joins the tokens of text with single spaces, so that prefixes match whatever
+// the punctuation and spacing of the denomination
+func suggestionKey(text string) string {
+	return strings.Join(Tokenize(text), " ")
+}
+
+// buildSuggestions sorts the denominations, and their tails starting at each word of the
+// denomination and of its substances, so that prefix lookups are a binary search
+func (idx *Index) buildSuggestions(medicaments []entities.Medicament) {
+	keys := make(map[int]string, len(medicaments))
+	for _, med := range medicaments {
+		keys[med.Cis] = suggestionKey(med.DenominationNormalized)
+	}
+
+	slices.SortFunc(idx.suggestions, func(a, b Suggestion) int {
+		return cmp.Or(cmp.Compare(keys[a.Cis], keys[b.Cis]), cmp.Compare(a.Cis, b.Cis))
+	})
+
+	positions := make(map[int]int, len(idx.suggestions))
+	idx.suggestionKeys = make([]string, len(idx.suggestions))
+	for i, suggestion := range idx.suggestions {
+		idx.suggestionKeys[i] = keys[suggestion.Cis]
+		positions[suggestion.Cis] = i
+	}
+
+	for _, med := range medicaments {
+		pos := positions[med.Cis]
+		for i, tail := range wordTails(keys[med.Cis]) {
+			if i == 0 {
+				continue // The whole denomination is already in suggestionKeys
+			}
+			idx.suggestionWords = append(idx.suggestionWords, suggestionWord{tail: tail, pos: pos})
+		}
+		for _, comp := range med.Composition {
+			for _, tail := range wordTails(suggestionKey(comp.DenominationSubstance)) {
+				idx.suggestionWords = append(idx.suggestionWords, suggestionWord{tail: tail, pos: pos})
+			}
+		}
+	}
+
+	slices.SortFunc(idx.suggestionWords, func(a, b suggestionWord) int {
+		return cmp.Or(cmp.Compare(a.tail, b.tail), cmp.Compare(a.pos, b.pos))
+	})
+}
+
+// wordTails returns the suffixes of a key starting at each of its words, the whole key first
+func wordTails(key string) []string {
+	if key == "" {
+		return nil
+	}
+	tails := []string{key}
+	for i := range len(key) {
+		if key[i] == ' ' {
+			tails = append(tails, key[i+1:])
+		}
+	}
+	return tails
+}
+
+// Suggest returns up to limit medicaments for a partially typed denomination.
+// Denominations starting with the query come first in alphabetical order, then those where
+// it starts one of the words of the denomination or of a substance. Both lookups are binary
+// searches in the keys sorted when the index is built.
+func (idx *Index) Suggest(query string, limit int) []Suggestion {
+	prefix := suggestionKey(query)
+	if prefix == "" || limit <= 0 {
+		return nil
+	}
+
+	suggestions := make([]Suggestion, 0, limit)
+	seen := make(map[int]bool, limit)
+
+	start := sort.SearchStrings(idx.suggestionKeys, prefix)
+	for i := start; i < len(idx.suggestionKeys) && len(suggestions) < limit; i++ {
+		if !strings.HasPrefix(idx.suggestionKeys[i], prefix) {
+			break
+		}
+		suggestions = append(suggestions, idx.suggestions[i])
+		seen[i] = true
+	}
+
+	var words []int
+	start = sort.Search(len(idx.suggestionWords), func(i int) bool { return idx.suggestionWords[i].tail >= prefix })
+	for i := start; i < len(idx.suggestionWords) && len(suggestions)+len(words) < limit; i++ {
+		word := idx.suggestionWords[i]
+		if !strings.HasPrefix(word.tail, prefix) {
+			break
+		}
+		if !seen[word.pos] {
+			seen[word.pos] = true
+			words = append(words, word.pos)
+		}
+	}
+
+	slices.Sort(words)
+	for _, pos := range words {
+		suggestions = append(suggestions, idx.suggestions[pos])
+	}
+
+	return suggestions
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func suggestionCIS(suggestions []Suggestion) []int {
+	cis := make([]int, len(suggestions))
+	for i, s := range suggestions {
+		cis[i] = s.Cis
+	}
+	return cis
+}
+
+func TestSuggest(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	tests := []struct {
+		name     string
+		query    string
+		limit    int
+		expected []int
+	}{
+		{"denomination prefix", "dafal", 10, []int{3}},
+		{"prefix across words", "paracetamol cod", 10, []int{2}},
+		{"accented prefix", "DAFALGAN 500 MG, GÉL", 10, []int{3}},
+		{"word prefix", "biogaran", 10, []int{1, 4}},
+		{"words inside the denomination", "500mg", 10, []int{2, 3}},
+		{"substance only", "codeine", 10, []int{2}},
+		{"limited", "paracetamol", 1, []int{1}},
+		{"no match", "xylocaine", 10, []int{}},
+		{"blank", " + ", 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := suggestionCIS(idx.Suggest(tt.query, tt.limit))
+			if tt.expected != nil {
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Suggest(%q, %d) = %v, expected %v", tt.query, tt.limit, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSuggestOrder(t *testing.T) {
+	idx := NewIndex(testMedicaments())
+
+	// Denominations starting with the query come first, alphabetically, then
+	// DAFALGAN which only matches through its substance
+	got := suggestionCIS(idx.Suggest("paracetamol", 10))
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Suggest(\"paracetamol\") = %v, expected [1 2 3]", got)
+	}
+}
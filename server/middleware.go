@@ -21,6 +21,12 @@ import (
 var (
 	medicamentsParams = []string{"search", "page", "cip"}
 	generiquesParams  = []string{"libelle"}
+
+	// Filters combinable with page or search on /v1/medicaments
+	medicamentsFilterParams = []string{
+		"mitm", "surveillanceRenforcee", "formePharmaceutique", "voiesAdministration", "titulaire",
+		"etatComercialisation", "statusAutorisation", "typeProcedure", "dateAMMFrom", "dateAMMTo",
+	}
 )
 
 const (
@@ -269,6 +275,7 @@ func getTokenCost(r *http.Request) int64 {
 			v1MedicamentsPrefix   = "/v1/medicaments/"
 			v1PresentationsPrefix = "/v1/presentations/"
 			v1GeneriquesPrefix    = "/v1/generiques/"
+			v1SubstancesPrefix    = "/v1/substances/"
 		)
 
 		// Match /v1/presentations/{id}
@@ -277,7 +284,7 @@ func getTokenCost(r *http.Request) int64 {
 			return 5
 		}
 
-		// Match /v1/medicaments/{cis} (excludes /v1/medicaments and /v1/medicaments/export/*)
+		// Match /v1/medicaments/{cis} and /v1/medicaments/{cis}/alerts (excludes /v1/medicaments and /v1/medicaments/export/*)
 		if len(requestPath) > len(v1MedicamentsPrefix) &&
 			requestPath[:len(v1MedicamentsPrefix)] == v1MedicamentsPrefix &&
 			!strings.HasPrefix(requestPath[len(v1MedicamentsPrefix):], "export") {
@@ -287,23 +294,44 @@ func getTokenCost(r *http.Request) int64 {
 		// Matches /v1/generiques/{groupID}
 		if len(requestPath) > len(v1GeneriquesPrefix) &&
 			requestPath[:len(v1GeneriquesPrefix)] == v1GeneriquesPrefix {
+			// Embedding the full medicaments of the group costs like a page of medicaments
+			if strings.Contains(q.Get("expand"), "medicaments") {
+				return 20
+			}
 			return 5
 		}
 
+		// Matches /v1/substances/{code}/medicaments
+		if strings.HasPrefix(requestPath, v1SubstancesPrefix) {
+			return 20
+		}
+
 		switch requestPath {
 		case "/v1/medicaments/export":
 			// Full medicament export - expensive operation
 			return 200
 
 		case "/v1/medicaments":
+			// Cursor pages are bounded by pageSize like offset pages
+			if q.Has("cursor") {
+				return 20
+			}
+
+			// Filters without page or search return their first page
+			if HasAnyParam(q, medicamentsFilterParams) && q.Get("search") == "" && q.Get("page") == "" && q.Get("cip") == "" {
+				return 20
+			}
+
+			// Ranked search results are paginated with page
+			if q.Get("search") != "" && q.Get("cip") == "" {
+				return 50
+			}
+
 			// Ensure only one parameter is present
 			if !HasSingleParam(q, medicamentsParams) {
 				return 5 // Default for invalid multi-param requests
 			}
 
-			if q.Get("search") != "" {
-				return 50
-			}
 			if q.Get("page") != "" {
 				return 20
 			}
@@ -324,6 +352,28 @@ func getTokenCost(r *http.Request) int64 {
 			}
 
 			return 5 // Default for /v1/generiques without recognized params
+		case "/v1/disponibilites":
+			if q.Get("cis") != "" {
+				return 10
+			}
+
+			return 20
+		case "/v1/suggest":
+			// Called on every keystroke, returns a few denominations from a precomputed sorted index
+			return 5
+		case "/v1/substances":
+			// Autocomplete returns a handful of substances, the full list is larger
+			if q.Get("search") != "" {
+				return 10
+			}
+
+			return 20
+		case "/v1/events":
+			// Charged once per connection, streams are capped per IP and overall by the broker
+			return 20
+		case "/v1/changes":
+			// Retained change sets are precomputed, their size depends on the updates
+			return 20
 		case "/v1/health", "/health":
 			// Health endpoint has no parameters
 			return 5
@@ -364,7 +414,8 @@ func getTokenCost(r *http.Request) int64 {
 }
 
 // HasSingleParam ensures exactly one of the specified parameters is present in the query.
-// Returns false if zero or multiple parameters are present.
+// Returns false if zero or multiple parameters are present. Parameters outside allowedParams,
+// such as the /v1/medicaments filters, are not counted.
 func HasSingleParam(q url.Values, allowedParams []string) bool {
 	count := 0
 	for _, param := range allowedParams {
@@ -375,6 +426,16 @@ func HasSingleParam(q url.Values, allowedParams []string) bool {
 	return count == 1
 }
 
+// HasAnyParam reports whether at least one of the specified parameters is present in the query
+func HasAnyParam(q url.Values, params []string) bool {
+	for _, param := range params {
+		if q.Get(param) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // RateLimitHandler implements rate limiting using token bucket
 func RateLimitHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -24,16 +24,43 @@ func TestGetTokenCost(t *testing.T) {
 		{"V1 search query", "/v1/medicaments", "search=paracetamol", 50},
 		{"V1 CIP query", "/v1/medicaments", "cip=1234567", 10},
 		{"V1 medicaments by CIS (path param)", "/v1/medicaments/12345678", "", 10},
+		{"V1 medicament alerts", "/v1/medicaments/12345678/alerts", "", 10},
+		{"V1 mitm listing", "/v1/medicaments", "mitm=true", 20},
+		{"V1 page query with mitm filter", "/v1/medicaments", "page=1&mitm=true", 20},
+		{"V1 search query with mitm filter", "/v1/medicaments", "search=paracetamol&mitm=true", 50},
+		{"V1 filtered listing", "/v1/medicaments", "titulaire=biogaran&voiesAdministration=orale", 20},
+		{"V1 page query with filters", "/v1/medicaments", "page=1&etatComercialisation=commercialisee&dateAMMFrom=2020-01-01", 20},
+		{"V1 page query with sort", "/v1/medicaments", "page=1&sort=-dateAMM", 20},
+		{"V1 first cursor page", "/v1/medicaments", "cursor=&pageSize=100", 20},
+		{"V1 cursor page with filters", "/v1/medicaments", "cursor=eyJ2IjoxLCJjIjoxfQ&titulaire=biogaran", 20},
+		{"V1 filters with sort", "/v1/medicaments", "titulaire=biogaran&sort=denomination", 20},
+		{"V1 search query with filters", "/v1/medicaments", "search=paracetamol&surveillanceRenforcee=true", 50},
+		{"V1 search query with page", "/v1/medicaments", "search=paracetamol&page=2&pageSize=50", 50},
+		{"V1 fuzzy search query", "/v1/medicaments", "search=dolipane&fuzzy=true", 50},
 		{"V1 medicaments default", "/v1/medicaments", "", 5},
 
 		// V1 Generiques endpoint
 		{"V1 generiques libelle", "/v1/generiques", "libelle=paracetamol", 30},
 		{"V1 generiques by group ID (path param)", "/v1/generiques/1", "", 5},
+		{"V1 generiques by group ID with references", "/v1/generiques/1", "expand=", 5},
+		{"V1 generiques by group ID with medicaments", "/v1/generiques/1", "expand=medicaments", 20},
+		{"V1 medicament by CIS with generique groups", "/v1/medicaments/12345678", "expand=generiqueGroups", 10},
 		{"V1 generiques default", "/v1/generiques", "", 5},
 
 		// V1 Presentations endpoint (now uses path parameter)
 		{"V1 presentations", "/v1/presentations/1234567", "", 5},
 
+		// V1 Disponibilites endpoint
+		{"V1 disponibilites", "/v1/disponibilites", "", 20},
+		{"V1 disponibilites by status", "/v1/disponibilites", "status=1", 20},
+		{"V1 disponibilites by CIS", "/v1/disponibilites", "cis=60002283", 10},
+		{"V1 suggest", "/v1/suggest", "q=dolip", 5},
+		{"V1 changes", "/v1/changes", "since=12", 20},
+		{"V1 events", "/v1/events", "", 20},
+		{"V1 substances", "/v1/substances", "", 20},
+		{"V1 substances autocomplete", "/v1/substances", "search=amox", 10},
+		{"V1 substance medicaments", "/v1/substances/2202/medicaments", "page=2", 20},
+
 		// Legacy endpoints (for backward compatibility)
 		{"Legacy database", "/database", "", 200},
 		{"Legacy database page", "/database/1", "", 20},
@@ -48,7 +75,8 @@ func TestGetTokenCost(t *testing.T) {
 
 		// ===== EDGE CASES =====
 		// Multi-parameter scenarios (should return default 5)
-		{"V1 medicaments page+search", "/v1/medicaments", "page=1&search=test", 5},
+		{"V1 medicaments page+cip", "/v1/medicaments", "page=1&cip=1234567", 5},
+		{"V1 medicaments search+cip", "/v1/medicaments", "search=test&cip=1234567", 5},
 		{"V1 generiques unknown param", "/v1/generiques", "unknown=value", 5},
 
 		// Invalid parameter values (cost based on param type, handler validates value)
@@ -93,6 +121,7 @@ func TestHasSingleParam(t *testing.T) {
 		{"Param not in allowed list", "other=value", []string{"page", "search"}, false},
 		{"Empty string param", "param=", []string{"param"}, false},
 		{"Empty allowed list", "param=1", []string{}, false},
+		{"Filters are not counted", "page=1&mitm=true&titulaire=biogaran", []string{"page", "search", "cip"}, true},
 	}
 
 	for _, tt := range tests {
@@ -108,6 +137,29 @@ func TestHasSingleParam(t *testing.T) {
 	}
 }
 
+func TestHasAnyParam(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		params   []string
+		expected bool
+	}{
+		{"One param present", "titulaire=biogaran", []string{"mitm", "titulaire"}, true},
+		{"Several params present", "mitm=true&titulaire=biogaran", []string{"mitm", "titulaire"}, true},
+		{"No params present", "page=1", []string{"mitm", "titulaire"}, false},
+		{"Empty string param", "mitm=", []string{"mitm"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			if result := HasAnyParam(values, tt.params); result != tt.expected {
+				t.Errorf("Expected %v for query %s with params %v, got %v", tt.expected, tt.query, tt.params, result)
+			}
+		})
+	}
+}
+
 func TestBlockDirectAccessMiddleware_WithAllowDirectAccess(t *testing.T) {
 	// Create handler that sets a flag
 	handlerCalled := false
@@ -11,12 +11,14 @@ import (
 
 	"github.com/giygas/medicaments-api/config"
 	"github.com/giygas/medicaments-api/data"
+	"github.com/giygas/medicaments-api/events"
 	"github.com/giygas/medicaments-api/handlers"
 	"github.com/giygas/medicaments-api/health"
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/metrics"
 	"github.com/giygas/medicaments-api/validation"
+	"github.com/giygas/medicaments-api/webhooks"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -112,9 +114,15 @@ func (s *Server) setupRoutes() {
 	s.router.Get("/v1/medicaments/export", s.httpHandler.ExportMedicaments)
 	s.router.Get("/v1/medicaments", s.httpHandler.ServeMedicamentsV1)
 	s.router.Get("/v1/medicaments/{cis}", s.httpHandler.FindMedicamentByCIS)
+	s.router.Get("/v1/medicaments/{cis}/alerts", s.httpHandler.ServeMedicamentAlertsV1)
 	s.router.Get("/v1/presentations/{cip}", s.httpHandler.ServePresentationsV1)
 	s.router.Get("/v1/generiques/{groupID}", s.httpHandler.FindGeneriquesByGroupID)
 	s.router.Get("/v1/generiques", s.httpHandler.ServeGeneriquesV1)
+	s.router.Get("/v1/disponibilites", s.httpHandler.ServeDisponibilitesV1)
+	s.router.Get("/v1/suggest", s.httpHandler.ServeSuggestV1)
+	s.router.Get("/v1/substances", s.httpHandler.ServeSubstancesV1)
+	s.router.Get("/v1/substances/{code}/medicaments", s.httpHandler.ServeSubstanceMedicamentsV1)
+	s.router.Get("/v1/changes", s.httpHandler.ServeChangesV1)
 	s.router.Get("/v1/diagnostics", s.httpHandler.ServeDiagnosticsV1)
 
 	// Will get a 404 otherwise
@@ -122,6 +130,22 @@ func (s *Server) setupRoutes() {
 
 }
 
+// MountEvents exposes the dataset update event stream at /v1/events.
+// Open streams are ended when the server shuts down so that the graceful shutdown does not wait for them.
+func (s *Server) MountEvents(broker *events.Broker) {
+	s.router.Get("/v1/events", broker.ServeHTTP)
+	s.server.RegisterOnShutdown(broker.Close)
+}
+
+// MountWebhooksAdmin exposes the webhook subscription management under /admin/webhooks.
+// Nothing is mounted when no ADMIN_TOKEN is configured.
+func (s *Server) MountWebhooksAdmin(dispatcher *webhooks.Dispatcher) {
+	if s.config.AdminToken == "" {
+		return
+	}
+	s.router.Mount("/admin/webhooks", dispatcher.AdminRoutes(s.config.AdminToken))
+}
+
 // setupDocumentationRoutes configures documentation and static file routes
 func (s *Server) setupDocumentationRoutes() {
 	// Serve documentation with caching
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"net/http"
 	"net/http/httptest"
@@ -10,10 +11,12 @@ import (
 
 	"github.com/giygas/medicaments-api/config"
 	"github.com/giygas/medicaments-api/data"
+	"github.com/giygas/medicaments-api/events"
 	"github.com/giygas/medicaments-api/handlers"
 	"github.com/giygas/medicaments-api/health"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/validation"
+	"github.com/giygas/medicaments-api/webhooks"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -253,6 +256,91 @@ func TestSetupRoutes(t *testing.T) {
 	}
 }
 
+// TestMountWebhooksAdmin tests that the admin routes are only mounted with an admin token
+func TestMountWebhooksAdmin(t *testing.T) {
+	logging.InitLogger("")
+
+	token := strings.Repeat("t", 32)
+	tests := []struct {
+		name           string
+		adminToken     string
+		expectedStatus int
+	}{
+		{"no admin token", "", http.StatusNotFound},
+		{"admin token", token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Port:               "8080",
+				Address:            "localhost",
+				Env:                config.EnvTest,
+				LogLevel:           "info",
+				MaxRequestBody:     1048576,
+				MaxHeaderSize:      1048576,
+				DisableRateLimiter: true,
+				AdminToken:         tt.adminToken,
+			}
+
+			srv := NewServer(cfg, data.NewDataContainer())
+			srv.MountWebhooksAdmin(webhooks.NewDispatcher(http.DefaultClient))
+
+			req := httptest.NewRequest("GET", "/admin/webhooks", nil)
+			req.RemoteAddr = "127.0.0.1:1234"
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+			srv.router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+// TestMountEvents tests that the event stream is flushed through the middleware stack
+func TestMountEvents(t *testing.T) {
+	logging.InitLogger("")
+
+	cfg := &config.Config{
+		Port:               "8080",
+		Address:            "localhost",
+		Env:                config.EnvTest,
+		LogLevel:           "info",
+		MaxRequestBody:     1048576,
+		MaxHeaderSize:      1048576,
+		DisableRateLimiter: true,
+	}
+
+	srv := NewServer(cfg, data.NewDataContainer())
+	broker := events.NewBroker(10)
+	srv.MountEvents(broker)
+
+	ts := httptest.NewServer(srv.router)
+	t.Cleanup(ts.Close)
+	t.Cleanup(broker.Close)
+
+	resp, err := http.Get(ts.URL + "/v1/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", ct)
+	}
+
+	// The first message only arrives if the logging and metrics wrappers let it be flushed
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "retry:") {
+		t.Errorf("Expected the retry delay to be flushed, got %q (%v)", line, err)
+	}
+}
+
 // TestServerLifecycle tests server start and shutdown
 func TestServerLifecycle(t *testing.T) {
 	// Initialize logging for tests
@@ -0,0 +1,121 @@
+// Package snapshot persists the parsed dataset to disk so that the server can serve
+// the last good data at startup, before the first download and parse complete.
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giygas/medicaments-api/interfaces"
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// formatVersion is increased when the snapshot content changes incompatibly.
+// Snapshots of another version are ignored and replaced at the next update.
+// Version 2 added the data version.
+const formatVersion = 2
+
+// ErrNotFound is returned by Load when no snapshot has been saved yet
+var ErrNotFound = errors.New("snapshot not found")
+
+// Snapshot is the parsed dataset as it was passed to DataStore.UpdateData.
+// The medicaments and generiques maps are rebuilt from the slices on load.
+type Snapshot struct {
+	FormatVersion         int
+	SavedAt               time.Time
+	Medicaments           []entities.Medicament
+	Generiques            []entities.GeneriqueList
+	PresentationsCIP7Map  map[int]entities.Presentation
+	PresentationsCIP13Map map[int]entities.Presentation
+	Report                *interfaces.DataQualityReport
+	DataVersion           interfaces.DataVersion
+}
+
+// Save writes the snapshot as gzip-compressed gob. The file is written next to its
+// destination and renamed, so a crash never leaves a truncated snapshot behind.
+func Save(path string, snap *Snapshot) error {
+	snap.FormatVersion = formatVersion
+	snap.SavedAt = time.Now()
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // No-op once renamed
+
+	zw := gzip.NewWriter(tmp)
+	if err := gob.NewEncoder(zw).Encode(snap); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads a snapshot written by Save
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(path) // #nosec G304 -- path comes from the configuration
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	var snap Snapshot
+	if err := gob.NewDecoder(zr).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	if snap.FormatVersion != formatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format %d (expected %d)", snap.FormatVersion, formatVersion)
+	}
+
+	return &snap, nil
+}
+
+// MedicamentsMap indexes the medicaments by CIS
+func (s *Snapshot) MedicamentsMap() map[int]entities.Medicament {
+	medicamentsMap := make(map[int]entities.Medicament, len(s.Medicaments))
+	for i := range s.Medicaments {
+		medicamentsMap[s.Medicaments[i].Cis] = s.Medicaments[i]
+	}
+	return medicamentsMap
+}
+
+// GeneriquesMap indexes the generique groups by group ID
+func (s *Snapshot) GeneriquesMap() map[int]entities.GeneriqueList {
+	generiquesMap := make(map[int]entities.GeneriqueList, len(s.Generiques))
+	for i := range s.Generiques {
+		generiquesMap[s.Generiques[i].GroupID] = s.Generiques[i]
+	}
+	return generiquesMap
+}
@@ -0,0 +1,114 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giygas/medicaments-api/interfaces"
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+func testSnapshot() *Snapshot {
+	presentation := entities.Presentation{
+		Cis:           61266250,
+		Cip7:          3000001,
+		Cip13:         3400930000001,
+		Prix:          2.18,
+		Disponibilite: &entities.Disponibilite{Cis: 61266250, Cip13: 3400930000001, CodeStatut: 2},
+	}
+
+	return &Snapshot{
+		Medicaments: []entities.Medicament{
+			{Cis: 61266250, Denomination: "DOLIPRANE 1000 mg", DenominationNormalized: "doliprane 1000 mg", Presentation: []entities.Presentation{presentation}},
+			{Cis: 60234100, Denomination: "IBUPROFÈNE 400 mg", DenominationNormalized: "ibuprofene 400 mg"},
+		},
+		Generiques: []entities.GeneriqueList{
+			{GroupID: 1, Libelle: "PARACETAMOL 1000 mg", OrphanCIS: []int{60000001}},
+		},
+		PresentationsCIP7Map:  map[int]entities.Presentation{3000001: presentation},
+		PresentationsCIP13Map: map[int]entities.Presentation{3400930000001: presentation},
+		Report:                &interfaces.DataQualityReport{DuplicateCIS: []int{1}, MedicamentsWithoutCompositions: 2},
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots", "dataset.gob.gz")
+
+	if err := Save(path, testSnapshot()); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	snap, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if snap.SavedAt.IsZero() {
+		t.Error("Expected SavedAt to be set")
+	}
+	if len(snap.Medicaments) != 2 || snap.Medicaments[1].DenominationNormalized != "ibuprofene 400 mg" {
+		t.Errorf("Unexpected medicaments: %+v", snap.Medicaments)
+	}
+	if d := snap.Medicaments[0].Presentation[0].Disponibilite; d == nil || d.CodeStatut != 2 {
+		t.Errorf("Expected the disponibilite to be kept, got %+v", d)
+	}
+	if snap.PresentationsCIP13Map[3400930000001].Prix != 2.18 {
+		t.Errorf("Unexpected CIP13 map: %+v", snap.PresentationsCIP13Map)
+	}
+	if snap.Report == nil || snap.Report.MedicamentsWithoutCompositions != 2 {
+		t.Errorf("Unexpected report: %+v", snap.Report)
+	}
+
+	if snap.MedicamentsMap()[60234100].Denomination != "IBUPROFÈNE 400 mg" {
+		t.Error("Expected MedicamentsMap to index by CIS")
+	}
+	if snap.GeneriquesMap()[1].Libelle != "PARACETAMOL 1000 mg" {
+		t.Error("Expected GeneriquesMap to index by group ID")
+	}
+
+	// Only the snapshot is left in the directory
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the snapshot file, got %d entries", len(entries))
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Load(filepath.Join(dir, "missing.gob.gz")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	corrupted := filepath.Join(dir, "corrupted.gob.gz")
+	if err := os.WriteFile(corrupted, []byte("not a snapshot"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := Load(corrupted); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a decoding error, got %v", err)
+	}
+
+	// A snapshot written by another format version is ignored
+	outdated := filepath.Join(dir, "outdated.gob.gz")
+	f, err := os.Create(outdated)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	zw := gzip.NewWriter(f)
+	if err := gob.NewEncoder(zw).Encode(&Snapshot{FormatVersion: formatVersion + 1}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	_ = zw.Close()
+	_ = f.Close()
+
+	if _, err := Load(outdated); err == nil {
+		t.Error("Expected an error for another format version")
+	}
+}
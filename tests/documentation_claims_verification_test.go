@@ -488,7 +488,7 @@ func createFullTestData() *data.DataContainer {
 				MedicamentsWithoutPresentationsCIS: []int{},
 				MedicamentsWithoutCompositionsCIS:  []int{},
 				GeneriqueOnlyCISList:               []int{},
-			})
+			}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 
 		fmt.Printf("Loaded: %d medicaments, %d generiques\n", len(medicaments), len(generiques))
 	})
@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/giygas/medicaments-api/config"
 	"github.com/giygas/medicaments-api/data"
@@ -80,7 +81,7 @@ func TestMain(m *testing.M) {
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 	fmt.Printf("Mock data initialized: %d medicaments, %d generiques\n", len(testMedicaments), len(testGeneriques))
 
 	fmt.Println("Running tests...")
@@ -120,7 +121,7 @@ func TestEndpoints(t *testing.T) {
 		{"Test generiques with invalid group ID", "/v1/generiques/invalid", http.StatusBadRequest},
 		{"Test generiques with not found group ID", "/v1/generiques/99999", http.StatusNotFound},
 		{"Test medicaments no params", "/v1/medicaments", http.StatusBadRequest},
-		{"Test medicaments multiple params", "/v1/medicaments?page=1&search=test", http.StatusBadRequest},
+		{"Test medicaments multiple params", "/v1/medicaments?search=test&cip=3400930000001", http.StatusBadRequest},
 		{"Test medicaments invalid page", "/v1/medicaments?page=0", http.StatusBadRequest},
 		{"Test medicaments negative page", "/v1/medicaments?page=-1", http.StatusBadRequest},
 		{"Test medicaments invalid CIS", "/v1/medicaments/abc", http.StatusBadRequest},
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/giygas/medicaments-api/config"
 	"github.com/giygas/medicaments-api/data"
@@ -119,7 +120,7 @@ func TestETagFunctionality(t *testing.T) {
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 
 	srv := setupETagTestServer(dataContainer)
 	router := srv.Router()
@@ -360,7 +360,7 @@ func testAPIEndpointsWithRealData(t *testing.T, medicaments []entities.Medicamen
 			MedicamentsWithoutPresentationsCIS: []int{},
 			MedicamentsWithoutCompositionsCIS:  []int{},
 			GeneriqueOnlyCISList:               []int{},
-		})
+		}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 
 	srv := setupIntegrationTestServer(dataContainer)
 	router := srv.Router()
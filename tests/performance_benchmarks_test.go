@@ -134,7 +134,7 @@ func setupAlgorithmicContainer() *data.DataContainer {
 				MedicamentsWithoutPresentationsCIS: []int{},
 				MedicamentsWithoutCompositionsCIS:  []int{},
 				GeneriqueOnlyCISList:               []int{},
-			})
+			}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 
 		fmt.Printf("Algorithmic test data loaded: %d medicaments, %d generiques\n",
 			len(algorithmicMedicaments), len(generiques))
@@ -183,7 +183,7 @@ func setupRealWorldData() *data.DataContainer {
 				MedicamentsWithoutPresentationsCIS: []int{},
 				MedicamentsWithoutCompositionsCIS:  []int{},
 				GeneriqueOnlyCISList:               []int{},
-			})
+			}, interfaces.DataVersion{Version: 1, LoadedAt: time.Now()})
 
 		fmt.Printf("Real-world test data loaded: %d medicaments, %d generiques\n",
 			len(medicaments), len(generiques))
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/giygas/medicaments-api/data"
 	"github.com/giygas/medicaments-api/handlers"
@@ -75,6 +76,7 @@ func TestApplicationStartupSmoke(t *testing.T) {
 		map[int]entities.Presentation{},
 		map[int]entities.Presentation{},
 		&interfaces.DataQualityReport{},
+		interfaces.DataVersion{Version: 1, LoadedAt: time.Now()},
 	)
 
 	// 2. Create validator and handler
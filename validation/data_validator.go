@@ -10,12 +10,13 @@ import (
 	"github.com/giygas/medicaments-api/interfaces"
 	"github.com/giygas/medicaments-api/logging"
 	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+	"github.com/giygas/medicaments-api/search"
 )
 
 // Pre-compiled regex patterns for performance optimization
 // Compiled once at package initialization and reused for all validations
 var (
-	// Input validation: alphanumeric + safe punctuation (ASCII-only)
+	// Input validation: alphanumeric + safe punctuation (ASCII-only, checked after accent folding)
 	inputRegex = regexp.MustCompile(`^[a-zA-Z0-9\s\+\.\-\/']+$`)
 
 	// This whitelist regex already blocks:
@@ -326,15 +327,11 @@ func (v *Validator) ValidateInput(input string) error {
 		return fmt.Errorf("search query too complex: maximum 6 words allowed")
 	}
 
-	// Check for French accented characters (source BDPM data is uppercase without accents)
-	if v.containsAccents(input) {
-		return fmt.Errorf("accents not supported. Try removing them (e.g., use 'ibuprofene' instead of 'ibuprofène')")
-	}
-
 	// Check that user input contains only accepted characters
 	// Allow only alphanumeric characters, spaces, and safe punctuation
 	// Pattern: letters, numbers, spaces, hyphens, periods, forward slash, apostrophe, and plus sign
-	if !inputRegex.MatchString(input) {
+	// Accented letters are folded first (e.g. "ibuprofène" -> "ibuprofene") and match like their base letter
+	if !inputRegex.MatchString(search.FoldAccents(input)) {
 		return fmt.Errorf("input contains invalid characters. Only letters, numbers, spaces, hyphens, periods, forward slash, apostrophe, and plus sign are allowed")
 	}
 
@@ -413,15 +410,3 @@ func (v *Validator) hasExcessiveRepetition(input string) bool {
 	}
 	return false
 }
-
-// containsAccents checks if input contains French accented characters
-// Source BDPM data is uppercase without accents (e.g., IBUPROFENE, PARACETAMOL)
-func (v *Validator) containsAccents(input string) bool {
-	accents := "àâäéèêëïîôöùûüÿçÀÂÄÉÈÊËÏÎÔÖÙÛÜŸÇ"
-	for _, r := range input {
-		if strings.ContainsRune(accents, r) {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/giygas/medicaments-api/logging"
+	"github.com/go-chi/chi/v5"
+)
+
+// errReadOnly answers changes made without a subscriptions file, they would be lost at restart
+const errReadOnly = "Subscriptions are read-only: set WEBHOOKS_FILE to manage them with the admin API"
+
+// AdminRoutes returns the subscription management routes, protected by a bearer token:
+//
+//	GET    /             lists the subscriptions (secrets are not returned)
+//	POST   /             registers a subscription
+//	DELETE /{id}         removes a subscription
+//	GET    /deliveries   lists the most recent deliveries
+//
+// POST and DELETE save the subscriptions file, and answer 409 when there is none.
+func (d *Dispatcher) AdminRoutes(token string) http.Handler {
+	router := chi.NewRouter()
+	router.Use(requireToken(token))
+
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, d.Subscriptions())
+	})
+
+	router.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		if d.readOnly() {
+			respondWithError(w, http.StatusConflict, errReadOnly)
+			return
+		}
+
+		var subscription Subscription
+		if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+
+		created, err := d.Add(subscription)
+		if errors.Is(err, errSave) {
+			logging.Error("Failed to save webhook subscriptions", "error", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to save the subscriptions")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		logging.Info("Webhook subscription added", "subscription", created.ID, "url", created.URL)
+		created.Secret = ""
+		respondWithJSON(w, http.StatusCreated, created)
+	})
+
+	router.Delete("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if d.readOnly() {
+			respondWithError(w, http.StatusConflict, errReadOnly)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		removed, err := d.Remove(id)
+		if err != nil {
+			logging.Error("Failed to save webhook subscriptions", "error", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to save the subscriptions")
+			return
+		}
+		if !removed {
+			respondWithError(w, http.StatusNotFound, "Subscription not found")
+			return
+		}
+
+		logging.Info("Webhook subscription removed", "subscription", id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	router.Get("/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, d.Deliveries())
+	})
+
+	return router
+}
+
+// requireToken rejects requests without the expected "Authorization: Bearer <token>" header
+func requireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				respondWithError(w, http.StatusUnauthorized, "Missing or invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logging.Error("Failed to marshal JSON response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		logging.Error("Failed to write response", "error", err)
+	}
+}
+
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	respondWithJSON(w, code, map[string]any{
+		"error":   http.StatusText(code),
+		"message": message,
+		"code":    code,
+	})
+}
@@ -0,0 +1,134 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testToken = "admin-token-admin-token-admin-token"
+
+func adminRequest(t *testing.T, handler http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminRoutes_RequiresToken(t *testing.T) {
+	handler := newTestDispatcher().AdminRoutes(testToken)
+
+	for _, token := range []string{"", "wrong-token"} {
+		rr := adminRequest(t, handler, http.MethodGet, "/", token, "")
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for token %q, got %d", token, rr.Code)
+		}
+	}
+
+	rr := adminRequest(t, handler, http.MethodGet, "/", testToken, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the admin token, got %d", rr.Code)
+	}
+}
+
+func TestAdminRoutes_Subscriptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	d := newTestDispatcher()
+	d.SetSubscriptionsFile(path)
+	handler := d.AdminRoutes(testToken)
+
+	rr := adminRequest(t, handler, http.MethodPost, "/", testToken, `{"url": "ftp://example.com", "secret": "`+testSecret+`"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid subscription, got %d", rr.Code)
+	}
+
+	rr = adminRequest(t, handler, http.MethodPost, "/", testToken, `not json`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid body, got %d", rr.Code)
+	}
+
+	rr = adminRequest(t, handler, http.MethodPost, "/", testToken,
+		`{"url": "https://example.com/hook", "secret": "`+testSecret+`", "cis": [61266250], "events": ["price"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created Subscription
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if created.ID == "" || created.Secret != "" {
+		t.Errorf("Expected a generated ID and no secret, got %+v", created)
+	}
+
+	rr = adminRequest(t, handler, http.MethodGet, "/", testToken, "")
+	var subscriptions []Subscription
+	if err := json.Unmarshal(rr.Body.Bytes(), &subscriptions); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].ID != created.ID {
+		t.Errorf("Expected the created subscription, got %+v", subscriptions)
+	}
+	if saved, err := LoadSubscriptions(path); err != nil || len(saved) != 1 || saved[0].ID != created.ID {
+		t.Errorf("Expected the created subscription to be saved, got %+v, %v", saved, err)
+	}
+
+	rr = adminRequest(t, handler, http.MethodDelete, "/"+created.ID, testToken, "")
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rr.Code)
+	}
+	rr = adminRequest(t, handler, http.MethodDelete, "/"+created.ID, testToken, "")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed subscription, got %d", rr.Code)
+	}
+}
+
+func TestAdminRoutes_ReadOnlyWithoutFile(t *testing.T) {
+	d := newTestDispatcher()
+	if _, err := d.Add(Subscription{ID: "sub", URL: "https://example.com/hook", Secret: testSecret}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	handler := d.AdminRoutes(testToken)
+
+	rr := adminRequest(t, handler, http.MethodPost, "/", testToken, `{"url": "https://example.com/hook", "secret": "`+testSecret+`"}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 without a subscriptions file, got %d", rr.Code)
+	}
+	rr = adminRequest(t, handler, http.MethodDelete, "/sub", testToken, "")
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 without a subscriptions file, got %d", rr.Code)
+	}
+	if len(d.Subscriptions()) != 1 {
+		t.Errorf("Expected the subscriptions to be unchanged, got %+v", d.Subscriptions())
+	}
+
+	rr = adminRequest(t, handler, http.MethodGet, "/", testToken, "")
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the subscriptions to be listed, got %d", rr.Code)
+	}
+}
+
+func TestAdminRoutes_Deliveries(t *testing.T) {
+	d := newTestDispatcher()
+	d.record(Delivery{ID: "delivery", SubscriptionID: "sub", Delivered: true, Attempts: 1})
+
+	rr := adminRequest(t, d.AdminRoutes(testToken), http.MethodGet, "/deliveries", testToken, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	var deliveries []Delivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != "delivery" {
+		t.Errorf("Expected the logged delivery, got %+v", deliveries)
+	}
+}
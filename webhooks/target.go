@@ -0,0 +1,68 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateTarget rejects subscriptions and connections to addresses of the server's own network,
+// which would let an admin token holder probe internal services (SSRF)
+var errPrivateTarget = errors.New("url must not target a loopback, private or link-local address (set WEBHOOKS_ALLOW_PRIVATE=true to allow it)")
+
+// checkTarget rejects URLs whose host is localhost or a literal non-public address.
+// Names resolving to such addresses are refused when connecting, see NewClient.
+func checkTarget(rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateTarget
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return errPrivateTarget
+	}
+	return nil
+}
+
+// isPublic reports whether an address can be reached by deliveries
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast()
+}
+
+// NewClient returns the HTTP client of the deliveries. Unless allowPrivate is set, it refuses to
+// connect to non-public addresses once names are resolved, which also covers DNS records changed
+// after the subscription and redirects. Proxies are not used, they would hide the target address.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer := &net.Dialer{
+			Timeout: timeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if !isPublic(addrPort.Addr()) {
+					return fmt.Errorf("%s: %w", address, errPrivateTarget)
+				}
+				return nil
+			},
+		}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
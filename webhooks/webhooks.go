@@ -0,0 +1,509 @@
+// Package webhooks notifies subscribers when tracked medicaments change between two data
+// updates. Payloads are signed with HMAC-SHA256 and delivered with retries and backoff.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/interfaces"
+	"github.com/giygas/medicaments-api/logging"
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+// Compile-time check to ensure Dispatcher implements ChangeNotifier interface
+var _ interfaces.ChangeNotifier = (*Dispatcher)(nil)
+
+// Events a subscription can follow
+const (
+	EventCommercialisation = "commercialisation" // etatComercialisation of a medicament or presentation
+	EventPrice             = "price"             // prix of a presentation
+	EventAvailability      = "availability"      // disponibilite of a presentation
+)
+
+// Events lists the accepted events
+var Events = []string{EventCommercialisation, EventPrice, EventAvailability}
+
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = 2 * time.Second
+	maxDeliveryLog     = 100
+	minSecretLength    = 16
+
+	// SignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of the body with the subscription secret
+	SignatureHeader = "X-Webhook-Signature"
+	// DeliveryHeader holds the delivery ID, identical across the retries of a delivery
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// Subscription is a webhook endpoint and the medicaments and events it follows.
+// An empty CIS list follows every medicament, an empty Events list every event.
+type Subscription struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	CIS    []int    `json:"cis"`
+	Events []string `json:"events"`
+}
+
+// Change is one change of a tracked medicament sent to the subscribers
+type Change struct {
+	Event string `json:"event"`
+	Cis   int    `json:"cis"`
+	Cip13 int    `json:"cip13,omitempty"` // Set for presentation changes
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// Payload is the signed JSON body sent to a subscription
+type Payload struct {
+	DeliveryID     string    `json:"deliveryId"`
+	SubscriptionID string    `json:"subscriptionId"`
+	DataVersion    uint64    `json:"dataVersion"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Changes        []Change  `json:"changes"`
+}
+
+// Delivery is the outcome of one payload sent to a subscription
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscriptionId"`
+	URL            string    `json:"url"`
+	DataVersion    uint64    `json:"dataVersion"`
+	Changes        int       `json:"changes"`
+	Attempts       int       `json:"attempts"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Delivered      bool      `json:"delivered"`
+	CreatedAt      time.Time `json:"createdAt"`
+	CompletedAt    time.Time `json:"completedAt"`
+}
+
+// Dispatcher holds the subscriptions and delivers the payloads in the background
+type Dispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration // Doubled after each failed attempt
+
+	mu            sync.RWMutex
+	subscriptions []Subscription
+	deliveries    []Delivery // Most recent last
+	file          string     // Saved after each change when set, the admin API is read-only otherwise
+	allowPrivate  bool       // Accept loopback, private and link-local targets
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pending sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher without subscriptions
+func NewDispatcher(client *http.Client) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		client:        client,
+		maxAttempts:   defaultMaxAttempts,
+		backoff:       defaultBackoff,
+		subscriptions: make([]Subscription, 0),
+		deliveries:    make([]Delivery, 0),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// LoadSubscriptions reads a JSON array of subscriptions from a file
+func LoadSubscriptions(path string) ([]Subscription, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path comes from the configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks file: %w", err)
+	}
+
+	var subscriptions []Subscription
+	if err := json.Unmarshal(data, &subscriptions); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// errSave marks the subscriptions that were valid but could not be saved
+var errSave = errors.New("failed to save webhook subscriptions")
+
+// SaveSubscriptions writes the subscriptions, secrets included, as the JSON array read by
+// LoadSubscriptions. The file is written next to its destination with owner-only permissions
+// and renamed, so a crash never leaves a truncated file behind.
+func SaveSubscriptions(path string, subscriptions []Subscription) error {
+	data, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode webhooks file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create webhooks file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // No-op once renamed
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write webhooks file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write webhooks file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace webhooks file: %w", err)
+	}
+	return nil
+}
+
+// SetSubscriptionsFile saves the subscriptions to path after each Add or Remove, so that the
+// subscriptions managed with the admin API survive a restart. Without it the admin API is read-only.
+func (d *Dispatcher) SetSubscriptionsFile(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.file = path
+}
+
+// AllowPrivateTargets accepts subscriptions to loopback, private and link-local addresses, for
+// receivers on the same host or network. They are rejected by default.
+func (d *Dispatcher) AllowPrivateTargets(allow bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.allowPrivate = allow
+}
+
+// readOnly reports whether the subscriptions cannot be changed with the admin API
+func (d *Dispatcher) readOnly() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.file == ""
+}
+
+// save writes the subscriptions to the subscriptions file, if any. d.mu must be held.
+func (d *Dispatcher) save() error {
+	if d.file == "" {
+		return nil
+	}
+	if err := SaveSubscriptions(d.file, d.subscriptions); err != nil {
+		return fmt.Errorf("%w: %w", errSave, err)
+	}
+	return nil
+}
+
+// Validate checks the URL, secret and events of a subscription
+func (s Subscription) Validate() error {
+	target, err := url.Parse(s.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	if len(s.Secret) < minSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minSecretLength)
+	}
+
+	for _, event := range s.Events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("unknown event %q. Must be one of: %v", event, Events)
+		}
+	}
+
+	return nil
+}
+
+// Add validates and registers a subscription, generating its ID when empty.
+// The subscriptions file is saved, and the subscription dropped if that fails.
+func (d *Dispatcher) Add(subscription Subscription) (Subscription, error) {
+	if err := subscription.Validate(); err != nil {
+		return Subscription{}, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.allowPrivate {
+		if err := checkTarget(subscription.URL); err != nil {
+			return Subscription{}, err
+		}
+	}
+
+	if subscription.ID == "" {
+		subscription.ID = newID()
+	}
+	if slices.ContainsFunc(d.subscriptions, func(s Subscription) bool { return s.ID == subscription.ID }) {
+		return Subscription{}, fmt.Errorf("subscription %s already exists", subscription.ID)
+	}
+
+	d.subscriptions = append(d.subscriptions, subscription)
+	if err := d.save(); err != nil {
+		d.subscriptions = d.subscriptions[:len(d.subscriptions)-1]
+		return Subscription{}, err
+	}
+	return subscription, nil
+}
+
+// Remove deletes a subscription and reports whether it existed.
+// The subscriptions file is saved, and the subscription kept if that fails.
+func (d *Dispatcher) Remove(id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := slices.IndexFunc(d.subscriptions, func(s Subscription) bool { return s.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+
+	remaining := slices.Delete(slices.Clone(d.subscriptions), i, i+1)
+	previous := d.subscriptions
+	d.subscriptions = remaining
+	if err := d.save(); err != nil {
+		d.subscriptions = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// Subscriptions returns the registered subscriptions without their secrets
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	subscriptions := make([]Subscription, len(d.subscriptions))
+	for i, subscription := range d.subscriptions {
+		subscription.Secret = ""
+		subscriptions[i] = subscription
+	}
+	return subscriptions
+}
+
+// Deliveries returns the most recent deliveries, oldest first
+func (d *Dispatcher) Deliveries() []Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return slices.Clone(d.deliveries)
+}
+
+// NotifyChanges sends the tracked changes of an update to each subscription in the background
+func (d *Dispatcher) NotifyChanges(changeSet changes.ChangeSet, presentationsCIP13Map map[int]entities.Presentation) {
+	found := collectChanges(changeSet, presentationsCIP13Map)
+	if len(found) == 0 {
+		return
+	}
+
+	d.mu.RLock()
+	subscriptions := slices.Clone(d.subscriptions)
+	d.mu.RUnlock()
+
+	for _, subscription := range subscriptions {
+		matching := subscription.filter(found)
+		if len(matching) == 0 {
+			continue
+		}
+
+		payload := Payload{
+			DeliveryID:     newID(),
+			SubscriptionID: subscription.ID,
+			DataVersion:    changeSet.ToVersion,
+			CreatedAt:      time.Now().UTC(),
+			Changes:        matching,
+		}
+
+		d.pending.Add(1)
+		go func() {
+			defer d.pending.Done()
+			d.deliver(subscription, payload)
+		}()
+	}
+}
+
+// Wait blocks until the pending deliveries are done
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// Close stops the retries in progress and waits for the pending deliveries
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.pending.Wait()
+}
+
+// Sign returns the signature header value of a body for a secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the payload until it is accepted, the error is permanent or the attempts are exhausted
+func (d *Dispatcher) deliver(subscription Subscription, payload Payload) {
+	delivery := Delivery{
+		ID:             payload.DeliveryID,
+		SubscriptionID: subscription.ID,
+		URL:            subscription.URL,
+		DataVersion:    payload.DataVersion,
+		Changes:        len(payload.Changes),
+		CreatedAt:      payload.CreatedAt,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		d.record(delivery)
+		return
+	}
+	signature := Sign(subscription.Secret, body)
+
+	backoff := d.backoff
+	for delivery.Attempts < d.maxAttempts {
+		delivery.Attempts++
+
+		statusCode, err := d.post(subscription.URL, body, signature, payload.DeliveryID)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Error = ""
+			delivery.Delivered = true
+			break
+		}
+		delivery.Error = err.Error()
+
+		// Client errors other than timeouts and rate limiting will not succeed on retry
+		if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests {
+			break
+		}
+		if delivery.Attempts == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-d.ctx.Done():
+			delivery.Error = "dispatcher closed: " + delivery.Error
+			d.record(delivery)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	d.record(delivery)
+}
+
+// post sends one attempt. A non-2xx status is returned as an error along with the status code.
+func (d *Dispatcher) post(target string, body []byte, signature, deliveryID string) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "medicaments-api-webhooks")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(DeliveryHeader, deliveryID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logging.Warn("Failed to close webhook response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record appends a delivery to the log, keeping the most recent ones
+func (d *Dispatcher) record(delivery Delivery) {
+	delivery.CompletedAt = time.Now().UTC()
+
+	if delivery.Delivered {
+		logging.Info("Webhook delivered", "subscription", delivery.SubscriptionID, "delivery", delivery.ID, "attempts", delivery.Attempts)
+	} else {
+		logging.Warn("Webhook delivery failed", "subscription", delivery.SubscriptionID, "delivery", delivery.ID,
+			"attempts", delivery.Attempts, "error", delivery.Error)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deliveries = append(d.deliveries, delivery)
+	if excess := len(d.deliveries) - maxDeliveryLog; excess > 0 {
+		d.deliveries = slices.Delete(d.deliveries, 0, excess)
+	}
+}
+
+// filter returns the changes on the medicaments and events followed by the subscription
+func (s Subscription) filter(found []Change) []Change {
+	var matching []Change
+	for _, change := range found {
+		if len(s.CIS) > 0 && !slices.Contains(s.CIS, change.Cis) {
+			continue
+		}
+		if len(s.Events) > 0 && !slices.Contains(s.Events, change.Event) {
+			continue
+		}
+		matching = append(matching, change)
+	}
+	return matching
+}
+
+// collectChanges turns the field changes of an update into webhook changes.
+// Presentations are identified by CIP13 in the change set, their CIS comes from the new map.
+func collectChanges(changeSet changes.ChangeSet, presentationsCIP13Map map[int]entities.Presentation) []Change {
+	var found []Change
+
+	for _, modification := range changeSet.Medicaments.Modified {
+		for _, field := range modification.Fields {
+			if field.Field == "etatComercialisation" {
+				found = append(found, Change{Event: EventCommercialisation, Cis: modification.ID, Old: field.Old, New: field.New})
+			}
+		}
+	}
+
+	presentationEvents := map[string]string{
+		"etatComercialisation": EventCommercialisation,
+		"prix":                 EventPrice,
+		"disponibilite":        EventAvailability,
+	}
+	for _, modification := range changeSet.Presentations.Modified {
+		for _, field := range modification.Fields {
+			event, tracked := presentationEvents[field.Field]
+			if !tracked {
+				continue
+			}
+			found = append(found, Change{
+				Event: event,
+				Cis:   presentationsCIP13Map[modification.ID].Cis,
+				Cip13: modification.ID,
+				Old:   field.Old,
+				New:   field.New,
+			})
+		}
+	}
+
+	return found
+}
+
+// newID returns a random hex identifier
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
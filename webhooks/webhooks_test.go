@@ -0,0 +1,470 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giygas/medicaments-api/changes"
+	"github.com/giygas/medicaments-api/medicamentsparser/entities"
+)
+
+const testSecret = "0123456789abcdef"
+
+// receiver is a local stand-in for a subscriber endpoint. It answers with the queued
+// status codes in order, then 200.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// newTestDispatcher accepts private targets, the receivers listen on the loopback
+func newTestDispatcher() *Dispatcher {
+	d := NewDispatcher(&http.Client{Timeout: time.Second})
+	d.backoff = time.Millisecond
+	d.AllowPrivateTargets(true)
+	return d
+}
+
+// priceChange is a change set where presentation 3400900000001 of CIS 1 changed price
+// and medicament 2 stopped being commercialised
+func priceChange() (changes.ChangeSet, map[int]entities.Presentation) {
+	changeSet := changes.ChangeSet{
+		FromVersion: 1,
+		ToVersion:   2,
+		Medicaments: changes.Diff{
+			Modified: []changes.Modification{
+				{ID: 2, Fields: []changes.FieldChange{
+					{Field: "etatComercialisation", Old: "Commercialisée", New: "Non commercialisée"},
+					{Field: "denomination", Old: "A", New: "B"},
+				}},
+			},
+		},
+		Presentations: changes.Diff{
+			Modified: []changes.Modification{
+				{ID: 3400900000001, Fields: []changes.FieldChange{{Field: "prix", Old: 2.5, New: 3.0}}},
+			},
+		},
+	}
+	cip13Map := map[int]entities.Presentation{
+		3400900000001: {Cis: 1, Cip13: 3400900000001, Prix: 3},
+	}
+	return changeSet, cip13Map
+}
+
+func TestNotifyChanges_SignedPayload(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := newTestDispatcher()
+	if _, err := d.Add(Subscription{ID: "sub", URL: srv.URL, Secret: testSecret}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	d.NotifyChanges(priceChange())
+	d.Wait()
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(rc.requests))
+	}
+	req, body := rc.requests[0], rc.bodies[0]
+
+	if got := req.Header.Get(SignatureHeader); got != Sign(testSecret, body) {
+		t.Errorf("Expected signature %s, got %s", Sign(testSecret, body), got)
+	}
+	if req.Header.Get(DeliveryHeader) == "" {
+		t.Error("Expected a delivery ID header")
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if payload.SubscriptionID != "sub" || payload.DataVersion != 2 {
+		t.Errorf("Unexpected payload header: %+v", payload)
+	}
+	if payload.DeliveryID != req.Header.Get(DeliveryHeader) {
+		t.Errorf("Expected delivery ID %s in payload, got %s", req.Header.Get(DeliveryHeader), payload.DeliveryID)
+	}
+	if len(payload.Changes) != 2 {
+		t.Fatalf("Expected 2 tracked changes, got %+v", payload.Changes)
+	}
+	if payload.Changes[0].Event != EventCommercialisation || payload.Changes[0].Cis != 2 {
+		t.Errorf("Expected commercialisation change on CIS 2, got %+v", payload.Changes[0])
+	}
+	if payload.Changes[1].Event != EventPrice || payload.Changes[1].Cis != 1 || payload.Changes[1].Cip13 != 3400900000001 {
+		t.Errorf("Expected price change on CIS 1, got %+v", payload.Changes[1])
+	}
+}
+
+func TestNotifyChanges_Filters(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscription  Subscription
+		expectedCalls int
+		expectedEvent string
+	}{
+		{"all changes", Subscription{}, 1, ""},
+		{"tracked CIS", Subscription{CIS: []int{1}}, 1, EventPrice},
+		{"untracked CIS", Subscription{CIS: []int{3}}, 0, ""},
+		{"tracked event", Subscription{Events: []string{EventCommercialisation}}, 1, EventCommercialisation},
+		{"untracked event", Subscription{Events: []string{EventAvailability}}, 0, ""},
+		{"CIS and event mismatch", Subscription{CIS: []int{1}, Events: []string{EventCommercialisation}}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+
+			d := newTestDispatcher()
+			tt.subscription.URL = srv.URL
+			tt.subscription.Secret = testSecret
+			if _, err := d.Add(tt.subscription); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+
+			d.NotifyChanges(priceChange())
+			d.Wait()
+
+			if len(rc.requests) != tt.expectedCalls {
+				t.Fatalf("Expected %d requests, got %d", tt.expectedCalls, len(rc.requests))
+			}
+			if tt.expectedEvent == "" {
+				return
+			}
+
+			var payload Payload
+			if err := json.Unmarshal(rc.bodies[0], &payload); err != nil {
+				t.Fatalf("Invalid payload: %v", err)
+			}
+			if len(payload.Changes) != 1 || payload.Changes[0].Event != tt.expectedEvent {
+				t.Errorf("Expected a single %s change, got %+v", tt.expectedEvent, payload.Changes)
+			}
+		})
+	}
+}
+
+func TestNotifyChanges_NoTrackedChange(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := newTestDispatcher()
+	if _, err := d.Add(Subscription{URL: srv.URL, Secret: testSecret}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	changeSet := changes.ChangeSet{
+		Medicaments: changes.Diff{
+			Added:    []int{5},
+			Modified: []changes.Modification{{ID: 2, Fields: []changes.FieldChange{{Field: "denomination"}}}},
+		},
+	}
+	d.NotifyChanges(changeSet, nil)
+	d.Wait()
+
+	if len(rc.requests) != 0 {
+		t.Errorf("Expected no request, got %d", len(rc.requests))
+	}
+	if len(d.Deliveries()) != 0 {
+		t.Errorf("Expected no delivery, got %d", len(d.Deliveries()))
+	}
+}
+
+func TestNotifyChanges_Retries(t *testing.T) {
+	tests := []struct {
+		name              string
+		statuses          []int
+		expectedAttempts  int
+		expectedDelivered bool
+		expectedStatus    int
+	}{
+		{"success", nil, 1, true, http.StatusOK},
+		{"server errors then success", []int{500, 503}, 3, true, http.StatusOK},
+		{"rate limited then success", []int{429}, 2, true, http.StatusOK},
+		{"client error is not retried", []int{400}, 1, false, http.StatusBadRequest},
+		{"attempts exhausted", []int{500, 500, 500, 500, 500}, defaultMaxAttempts, false, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{statuses: tt.statuses}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+
+			d := newTestDispatcher()
+			if _, err := d.Add(Subscription{ID: "sub", URL: srv.URL, Secret: testSecret}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+
+			d.NotifyChanges(priceChange())
+			d.Wait()
+
+			if len(rc.requests) != tt.expectedAttempts {
+				t.Errorf("Expected %d requests, got %d", tt.expectedAttempts, len(rc.requests))
+			}
+
+			// Every retry carries the same delivery ID and signed body
+			for i := 1; i < len(rc.requests); i++ {
+				if rc.requests[i].Header.Get(DeliveryHeader) != rc.requests[0].Header.Get(DeliveryHeader) {
+					t.Error("Expected the same delivery ID across retries")
+				}
+				if string(rc.bodies[i]) != string(rc.bodies[0]) {
+					t.Error("Expected the same body across retries")
+				}
+			}
+
+			deliveries := d.Deliveries()
+			if len(deliveries) != 1 {
+				t.Fatalf("Expected 1 logged delivery, got %d", len(deliveries))
+			}
+			delivery := deliveries[0]
+			if delivery.Attempts != tt.expectedAttempts || delivery.Delivered != tt.expectedDelivered || delivery.StatusCode != tt.expectedStatus {
+				t.Errorf("Unexpected delivery: %+v", delivery)
+			}
+			if delivery.Delivered == (delivery.Error != "") {
+				t.Errorf("Expected an error only on failed deliveries, got %+v", delivery)
+			}
+		})
+	}
+}
+
+func TestDispatcher_CloseStopsRetries(t *testing.T) {
+	rc := &receiver{statuses: []int{500, 500, 500, 500, 500}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := newTestDispatcher()
+	d.backoff = time.Hour
+	if _, err := d.Add(Subscription{URL: srv.URL, Secret: testSecret}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	d.NotifyChanges(priceChange())
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			rc.mu.Lock()
+			attempted := len(rc.requests) > 0
+			rc.mu.Unlock()
+			if attempted {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		d.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt the backoff")
+	}
+
+	deliveries := d.Deliveries()
+	if len(deliveries) != 1 || deliveries[0].Delivered || deliveries[0].Attempts != 1 {
+		t.Errorf("Expected one failed delivery after 1 attempt, got %+v", deliveries)
+	}
+}
+
+func TestDispatcher_DeliveryLogIsBounded(t *testing.T) {
+	d := newTestDispatcher()
+	for i := 0; i < maxDeliveryLog+10; i++ {
+		d.record(Delivery{ID: string(rune('a' + i%26)), Delivered: true})
+	}
+
+	if len(d.Deliveries()) != maxDeliveryLog {
+		t.Errorf("Expected %d logged deliveries, got %d", maxDeliveryLog, len(d.Deliveries()))
+	}
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription Subscription
+		expectError  bool
+	}{
+		{"valid", Subscription{URL: "https://example.com/hook", Secret: testSecret, Events: []string{EventPrice}}, false},
+		{"relative URL", Subscription{URL: "/hook", Secret: testSecret}, true},
+		{"unsupported scheme", Subscription{URL: "ftp://example.com", Secret: testSecret}, true},
+		{"short secret", Subscription{URL: "https://example.com/hook", Secret: "short"}, true},
+		{"unknown event", Subscription{URL: "https://example.com/hook", Secret: testSecret, Events: []string{"deleted"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.subscription.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error=%v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestDispatcher_AddRemove(t *testing.T) {
+	d := newTestDispatcher()
+
+	created, err := d.Add(Subscription{URL: "https://example.com/hook", Secret: testSecret})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if created.ID == "" {
+		t.Error("Expected a generated ID")
+	}
+	if _, err := d.Add(Subscription{ID: created.ID, URL: "https://example.com/other", Secret: testSecret}); err == nil {
+		t.Error("Expected an error for a duplicate ID")
+	}
+
+	subscriptions := d.Subscriptions()
+	if len(subscriptions) != 1 || subscriptions[0].Secret != "" {
+		t.Errorf("Expected 1 subscription without secret, got %+v", subscriptions)
+	}
+
+	if removed, err := d.Remove(created.ID); !removed || err != nil {
+		t.Errorf("Expected Remove to find the subscription, got %v, %v", removed, err)
+	}
+	if removed, _ := d.Remove(created.ID); removed {
+		t.Error("Expected Remove to report a missing subscription")
+	}
+}
+
+func TestDispatcher_PrivateTargets(t *testing.T) {
+	targets := map[string]bool{
+		"https://example.com/hook":          true,
+		"https://93.184.215.14/hook":        true,
+		"http://localhost:8080/hook":        false,
+		"http://api.localhost/hook":         false,
+		"http://127.0.0.1/hook":             false,
+		"http://10.0.0.5/hook":              false,
+		"http://192.168.1.1/hook":           false,
+		"http://169.254.169.254/latest":     false,
+		"http://[::1]/hook":                 false,
+		"http://[fe80::1]/hook":             false,
+		"http://[::ffff:127.0.0.1]/hook":    false,
+		"http://0.0.0.0/hook":               false,
+		"http://[fd00::1]:8080/hook":        false,
+		"https://webhooks.example.org:8443": true,
+	}
+
+	for target, public := range targets {
+		t.Run(target, func(t *testing.T) {
+			d := NewDispatcher(&http.Client{Timeout: time.Second})
+			_, err := d.Add(Subscription{URL: target, Secret: testSecret})
+			if public != (err == nil) {
+				t.Errorf("Expected accepted=%v, got %v", public, err)
+			}
+
+			d.AllowPrivateTargets(true)
+			if _, err := d.Add(Subscription{URL: target, Secret: testSecret}); err != nil {
+				t.Errorf("Expected the target to be accepted when allowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(&receiver{})
+	defer srv.Close()
+
+	resp, err := NewClient(time.Second, false).Get(srv.URL)
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("Expected the loopback receiver to be refused")
+	}
+	if !errors.Is(err, errPrivateTarget) {
+		t.Errorf("Expected a private target error, got %v", err)
+	}
+
+	resp, err = NewClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected the loopback receiver to be reached when allowed, got %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestDispatcher_SubscriptionsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	d := newTestDispatcher()
+	d.SetSubscriptionsFile(path)
+
+	created, err := d.Add(Subscription{URL: "https://example.com/hook", Secret: testSecret, CIS: []int{61266250}})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	saved, err := LoadSubscriptions(path)
+	if err != nil {
+		t.Fatalf("LoadSubscriptions failed: %v", err)
+	}
+	if len(saved) != 1 || saved[0].ID != created.ID || saved[0].Secret != testSecret {
+		t.Errorf("Expected the subscription with its secret, got %+v", saved)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected an owner-only file, got %v, %v", info, err)
+	}
+
+	if removed, err := d.Remove(created.ID); !removed || err != nil {
+		t.Fatalf("Expected Remove to succeed, got %v, %v", removed, err)
+	}
+	if saved, _ = LoadSubscriptions(path); len(saved) != 0 {
+		t.Errorf("Expected no saved subscription, got %+v", saved)
+	}
+
+	// A subscription that cannot be saved is not kept either
+	d.SetSubscriptionsFile(filepath.Join(t.TempDir(), "missing", "webhooks.json"))
+	if _, err := d.Add(Subscription{URL: "https://example.com/hook", Secret: testSecret}); !errors.Is(err, errSave) {
+		t.Errorf("Expected a save error, got %v", err)
+	}
+	if len(d.Subscriptions()) != 0 {
+		t.Errorf("Expected the unsaved subscription to be dropped, got %+v", d.Subscriptions())
+	}
+}
+
+func TestLoadSubscriptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	content := `[{"id": "pharmacy", "url": "https://example.com/hook", "secret": "` + testSecret + `", "cis": [61266250], "events": ["price"]}]`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	subscriptions, err := LoadSubscriptions(path)
+	if err != nil {
+		t.Fatalf("LoadSubscriptions failed: %v", err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].ID != "pharmacy" || subscriptions[0].CIS[0] != 61266250 {
+		t.Errorf("Unexpected subscriptions: %+v", subscriptions)
+	}
+
+	if _, err := LoadSubscriptions(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}